@@ -238,13 +238,32 @@ func (cli *socketClient) didRecvResponse(res *types.Response) error {
 
 //----------------------------------------
 
+// Flush queues a flush request and waits for it to complete. Because the
+// application answers requests in order, all previously queued requests are
+// done once Flush returns nil. If ctx is done first, ctx.Err() is returned;
+// the outstanding requests are still answered or failed when the connection
+// stops.
 func (cli *socketClient) Flush(ctx context.Context) error {
 	reqRes, err := cli.queueRequest(ctx, types.ToRequestFlush())
 	if err != nil {
 		return err
 	}
-	reqRes.Wait()
-	return nil
+	if ctx.Done() == nil {
+		reqRes.Wait()
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		reqRes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func (cli *socketClient) Echo(ctx context.Context, msg string) (*types.ResponseEcho, error) {
@@ -73,6 +73,20 @@ func TestHangingAsyncCalls(t *testing.T) {
 	}
 }
 
+func TestQueryHonorsContext(t *testing.T) {
+	app := slowApp{}
+
+	_, c := setupClientServer(t, app)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := c.Query(ctx, &types.RequestQuery{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
 func TestBulk(t *testing.T) {
 	const numTxs = 700000
 	// use a socket instead of a port
@@ -161,6 +175,11 @@ func (slowApp) CheckTx(context.Context, *types.RequestCheckTx) (*types.ResponseC
 	return &types.ResponseCheckTx{}, nil
 }
 
+func (slowApp) Query(context.Context, *types.RequestQuery) (*types.ResponseQuery, error) {
+	time.Sleep(time.Second)
+	return &types.ResponseQuery{}, nil
+}
+
 // TestCallbackInvokedWhenSetLate ensures that the callback is invoked when
 // set after the client completes the call into the app. Currently this
 // test relies on the callback being allowed to be invoked twice if set multiple
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	dbm "github.com/cometbft/cometbft-db"
+
+	"github.com/cometbft/cometbft/evidence"
+	cmtjson "github.com/cometbft/cometbft/libs/json"
+	cmtos "github.com/cometbft/cometbft/libs/os"
+	"github.com/cometbft/cometbft/store"
+	"github.com/cometbft/cometbft/types"
+)
+
+var (
+	exportPending   bool
+	exportCommitted bool
+	exportOut       string
+)
+
+func init() {
+	ExportEvidenceCmd.Flags().BoolVar(&exportPending, "pending", false, "export the pending evidence")
+	ExportEvidenceCmd.Flags().BoolVar(&exportCommitted, "committed", false,
+		"export the committed evidence, loaded from the block store")
+	ExportEvidenceCmd.Flags().StringVar(&exportOut, "out", "", "file to write the evidence to (default: stdout)")
+}
+
+// ExportEvidenceCmd writes the evidence of a stopped node's evidence pool to
+// a JSON file, which can be read back with ImportEvidenceCmd.
+var ExportEvidenceCmd = &cobra.Command{
+	Use:     "export-evidence",
+	Aliases: []string{"export_evidence"},
+	Short:   "Write the evidence in the evidence pool of a stopped node to a JSON file",
+	Long: `Write the evidence in the evidence pool of a stopped node to a JSON file.
+
+The evidence is written as a JSON array, in the format read by import-evidence:
+the pending evidence with --pending, followed by the committed evidence with
+--committed (both if neither flag is given). The evidence database only
+records which evidence was committed, so committed evidence is loaded from the
+block store; committed evidence whose block was pruned is reported and skipped.
+The node must be stopped, as its databases are opened directly.`,
+	Args: cobra.NoArgs,
+	RunE: exportEvidence,
+}
+
+func exportEvidence(*cobra.Command, []string) error {
+	pending, committed := exportPending, exportCommitted
+	if !pending && !committed {
+		pending, committed = true, true
+	}
+
+	dbType := dbm.BackendType(config.DBBackend)
+	if !cmtos.FileExists(filepath.Join(config.DBDir(), "evidence.db")) {
+		return fmt.Errorf("no evidence store found in %v", config.DBDir())
+	}
+	evidenceDB, err := dbm.NewDB("evidence", dbType, config.DBDir())
+	if err != nil {
+		return err
+	}
+	defer evidenceDB.Close()
+
+	var blockStore *store.BlockStore
+	if committed {
+		if !cmtos.FileExists(filepath.Join(config.DBDir(), "blockstore.db")) {
+			return fmt.Errorf("no blockstore found in %v", config.DBDir())
+		}
+		blockStoreDB, err := dbm.NewDB("blockstore", dbType, config.DBDir())
+		if err != nil {
+			return err
+		}
+		blockStore = store.NewBlockStore(blockStoreDB)
+		defer blockStore.Close()
+	}
+
+	evList, missing, err := evidence.ExportEvidence(evidenceDB, blockStore, pending, committed)
+	if err != nil {
+		return fmt.Errorf("failed to export evidence: %w", err)
+	}
+	for _, hash := range missing {
+		fmt.Fprintf(os.Stderr, "WARNING: committed evidence %X not found in the block store, skipping it\n", hash)
+	}
+
+	if evList == nil {
+		evList = []types.Evidence{}
+	}
+	bz, err := cmtjson.MarshalIndent(evList, "", "  ")
+	if err != nil {
+		return err
+	}
+	if exportOut == "" {
+		_, err = fmt.Println(string(bz))
+		return err
+	}
+	if err := cmtos.WriteFile(exportOut, append(bz, '\n'), 0o600); err != nil {
+		return err
+	}
+	logger.Info("Exported evidence", "count", len(evList), "file", exportOut)
+	return nil
+}
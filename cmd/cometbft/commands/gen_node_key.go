@@ -1,10 +1,19 @@
 package commands
 
 import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/tmhash"
 	cmtos "github.com/cometbft/cometbft/libs/os"
 	"github.com/cometbft/cometbft/p2p"
 )
@@ -18,16 +27,136 @@ var GenNodeKeyCmd = &cobra.Command{
 	RunE:    genNodeKey,
 }
 
+var (
+	nodeKeyType         string
+	nodeKeyOutput       string
+	nodeKeyShowExisting bool
+	nodeKeyOutputFormat string
+	nodeKeyFingerprint  bool
+	nodeKeyMnemonic     string
+	nodeKeyGenMnemonic  bool
+)
+
+func init() {
+	GenNodeKeyCmd.Flags().StringVar(&nodeKeyType, "key-type", ed25519.KeyType,
+		fmt.Sprintf("type of the node key (one of: %s)", strings.Join(p2p.SupportedNodeKeyTypes(), ", ")))
+	GenNodeKeyCmd.Flags().StringVar(&nodeKeyOutput, "output", "",
+		"path to write the node key to (defaults to the node key file from the config)")
+	GenNodeKeyCmd.Flags().BoolVar(&nodeKeyShowExisting, "show-existing", false,
+		"if the node key already exists, print its ID instead of returning an error")
+	GenNodeKeyCmd.Flags().StringVar(&nodeKeyOutputFormat, "output-format", "text",
+		"output format (text|json)")
+	GenNodeKeyCmd.Flags().BoolVar(&nodeKeyFingerprint, "fingerprint", false,
+		"also print a short fingerprint of the ID, to confirm two IDs match")
+	GenNodeKeyCmd.Flags().StringVar(&nodeKeyMnemonic, "mnemonic", "",
+		"derive the ed25519 node key from this BIP39 mnemonic instead of generating it randomly, "+
+			"or - to read the mnemonic from the standard input")
+	GenNodeKeyCmd.Flags().BoolVar(&nodeKeyGenMnemonic, "generate-mnemonic", false,
+		"generate a new 24-word BIP39 mnemonic, print it and derive the node key from it, as with --mnemonic")
+}
+
 func genNodeKey(*cobra.Command, []string) error {
-	nodeKeyFile := config.NodeKeyFile()
+	return writeNodeKey(os.Stdout)
+}
+
+// writeNodeKey generates the node key, or loads it with --show-existing, and
+// prints its ID to w.
+func writeNodeKey(w io.Writer) error {
+	if nodeKeyOutputFormat != "text" && nodeKeyOutputFormat != "json" {
+		return fmt.Errorf("unsupported output format: %s", nodeKeyOutputFormat)
+	}
+	if nodeKeyGenMnemonic && nodeKeyMnemonic != "" {
+		return errors.New("--mnemonic and --generate-mnemonic cannot be used together")
+	}
+
+	nodeKeyFile := nodeKeyOutput
+	if nodeKeyFile == "" {
+		nodeKeyFile = config.NodeKeyFile()
+	}
 	if cmtos.FileExists(nodeKeyFile) {
-		return fmt.Errorf("node key at %s already exists", nodeKeyFile)
+		if !nodeKeyShowExisting {
+			return fmt.Errorf("node key at %s already exists", nodeKeyFile)
+		}
+		nodeKey, err := p2p.LoadNodeKey(nodeKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load existing node key at %s: %w", nodeKeyFile, err)
+		}
+		return printNodeKey(w, nodeKey, nodeKeyFile, "")
 	}
 
-	nodeKey, err := p2p.LoadOrGenNodeKey(nodeKeyFile)
+	var (
+		nodeKey  *p2p.NodeKey
+		mnemonic string
+		err      error
+	)
+	switch {
+	case nodeKeyGenMnemonic:
+		mnemonic = p2p.GenMnemonic()
+		nodeKey, err = nodeKeyFromMnemonic(mnemonic, nil)
+	case nodeKeyMnemonic != "":
+		nodeKey, err = nodeKeyFromMnemonic(nodeKeyMnemonic, os.Stdin)
+	default:
+		nodeKey, err = p2p.GenNodeKey(nodeKeyType)
+	}
 	if err != nil {
 		return err
 	}
-	fmt.Println(nodeKey.ID())
+	if err := nodeKey.SaveAs(nodeKeyFile); err != nil {
+		return fmt.Errorf("failed to save node key to %s: %w", nodeKeyFile, err)
+	}
+	return printNodeKey(w, nodeKey, nodeKeyFile, mnemonic)
+}
+
+// nodeKeyFromMnemonic derives the node key from mnemonic (see
+// p2p.NodeKeyFromMnemonic), which is read from stdin if it is "-".
+func nodeKeyFromMnemonic(mnemonic string, stdin io.Reader) (*p2p.NodeKey, error) {
+	if nodeKeyType != ed25519.KeyType {
+		return nil, fmt.Errorf("--mnemonic only derives %s node keys, not %s", ed25519.KeyType, nodeKeyType)
+	}
+	if mnemonic == "-" {
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read the mnemonic: %w", err)
+		}
+		mnemonic = line
+	}
+	return p2p.NodeKeyFromMnemonic(mnemonic, "")
+}
+
+// printNodeKey prints the node's ID to w in the format selected by
+// --output-format. With --fingerprint, the text output has a second line with
+// the fingerprint of the ID, so that the first line is unchanged. A generated
+// mnemonic, if not empty, is printed last.
+func printNodeKey(w io.Writer, nodeKey *p2p.NodeKey, path, mnemonic string) error {
+	var fingerprint string
+	if nodeKeyFingerprint {
+		fingerprint = nodeIDFingerprint(nodeKey.ID())
+	}
+	if nodeKeyOutputFormat == "json" {
+		bz, err := json.Marshal(struct {
+			ID          p2p.ID `json:"id"`
+			Path        string `json:"path"`
+			Fingerprint string `json:"fingerprint,omitempty"`
+			Mnemonic    string `json:"mnemonic,omitempty"`
+		}{nodeKey.ID(), path, fingerprint, mnemonic})
+		if err != nil {
+			return fmt.Errorf("failed to marshal node key info: %w", err)
+		}
+		fmt.Fprintln(w, string(bz))
+		return nil
+	}
+	fmt.Fprintln(w, nodeKey.ID())
+	if fingerprint != "" {
+		fmt.Fprintln(w, "fingerprint:", fingerprint)
+	}
+	if mnemonic != "" {
+		fmt.Fprintln(w, "mnemonic:", mnemonic)
+	}
 	return nil
 }
+
+// nodeIDFingerprint returns the first 8 hex characters of the hash of the
+// full ID. Unlike a prefix of the ID, it changes when any part of the ID does.
+func nodeIDFingerprint(id p2p.ID) string {
+	return hex.EncodeToString(tmhash.Sum([]byte(id))[:4])
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/p2p"
+)
+
+func TestNodeIDFingerprint(t *testing.T) {
+	id := p2p.ID("6ad4d2c9e1d81c5bba2fc4b14e2ee8b3e4af8ae3")
+
+	fingerprint := nodeIDFingerprint(id)
+	require.Len(t, fingerprint, 8)
+	require.Equal(t, fingerprint, nodeIDFingerprint(id))
+
+	// a single changed character, even at the end, changes the fingerprint
+	require.NotEqual(t, fingerprint, nodeIDFingerprint(id[:len(id)-1]+"4"))
+}
+
+func TestWriteNodeKeyJSON(t *testing.T) {
+	defer func(output, format string) {
+		nodeKeyOutput, nodeKeyOutputFormat = output, format
+	}(nodeKeyOutput, nodeKeyOutputFormat)
+	nodeKeyOutput = filepath.Join(t.TempDir(), "node_key.json")
+	nodeKeyOutputFormat = "json"
+
+	var out bytes.Buffer
+	require.NoError(t, writeNodeKey(&out))
+	var printed struct {
+		ID   p2p.ID `json:"id"`
+		Path string `json:"path"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &printed))
+	require.Equal(t, nodeKeyOutput, printed.Path)
+
+	// the key is written to --output, and the printed ID is its ID
+	nodeKey, err := p2p.LoadNodeKey(nodeKeyOutput)
+	require.NoError(t, err)
+	require.Equal(t, nodeKey.ID(), printed.ID)
+}
+
+func TestWriteNodeKeyUnsupportedFormat(t *testing.T) {
+	defer func(output, format string) {
+		nodeKeyOutput, nodeKeyOutputFormat = output, format
+	}(nodeKeyOutput, nodeKeyOutputFormat)
+	nodeKeyOutput = filepath.Join(t.TempDir(), "node_key.json")
+	nodeKeyOutputFormat = "yaml"
+
+	var out bytes.Buffer
+	err := writeNodeKey(&out)
+	require.ErrorContains(t, err, "unsupported output format")
+	require.Empty(t, out.String())
+	require.NoFileExists(t, nodeKeyOutput)
+}
+
+func TestWriteNodeKeyShowExisting(t *testing.T) {
+	defer func(output string, showExisting bool) {
+		nodeKeyOutput, nodeKeyShowExisting = output, showExisting
+	}(nodeKeyOutput, nodeKeyShowExisting)
+	nodeKeyOutput = filepath.Join(t.TempDir(), "node_key.json")
+	existing, err := p2p.LoadOrGenNodeKey(nodeKeyOutput)
+	require.NoError(t, err)
+	content, err := os.ReadFile(nodeKeyOutput)
+	require.NoError(t, err)
+
+	// without --show-existing, an existing key is never overwritten
+	nodeKeyShowExisting = false
+	var out bytes.Buffer
+	err = writeNodeKey(&out)
+	require.ErrorContains(t, err, "already exists")
+	require.Empty(t, out.String())
+
+	// with --show-existing, the ID of the existing key is printed
+	nodeKeyShowExisting = true
+	require.NoError(t, writeNodeKey(&out))
+	require.Equal(t, string(existing.ID())+"\n", out.String())
+	after, err := os.ReadFile(nodeKeyOutput)
+	require.NoError(t, err)
+	require.Equal(t, content, after)
+
+	// a corrupt key is reported with its path
+	require.NoError(t, os.WriteFile(nodeKeyOutput, []byte("{not json"), 0o600))
+	out.Reset()
+	err = writeNodeKey(&out)
+	require.ErrorContains(t, err, nodeKeyOutput)
+	require.Empty(t, out.String())
+}
+
+func TestWriteNodeKeyGenerateMnemonic(t *testing.T) {
+	defer func(output, format, mnemonic string, genMnemonic bool) {
+		nodeKeyOutput, nodeKeyOutputFormat, nodeKeyMnemonic, nodeKeyGenMnemonic = output, format, mnemonic, genMnemonic
+	}(nodeKeyOutput, nodeKeyOutputFormat, nodeKeyMnemonic, nodeKeyGenMnemonic)
+	nodeKeyOutput = filepath.Join(t.TempDir(), "node_key.json")
+	nodeKeyOutputFormat = "json"
+	nodeKeyGenMnemonic = true
+
+	var out bytes.Buffer
+	require.NoError(t, writeNodeKey(&out))
+	var printed struct {
+		ID       p2p.ID `json:"id"`
+		Mnemonic string `json:"mnemonic"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &printed))
+	require.Len(t, strings.Fields(printed.Mnemonic), 24)
+
+	// the printed mnemonic recreates the saved key
+	restored, err := p2p.NodeKeyFromMnemonic(printed.Mnemonic, "")
+	require.NoError(t, err)
+	saved, err := p2p.LoadNodeKey(nodeKeyOutput)
+	require.NoError(t, err)
+	require.Equal(t, saved.ID(), restored.ID())
+	require.Equal(t, saved.ID(), printed.ID)
+
+	require.NoError(t, os.Remove(nodeKeyOutput))
+	nodeKeyMnemonic = printed.Mnemonic
+	err = writeNodeKey(&out)
+	require.ErrorContains(t, err, "cannot be used together")
+	require.NoFileExists(t, nodeKeyOutput)
+}
+
+func TestNodeKeyFromMnemonic(t *testing.T) {
+	const mnemonic = "legal winner thank year wave sausage worth useful legal winner thank yellow"
+	want, err := p2p.NodeKeyFromMnemonic(mnemonic, "")
+	require.NoError(t, err)
+
+	nodeKey, err := nodeKeyFromMnemonic(mnemonic, strings.NewReader(""))
+	require.NoError(t, err)
+	require.Equal(t, want.ID(), nodeKey.ID())
+
+	// read from the standard input
+	nodeKey, err = nodeKeyFromMnemonic("-", strings.NewReader(mnemonic+"\nignored\n"))
+	require.NoError(t, err)
+	require.Equal(t, want.ID(), nodeKey.ID())
+
+	defer func(keyType string) { nodeKeyType = keyType }(nodeKeyType)
+	nodeKeyType = "secp256k1"
+	_, err = nodeKeyFromMnemonic(mnemonic, strings.NewReader(""))
+	require.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	dbm "github.com/cometbft/cometbft-db"
+
+	"github.com/cometbft/cometbft/evidence"
+	cmtjson "github.com/cometbft/cometbft/libs/json"
+	"github.com/cometbft/cometbft/types"
+)
+
+// ImportEvidenceCmd adds evidence read from a JSON file to the evidence pool
+// of a stopped node.
+var ImportEvidenceCmd = &cobra.Command{
+	Use:     "import-evidence [file]",
+	Aliases: []string{"import_evidence"},
+	Short:   "Add evidence from a JSON file to the evidence pool of a stopped node",
+	Long: `Add evidence from a JSON file to the evidence pool of a stopped node.
+
+The file holds a JSON array of evidence, encoded as in the RPC (e.g.
+{"type": "tendermint/DuplicateVoteEvidence", "value": {...}}). Each piece of
+evidence is verified against the node's state and block store like evidence
+received from a peer, and the result is reported for each of them. The node
+must be stopped, as its databases are opened directly.`,
+	Args: cobra.ExactArgs(1),
+	RunE: importEvidence,
+}
+
+// evidenceAdder is the part of evidence.Pool used to import evidence.
+type evidenceAdder interface {
+	AddEvidence(ev types.Evidence) error
+}
+
+func importEvidence(_ *cobra.Command, args []string) error {
+	evList, err := loadEvidenceFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	blockStore, stateStore, err := loadStateAndBlockStore(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = blockStore.Close()
+		_ = stateStore.Close()
+	}()
+
+	evidenceDB, err := dbm.NewDB("evidence", dbm.BackendType(config.DBBackend), config.DBDir())
+	if err != nil {
+		return err
+	}
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	if err != nil {
+		_ = evidenceDB.Close()
+		return err
+	}
+	defer pool.Close()
+	pool.SetLogger(logger.With("module", "evidence"))
+
+	if failed := addEvidenceList(pool, evList, os.Stdout); failed > 0 {
+		return fmt.Errorf("failed to add %d of %d evidence", failed, len(evList))
+	}
+	return nil
+}
+
+// loadEvidenceFile reads a JSON array of evidence from file.
+func loadEvidenceFile(file string) ([]types.Evidence, error) {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var evList []types.Evidence
+	if err := cmtjson.Unmarshal(bz, &evList); err != nil {
+		return nil, fmt.Errorf("failed to parse evidence in %s: %w", file, err)
+	}
+	for i, ev := range evList {
+		if ev == nil {
+			return nil, fmt.Errorf("evidence #%d in %s is null", i, file)
+		}
+	}
+	return evList, nil
+}
+
+// addEvidenceList adds each evidence in evList to pool, reporting the result
+// for each of them to w. It returns the number of evidence which could not be
+// added.
+func addEvidenceList(pool evidenceAdder, evList []types.Evidence, w io.Writer) (failed int) {
+	for i, ev := range evList {
+		if err := pool.AddEvidence(ev); err != nil {
+			failed++
+			fmt.Fprintf(w, "#%d %X: failed: %v\n", i, ev.Hash(), err)
+			continue
+		}
+		fmt.Fprintf(w, "#%d %X: added\n", i, ev.Hash())
+	}
+	return failed
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cmtjson "github.com/cometbft/cometbft/libs/json"
+	"github.com/cometbft/cometbft/types"
+)
+
+type fakeEvidencePool struct {
+	added  []types.Evidence
+	reject map[string]error
+}
+
+func (p *fakeEvidencePool) AddEvidence(ev types.Evidence) error {
+	if err := p.reject[string(ev.Hash())]; err != nil {
+		return err
+	}
+	p.added = append(p.added, ev)
+	return nil
+}
+
+func TestImportEvidence(t *testing.T) {
+	var evList []types.Evidence
+	for h := int64(1); h <= 3; h++ {
+		ev, err := types.NewMockDuplicateVoteEvidence(h, time.Now(), "test-chain")
+		require.NoError(t, err)
+		evList = append(evList, ev)
+	}
+	bz, err := cmtjson.Marshal(evList)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "evidence.json")
+	require.NoError(t, os.WriteFile(file, bz, 0o600))
+
+	loaded, err := loadEvidenceFile(file)
+	require.NoError(t, err)
+	require.Len(t, loaded, len(evList))
+	for i := range evList {
+		require.Equal(t, evList[i].Hash(), loaded[i].Hash())
+	}
+
+	pool := &fakeEvidencePool{reject: map[string]error{
+		string(evList[1].Hash()): errors.New("evidence already committed"),
+	}}
+	var out bytes.Buffer
+	failed := addEvidenceList(pool, loaded, &out)
+	require.Equal(t, 1, failed)
+	require.Len(t, pool.added, 2)
+	require.Contains(t, out.String(), "#0 ")
+	require.Contains(t, out.String(), "#1 ")
+	require.Contains(t, out.String(), "failed: evidence already committed")
+	require.Contains(t, out.String(), "#2 ")
+
+	require.NoError(t, os.WriteFile(file, []byte(`[null]`), 0o600))
+	_, err = loadEvidenceFile(file)
+	require.Error(t, err)
+	require.NoError(t, os.WriteFile(file, []byte(`{}`), 0o600))
+	_, err = loadEvidenceFile(file)
+	require.Error(t, err)
+}
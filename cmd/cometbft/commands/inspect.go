@@ -2,6 +2,8 @@ package commands
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
@@ -9,6 +11,7 @@ import (
 	"github.com/spf13/cobra"
 
 	cfg "github.com/cometbft/cometbft/config"
+	"github.com/cometbft/cometbft/evidence"
 	"github.com/cometbft/cometbft/inspect"
 	"github.com/cometbft/cometbft/state"
 	"github.com/cometbft/cometbft/state/indexer/block"
@@ -33,7 +36,50 @@ var InspectCmd = &cobra.Command{
 	RunE: runInspect,
 }
 
+// InspectEvidenceCmd checks the consistency of a stopped node's evidence
+// store.
+var InspectEvidenceCmd = &cobra.Command{
+	Use:   "evidence",
+	Short: "Check the consistency of the evidence store",
+	Long: `
+	evidence opens the evidence pool of a stopped node and checks that every
+	pending and committed evidence entry decodes, and that the amount of pending
+	evidence matches the pool's counters. Like on node startup, expired pending
+	evidence is removed from the store when the pool is opened.
+	`,
+	Args: cobra.NoArgs,
+	RunE: runInspectEvidence,
+}
+
+// InspectEvidenceConsistencyCmd cross-checks the committed evidence of a
+// stopped node's evidence store with the evidence in its block store.
+var InspectEvidenceConsistencyCmd = &cobra.Command{
+	Use:   "evidence-consistency",
+	Short: "Cross-check the committed evidence of the evidence store and the block store",
+	Long: `
+	evidence-consistency checks, for the blocks from --from to --to (by default
+	all the blocks in the block store), that every evidence in those blocks is
+	recorded as committed in the evidence store, and that every evidence the
+	evidence store records as committed with a height in that range is included
+	in a block. The differences are reported, and the command fails if there
+	are any. The databases of the stopped node are only read.
+	`,
+	Args: cobra.NoArgs,
+	RunE: runInspectEvidenceConsistency,
+}
+
+var (
+	consistencyFromHeight int64
+	consistencyToHeight   int64
+)
+
 func init() {
+	InspectCmd.AddCommand(InspectEvidenceCmd)
+	InspectCmd.AddCommand(InspectEvidenceConsistencyCmd)
+	InspectEvidenceConsistencyCmd.Flags().Int64Var(&consistencyFromHeight, "from", 0,
+		"first height to check (default: the block store base)")
+	InspectEvidenceConsistencyCmd.Flags().Int64Var(&consistencyToHeight, "to", 0,
+		"last height to check (default: the block store height)")
 	InspectCmd.Flags().
 		String("rpc.laddr",
 			config.RPC.ListenAddress, "RPC listener address. Port required")
@@ -82,3 +128,83 @@ func runInspect(cmd *cobra.Command, _ []string) error {
 	logger.Info("starting inspect server")
 	return ins.Run(ctx)
 }
+
+func runInspectEvidence(*cobra.Command, []string) error {
+	blockStore, stateStore, err := loadStateAndBlockStore(config)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = blockStore.Close()
+		_ = stateStore.Close()
+	}()
+
+	evidenceDB, err := cfg.DefaultDBProvider(&cfg.DBContext{ID: "evidence", Config: config})
+	if err != nil {
+		return err
+	}
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	if err != nil {
+		_ = evidenceDB.Close()
+		return err
+	}
+	defer pool.Close()
+	pool.SetLogger(logger.With("module", "evidence"))
+
+	if err := pool.Verify(); err != nil {
+		return fmt.Errorf("evidence store is inconsistent: %w", err)
+	}
+	fmt.Printf("evidence store is consistent, %d pending evidence\n", pool.Size())
+	return nil
+}
+
+func runInspectEvidenceConsistency(*cobra.Command, []string) error {
+	blockStoreDB, err := cfg.DefaultDBProvider(&cfg.DBContext{ID: "blockstore", Config: config})
+	if err != nil {
+		return err
+	}
+	blockStore := store.NewBlockStore(blockStoreDB)
+	defer blockStore.Close()
+
+	evidenceDB, err := cfg.DefaultDBProvider(&cfg.DBContext{ID: "evidence", Config: config})
+	if err != nil {
+		return err
+	}
+	defer evidenceDB.Close()
+
+	if blockStore.Height() == 0 {
+		return errors.New("the block store is empty")
+	}
+	from, to := consistencyFromHeight, consistencyToHeight
+	if from == 0 {
+		from = blockStore.Base()
+	}
+	if to == 0 {
+		to = blockStore.Height()
+	}
+	report, err := evidence.CheckConsistency(evidenceDB, blockStore, from, to)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range report.NotMarkedCommitted {
+		fmt.Printf("evidence %X (height %d) in block %d is not marked as committed\n",
+			ref.Hash, ref.Height, ref.BlockHeight)
+	}
+	for _, ref := range report.NotInBlockStore {
+		fmt.Printf("evidence %X (height %d) is marked as committed but is in no block\n", ref.Hash, ref.Height)
+	}
+	if report.PrunedBelow > from {
+		fmt.Fprintf(os.Stderr, "WARNING: committed evidence below height %d was pruned, "+
+			"the evidence of those heights is not checked\n", report.PrunedBelow)
+	}
+	if n := len(report.MissingBlocks); n > 0 {
+		fmt.Fprintf(os.Stderr, "WARNING: %d blocks not found in the block store, from height %d to %d\n",
+			n, report.MissingBlocks[0], report.MissingBlocks[n-1])
+	}
+	if !report.Consistent() {
+		return fmt.Errorf("evidence store is inconsistent with the block store from height %d to %d", from, to)
+	}
+	fmt.Printf("evidence store is consistent with the block store from height %d to %d\n", from, to)
+	return nil
+}
@@ -1,9 +1,15 @@
 package commands
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -23,6 +29,9 @@ import (
 
 const (
 	reindexFailed = "event re-index failed: "
+
+	// psqlPingTimeout bounds the connectivity check of the psql event sink
+	psqlPingTimeout = 10 * time.Second
 )
 
 var (
@@ -45,12 +54,42 @@ either or both arguments.
 
 Note: This operation requires ABCI Responses. Do not set DiscardABCIResponses to true if you
 want to use this command.
+
+The --filter-expr flag restricts re-indexing to the heights whose block matches
+an expression of the form <field><op><value>. Supported fields are height,
+num_txs and proposer (hex-encoded address). Supported operators are =, !=, >,
+>=, < and <=; proposer only supports = and !=.
+
+With --dry-run, the blocks and ABCI responses in the range are loaded but nothing
+is indexed. At the end, the number of blocks and txs that would be re-indexed is
+printed, along with the heights whose ABCI responses are missing.
+
+With --progress-json, the progress bar is replaced by one JSON object per
+re-indexed height written to stdout, e.g.
+{"height":12,"txs_indexed":340,"elapsed_ms":1520}, where txs_indexed is the
+number of txs re-indexed since the start and elapsed_ms the time since the
+start. Other messages are still printed as plain text lines.
+
+By default, the re-index stops at the first height whose block or ABCI
+responses cannot be loaded. With --skip-missing, such heights are logged and
+skipped instead, and the skipped heights are listed at the end.
+
+With --verify, the block events are re-indexed into a temporary in-memory
+index and compared with the existing block index, without writing to it. The
+heights whose indexed events diverge are reported, e.g. to validate a change of
+the block indexer before re-indexing for real. Only the kv event sink is
+supported, and the tx index is not checked.
 	`,
 	Example: `
 	cometbft reindex-event
 	cometbft reindex-event --start-height 2
 	cometbft reindex-event --end-height 10
 	cometbft reindex-event --start-height 2 --end-height 10
+	cometbft reindex-event --filter-expr "num_txs>0"
+	cometbft reindex-event --dry-run
+	cometbft reindex-event --progress-json
+	cometbft reindex-event --skip-missing
+	cometbft reindex-event --verify
 	`,
 	Run: func(cmd *cobra.Command, args []string) {
 		bs, ss, err := loadStateAndBlockStore(config)
@@ -70,12 +109,71 @@ want to use this command.
 			return
 		}
 
+		var filter *blockFilter
+		if filterExpr != "" {
+			filter, err = parseBlockFilter(filterExpr)
+			if err != nil {
+				fmt.Println(reindexFailed, err)
+				return
+			}
+		}
+
+		if dryRun && progressJSON {
+			fmt.Println(reindexFailed, "--progress-json cannot be used with --dry-run")
+			return
+		}
+
+		if dryRun {
+			report, err := eventReIndexDryRun(cmd, eventReIndexArgs{
+				startHeight: startHeight,
+				endHeight:   endHeight,
+				blockStore:  bs,
+				stateStore:  ss,
+				filter:      filter,
+			})
+			if err != nil {
+				fmt.Println(reindexFailed, err)
+				return
+			}
+			report.print()
+			return
+		}
+
+		if verify {
+			if dryRun || progressJSON {
+				fmt.Println(reindexFailed, "--verify cannot be used with --dry-run or --progress-json")
+				return
+			}
+			if !strings.EqualFold(config.TxIndex.Indexer, "kv") {
+				fmt.Println(reindexFailed, "--verify only supports the kv event sink")
+				return
+			}
+		}
+
 		bi, ti, err := loadEventSinks(config, state.ChainID)
 		if err != nil {
 			fmt.Println(reindexFailed, err)
 			return
 		}
 
+		if verify {
+			report, err := eventReIndexVerify(cmd, eventReIndexArgs{
+				startHeight:  startHeight,
+				endHeight:    endHeight,
+				blockIndexer: bi,
+				blockStore:   bs,
+				stateStore:   ss,
+				filter:       filter,
+				skipMissing:  skipMissing,
+			})
+			if err != nil {
+				fmt.Println(reindexFailed, err)
+				return
+			}
+			report.print()
+			return
+		}
+
 		riArgs := eventReIndexArgs{
 			startHeight:  startHeight,
 			endHeight:    endHeight,
@@ -83,10 +181,20 @@ want to use this command.
 			txIndexer:    ti,
 			blockStore:   bs,
 			stateStore:   ss,
+			filter:       filter,
+			batchSize:    batchSize,
+			skipMissing:  skipMissing,
+		}
+		if progressJSON {
+			riArgs.progress = newJSONProgress(os.Stdout)
 		}
-		if err := eventReIndex(cmd, riArgs); err != nil {
+		skipped, err := eventReIndex(cmd, riArgs)
+		if err != nil {
 			panic(fmt.Errorf("%s: %w", reindexFailed, err))
 		}
+		if len(skipped) > 0 {
+			fmt.Printf("skipped %d heights with a missing block or ABCI responses: %v\n", len(skipped), skipped)
+		}
 
 		fmt.Println("event re-index finished")
 	},
@@ -95,11 +203,30 @@ want to use this command.
 var (
 	startHeight int64
 	endHeight   int64
+	filterExpr  string
+	dryRun      bool
+	batchSize   int
+
+	progressJSON bool
+	skipMissing  bool
+	verify       bool
 )
 
 func init() {
 	ReIndexEventCmd.Flags().Int64Var(&startHeight, "start-height", 0, "the block height would like to start for re-index")
 	ReIndexEventCmd.Flags().Int64Var(&endHeight, "end-height", 0, "the block height would like to finish for re-index")
+	ReIndexEventCmd.Flags().StringVar(&filterExpr, "filter-expr", "",
+		"only re-index heights whose block matches the expression, e.g. \"num_txs>0\"")
+	ReIndexEventCmd.Flags().BoolVar(&dryRun, "dry-run", false,
+		"report the blocks and txs that would be re-indexed and any missing ABCI responses, without indexing")
+	ReIndexEventCmd.Flags().IntVar(&batchSize, "batch-size", 1,
+		"number of txs to accumulate across heights before writing them to the tx indexer")
+	ReIndexEventCmd.Flags().BoolVar(&progressJSON, "progress-json", false,
+		"report the progress as one JSON object per height on stdout instead of a progress bar")
+	ReIndexEventCmd.Flags().BoolVar(&skipMissing, "skip-missing", false,
+		"skip the heights whose block or ABCI responses cannot be loaded instead of stopping")
+	ReIndexEventCmd.Flags().BoolVar(&verify, "verify", false,
+		"re-index the block events into a temporary index and report divergences from the existing one, without writing")
 }
 
 func loadEventSinks(cfg *cmtcfg.Config, chainID string) (indexer.BlockIndexer, txindex.TxIndexer, error) {
@@ -115,6 +242,14 @@ func loadEventSinks(cfg *cmtcfg.Config, chainID string) (indexer.BlockIndexer, t
 		if err != nil {
 			return nil, nil, err
 		}
+		// sql.Open doesn't connect, so check that the database is reachable
+		// before starting rather than failing on the first write
+		ctx, cancel := context.WithTimeout(context.Background(), psqlPingTimeout)
+		defer cancel()
+		if err := es.DB().PingContext(ctx); err != nil {
+			_ = es.Stop()
+			return nil, nil, fmt.Errorf("cannot connect to the psql event sink: %w", err)
+		}
 		return es.BlockIndexer(), es.TxIndexer(), nil
 	case "kv":
 		store, err := dbm.NewDB("tx_index", dbm.BackendType(cfg.DBBackend), cfg.DBDir())
@@ -137,27 +272,155 @@ type eventReIndexArgs struct {
 	txIndexer    txindex.TxIndexer
 	blockStore   state.BlockStore
 	stateStore   state.Store
+	// optional; if set only the heights whose block matches are re-indexed
+	filter *blockFilter
+	// minimum number of txs accumulated before they're written to the tx
+	// indexer; values below 1 are treated as 1 (one write per height)
+	batchSize int
+	// optional; reports the progress, a progress bar is used if not set
+	progress reindexProgress
+	// if set, heights whose block or ABCI responses cannot be loaded are
+	// skipped rather than aborting the re-index
+	skipMissing bool
 }
 
-func eventReIndex(cmd *cobra.Command, args eventReIndexArgs) error {
-	var bar progressbar.Bar
-	bar.NewOption(args.startHeight-1, args.endHeight)
+// reindexProgress reports the progress of eventReIndex.
+type reindexProgress interface {
+	// start is called before the first height is re-indexed.
+	start(startHeight, endHeight int64)
+	// update is called after each height, with the number of txs re-indexed
+	// since the start.
+	update(height, txsIndexed int64)
+	// finish is called once eventReIndex returns.
+	finish()
+}
+
+// barProgress reports the progress with a progress bar.
+type barProgress struct {
+	bar progressbar.Bar
+}
 
+func (p *barProgress) start(startHeight, endHeight int64) {
+	p.bar.NewOption(startHeight-1, endHeight)
 	fmt.Println("start re-indexing events:")
-	defer bar.Finish()
+}
+
+func (p *barProgress) update(height, _ int64) {
+	p.bar.Play(height)
+}
+
+func (p *barProgress) finish() {
+	p.bar.Finish()
+}
+
+// jsonProgress reports the progress as one JSON object per height, on its own
+// line, so that it can be followed by another process.
+type jsonProgress struct {
+	enc     *json.Encoder
+	started time.Time
+}
+
+type jsonProgressLine struct {
+	Height     int64 `json:"height"`
+	TxsIndexed int64 `json:"txs_indexed"`
+	ElapsedMs  int64 `json:"elapsed_ms"`
+}
+
+func newJSONProgress(w io.Writer) *jsonProgress {
+	return &jsonProgress{enc: json.NewEncoder(w)}
+}
+
+func (p *jsonProgress) start(int64, int64) {
+	p.started = time.Now()
+}
+
+func (p *jsonProgress) update(height, txsIndexed int64) {
+	// progress is best effort, a failed write must not abort the re-index
+	_ = p.enc.Encode(jsonProgressLine{
+		Height:     height,
+		TxsIndexed: txsIndexed,
+		ElapsedMs:  time.Since(p.started).Milliseconds(),
+	})
+}
+
+func (*jsonProgress) finish() {}
+
+// eventReIndex re-indexes the events of the heights in args. If
+// args.skipMissing is set, it returns the heights skipped because their block
+// or ABCI responses could not be loaded.
+func eventReIndex(cmd *cobra.Command, args eventReIndexArgs) (skipped []int64, err error) {
+	progress := args.progress
+	if progress == nil {
+		progress = &barProgress{}
+	}
+	progress.start(args.startHeight, args.endHeight)
+	defer progress.finish()
+
+	batchSize := args.batchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	// tx results are accumulated across heights and flushed once the batch
+	// holds batchSize txs; batchStart is the first height in the batch.
+	// NOTE: Batch.Add places results by their index within a block, so results
+	// spanning several heights are appended to Ops directly.
+	var (
+		batch      = txindex.NewBatch(0)
+		batchStart int64
+	)
+	flush := func(lastHeight int64) error {
+		if batch.Size() == 0 {
+			return nil
+		}
+		if err := args.txIndexer.AddBatch(batch); err != nil {
+			return fmt.Errorf("tx event re-index of heights %d to %d (%d txs) failed, "+
+				"txs are indexed up to height %d: %w",
+				batchStart, lastHeight, batch.Size(), batchStart-1, err)
+		}
+		batch = txindex.NewBatch(0)
+		return nil
+	}
+	// withPending annotates err with the txs accumulated up to lastHeight
+	// but not written yet, so the operator knows where to resume from.
+	withPending := func(lastHeight int64, err error) error {
+		if batch.Size() == 0 {
+			return err
+		}
+		return fmt.Errorf("%w; tx events of heights %d to %d (%d txs) were not re-indexed, "+
+			"txs are indexed up to height %d", err, batchStart, lastHeight, batch.Size(), batchStart-1)
+	}
+
+	var txsIndexed int64
 	for height := args.startHeight; height <= args.endHeight; height++ {
 		select {
 		case <-cmd.Context().Done():
-			return fmt.Errorf("event re-index terminated at height %d: %w", height, cmd.Context().Err())
+			return skipped, withPending(height-1, fmt.Errorf("event re-index terminated at height %d: %w", height, cmd.Context().Err()))
 		default:
 			block := args.blockStore.LoadBlock(height)
 			if block == nil {
-				return fmt.Errorf("not able to load block at height %d from the blockstore", height)
+				err := fmt.Errorf("not able to load block at height %d from the blockstore", height)
+				if !args.skipMissing {
+					return skipped, withPending(height-1, err)
+				}
+				logger.Error("Skipping height", "height", height, "err", err)
+				skipped = append(skipped, height)
+				break
+			}
+
+			if args.filter != nil && !args.filter.Matches(block) {
+				// skip this height
+				break
 			}
 
 			resp, err := args.stateStore.LoadFinalizeBlockResponse(height)
 			if err != nil {
-				return fmt.Errorf("not able to load ABCI Response at height %d from the statestore", height)
+				err := fmt.Errorf("not able to load ABCI Response at height %d from the statestore: %w", height, err)
+				if !args.skipMissing {
+					return skipped, withPending(height-1, err)
+				}
+				logger.Error("Skipping height", "height", height, "err", err)
+				skipped = append(skipped, height)
+				break
 			}
 
 			e := types.EventDataNewBlockEvents{
@@ -165,39 +428,232 @@ func eventReIndex(cmd *cobra.Command, args eventReIndexArgs) error {
 				Events: resp.Events,
 			}
 
-			numTxs := len(resp.TxResults)
+			if len(resp.TxResults) > 0 && batch.Size() == 0 {
+				batchStart = height
+			}
+			for idx, txResult := range resp.TxResults {
+				tr := abcitypes.TxResult{
+					Height: height,
+					Index:  uint32(idx),
+					Tx:     block.Txs[idx],
+					Result: *txResult,
+				}
 
-			var batch *txindex.Batch
-			if numTxs > 0 {
-				batch = txindex.NewBatch(int64(numTxs))
+				batch.Ops = append(batch.Ops, &tr)
+			}
 
-				for idx, txResult := range resp.TxResults {
-					tr := abcitypes.TxResult{
-						Height: height,
-						Index:  uint32(idx),
-						Tx:     block.Txs[idx],
-						Result: *txResult,
-					}
+			if batch.Size() >= batchSize {
+				if err := flush(height); err != nil {
+					return skipped, err
+				}
+			}
+
+			if err := args.blockIndexer.Index(e); err != nil {
+				return skipped, withPending(height, fmt.Errorf("block event re-index at height %d failed: %w", height, err))
+			}
+			txsIndexed += int64(len(resp.TxResults))
+		}
 
-					if err = batch.Add(&tr); err != nil {
-						return fmt.Errorf("adding tx to batch: %w", err)
-					}
+		progress.update(height, txsIndexed)
+	}
+
+	return skipped, flush(args.endHeight)
+}
+
+// dryRunReport summarizes the work a re-index of a height range would do.
+type dryRunReport struct {
+	blocks int64
+	txs    int64
+	// heights whose ABCI responses could not be loaded
+	missingResponses []int64
+}
+
+func (r *dryRunReport) print() {
+	fmt.Printf("dry run: %d blocks with %d txs would be re-indexed\n", r.blocks, r.txs)
+	if len(r.missingResponses) == 0 {
+		fmt.Println("all ABCI responses are available")
+		return
+	}
+	fmt.Printf("ABCI responses are missing at %d heights: %v\n", len(r.missingResponses), r.missingResponses)
+}
+
+// eventReIndexDryRun walks the height range like eventReIndex, loading blocks
+// and ABCI responses, but doesn't index anything. Missing ABCI responses are
+// recorded in the report instead of aborting the run.
+func eventReIndexDryRun(cmd *cobra.Command, args eventReIndexArgs) (*dryRunReport, error) {
+	var bar progressbar.Bar
+	bar.NewOption(args.startHeight-1, args.endHeight)
+
+	fmt.Println("start dry run:")
+	defer bar.Finish()
+	report := &dryRunReport{}
+	for height := args.startHeight; height <= args.endHeight; height++ {
+		select {
+		case <-cmd.Context().Done():
+			return nil, fmt.Errorf("event re-index dry run terminated at height %d: %w", height, cmd.Context().Err())
+		default:
+			block := args.blockStore.LoadBlock(height)
+			if block == nil {
+				return nil, fmt.Errorf("not able to load block at height %d from the blockstore", height)
+			}
+
+			if args.filter != nil && !args.filter.Matches(block) {
+				// skip this height
+				break
+			}
+
+			report.blocks++
+			report.txs += int64(len(block.Txs))
+			if _, err := args.stateStore.LoadFinalizeBlockResponse(height); err != nil {
+				report.missingResponses = append(report.missingResponses, height)
+			}
+		}
+
+		bar.Play(height)
+	}
+
+	return report, nil
+}
+
+// verifyReport lists the heights whose re-indexed block events diverge from
+// the existing block index.
+type verifyReport struct {
+	compared    int64
+	divergences []verifyDivergence
+	// heights skipped because their block or ABCI responses are missing
+	skipped []int64
+}
+
+// verifyDivergence describes how the existing index of a height differs from
+// its re-indexed events. Events are formatted as "<type>.<key>=<value>" and
+// listed once per occurrence.
+type verifyDivergence struct {
+	height int64
+	// the height is not in the existing index at all
+	notIndexed bool
+	// events which are re-indexed but not in the existing index
+	missing []string
+	// events which are in the existing index but not re-indexed
+	unexpected []string
+}
+
+func (r *verifyReport) print() {
+	fmt.Printf("verify: %d heights compared, %d diverge from the existing block index\n",
+		r.compared, len(r.divergences))
+	for _, d := range r.divergences {
+		if d.notIndexed {
+			fmt.Printf("height %d: not indexed\n", d.height)
+			continue
+		}
+		fmt.Printf("height %d: missing %v, unexpected %v\n", d.height, d.missing, d.unexpected)
+	}
+	if len(r.skipped) > 0 {
+		fmt.Printf("skipped %d heights with a missing block or ABCI responses: %v\n", len(r.skipped), r.skipped)
+	}
+}
+
+// eventReIndexVerify re-indexes the block events of the heights in args into a
+// temporary in-memory index, and compares them with the ones in
+// args.blockIndexer, which must be a kv block indexer. Nothing is written to
+// args.blockIndexer.
+func eventReIndexVerify(cmd *cobra.Command, args eventReIndexArgs) (*verifyReport, error) {
+	existingIndexer, ok := args.blockIndexer.(*blockidxkv.BlockerIndexer)
+	if !ok {
+		return nil, fmt.Errorf("verify only supports the kv block indexer, got %T", args.blockIndexer)
+	}
+	tmpIndexer := blockidxkv.New(dbm.NewMemDB())
+
+	var bar progressbar.Bar
+	bar.NewOption(args.startHeight-1, args.endHeight)
+
+	fmt.Println("start verifying block events:")
+	defer bar.Finish()
+	report := &verifyReport{}
+	var heights []int64
+	for height := args.startHeight; height <= args.endHeight; height++ {
+		select {
+		case <-cmd.Context().Done():
+			return nil, fmt.Errorf("event re-index verify terminated at height %d: %w", height, cmd.Context().Err())
+		default:
+			block := args.blockStore.LoadBlock(height)
+			if block == nil {
+				err := fmt.Errorf("not able to load block at height %d from the blockstore", height)
+				if !args.skipMissing {
+					return nil, err
 				}
+				logger.Error("Skipping height", "height", height, "err", err)
+				report.skipped = append(report.skipped, height)
+				break
+			}
+
+			if args.filter != nil && !args.filter.Matches(block) {
+				// skip this height
+				break
+			}
 
-				if err := args.txIndexer.AddBatch(batch); err != nil {
-					return fmt.Errorf("tx event re-index at height %d failed: %w", height, err)
+			resp, err := args.stateStore.LoadFinalizeBlockResponse(height)
+			if err != nil {
+				err := fmt.Errorf("not able to load ABCI Response at height %d from the statestore: %w", height, err)
+				if !args.skipMissing {
+					return nil, err
 				}
+				logger.Error("Skipping height", "height", height, "err", err)
+				report.skipped = append(report.skipped, height)
+				break
 			}
 
-			if err := args.blockIndexer.Index(e); err != nil {
-				return fmt.Errorf("block event re-index at height %d failed: %w", height, err)
+			if err := tmpIndexer.Index(types.EventDataNewBlockEvents{Height: height, Events: resp.Events}); err != nil {
+				return nil, fmt.Errorf("block event re-index at height %d failed: %w", height, err)
 			}
+			heights = append(heights, height)
 		}
 
 		bar.Play(height)
 	}
 
-	return nil
+	existing, err := existingIndexer.IndexedEvents(args.startHeight, args.endHeight)
+	if err != nil {
+		return nil, fmt.Errorf("reading the existing block index: %w", err)
+	}
+	reindexed, err := tmpIndexer.IndexedEvents(args.startHeight, args.endHeight)
+	if err != nil {
+		return nil, fmt.Errorf("reading the re-indexed block events: %w", err)
+	}
+
+	for _, height := range heights {
+		report.compared++
+		if d, ok := compareIndexedEvents(height, existing[height], reindexed[height]); ok {
+			report.divergences = append(report.divergences, d)
+		}
+	}
+	return report, nil
+}
+
+// compareIndexedEvents compares the events indexed at height in the existing
+// index with the re-indexed ones, as returned by IndexedEvents. It returns
+// false if they are the same.
+func compareIndexedEvents(height int64, existing, reindexed map[string]int) (verifyDivergence, bool) {
+	d := verifyDivergence{height: height}
+	if existing == nil {
+		d.notIndexed = true
+		return d, true
+	}
+	for event, n := range reindexed {
+		for i := existing[event]; i < n; i++ {
+			d.missing = append(d.missing, event)
+		}
+	}
+	for event, n := range existing {
+		for i := reindexed[event]; i < n; i++ {
+			d.unexpected = append(d.unexpected, event)
+		}
+	}
+	if len(d.missing) == 0 && len(d.unexpected) == 0 {
+		return d, false
+	}
+	sort.Strings(d.missing)
+	sort.Strings(d.unexpected)
+	return d, true
 }
 
 func checkValidHeight(bs state.BlockStore) error {
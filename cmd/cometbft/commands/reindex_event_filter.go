@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+// blockFilterOps lists the supported operators. Two-character operators come
+// first so that e.g. ">=" is not parsed as ">".
+var blockFilterOps = []string{">=", "<=", "!=", "=", ">", "<"}
+
+// blockFilter is a predicate over block metadata used by reindex-event to
+// select which heights get reindexed. It is parsed from expressions of the
+// form "<field><op><value>", for example "num_txs>0" or "proposer=ABCD...".
+//
+// Supported fields:
+//   - height: the block height (all operators)
+//   - num_txs: the number of txs in the block (all operators)
+//   - proposer: the hex-encoded proposer address (= and != only)
+type blockFilter struct {
+	field string
+	op    string
+
+	intValue   int64
+	bytesValue []byte
+}
+
+func parseBlockFilter(expr string) (*blockFilter, error) {
+	expr = strings.TrimSpace(expr)
+	for _, op := range blockFilterOps {
+		idx := strings.Index(expr, op)
+		if idx < 0 {
+			continue
+		}
+		f := &blockFilter{
+			field: strings.ToLower(strings.TrimSpace(expr[:idx])),
+			op:    op,
+		}
+		value := strings.TrimSpace(expr[idx+len(op):])
+		if value == "" {
+			return nil, fmt.Errorf("missing value in filter expression %q", expr)
+		}
+
+		switch f.field {
+		case "height", "num_txs":
+			v, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s in filter expression %q: %w", f.field, expr, err)
+			}
+			f.intValue = v
+		case "proposer":
+			if op != "=" && op != "!=" {
+				return nil, fmt.Errorf("operator %s is not supported for proposer, use = or !=", op)
+			}
+			v, err := hex.DecodeString(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proposer address in filter expression %q: %w", expr, err)
+			}
+			f.bytesValue = v
+		default:
+			return nil, fmt.Errorf("unsupported field %q in filter expression %q (supported: height, num_txs, proposer)",
+				f.field, expr)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("no operator found in filter expression %q (supported: %s)",
+		expr, strings.Join(blockFilterOps, " "))
+}
+
+// Matches returns true if the block satisfies the filter.
+func (f *blockFilter) Matches(block *types.Block) bool {
+	switch f.field {
+	case "height":
+		return compareInt(block.Height, f.op, f.intValue)
+	case "num_txs":
+		return compareInt(int64(len(block.Txs)), f.op, f.intValue)
+	case "proposer":
+		equal := bytes.Equal(block.ProposerAddress, f.bytesValue)
+		if f.op == "=" {
+			return equal
+		}
+		return !equal
+	}
+	return false
+}
+
+func compareInt(a int64, op string, b int64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	cmtos "github.com/cometbft/cometbft/libs/os"
+	"github.com/cometbft/cometbft/p2p"
+)
+
+// RotateNodeKeyCmd replaces the node key with a new one, keeping a backup of
+// the old key next to it.
+var RotateNodeKeyCmd = &cobra.Command{
+	Use:     "rotate-node-key",
+	Aliases: []string{"rotate_node_key"},
+	Short:   "Replace the node key with a new one, keeping a backup of the old key",
+	Long: `Replace the node key with a new one, keeping a backup of the old key.
+
+The old key is copied to node_key.json.bak-<timestamp> before being replaced.
+WARNING: the node ID changes, so peers referring to the node by its old ID
+(e.g. in persistent_peers) can no longer connect to it.`,
+	RunE: rotateNodeKey,
+}
+
+func rotateNodeKey(*cobra.Command, []string) error {
+	nodeKeyFile := config.NodeKeyFile()
+	oldKey, newKey, backupFile, err := rotateNodeKeyFile(nodeKeyFile, time.Now())
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "WARNING: the node ID changed from %s to %s; "+
+		"update the peers which refer to this node by its ID\n", oldKey.ID(), newKey.ID())
+	logger.Info("Rotated node key", "file", nodeKeyFile, "backup", backupFile)
+	fmt.Println("old:", oldKey.ID())
+	fmt.Println("new:", newKey.ID())
+	return nil
+}
+
+// rotateNodeKeyFile backs up the node key in nodeKeyFile to
+// nodeKeyFile.bak-<now> and replaces it with a newly generated key. It returns
+// an error without changing anything if the backup file already exists.
+func rotateNodeKeyFile(nodeKeyFile string, now time.Time) (oldKey, newKey *p2p.NodeKey, backupFile string, err error) {
+	if !cmtos.FileExists(nodeKeyFile) {
+		return nil, nil, "", fmt.Errorf("node key at %s does not exist", nodeKeyFile)
+	}
+	oldKey, err = p2p.LoadNodeKey(nodeKeyFile)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to load node key at %s: %w", nodeKeyFile, err)
+	}
+	bz, err := os.ReadFile(nodeKeyFile)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	backupFile = nodeKeyFile + ".bak-" + now.UTC().Format("20060102T150405Z")
+	f, err := os.OpenFile(backupFile, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, nil, "", fmt.Errorf("backup %s already exists, refusing to overwrite it", backupFile)
+	} else if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to create backup %s: %w", backupFile, err)
+	}
+	_, err = f.Write(bz)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to write backup %s: %w", backupFile, err)
+	}
+
+	if err := os.Remove(nodeKeyFile); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to remove node key at %s: %w", nodeKeyFile, err)
+	}
+	newKey, err = p2p.LoadOrGenNodeKey(nodeKeyFile)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to generate node key (the old key is in %s): %w", backupFile, err)
+	}
+	return oldKey, newKey, backupFile, nil
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/cometbft/cometbft/p2p"
+)
+
+func TestRotateNodeKeyFile(t *testing.T) {
+	nodeKeyFile := filepath.Join(t.TempDir(), "node_key.json")
+	_, _, _, err := rotateNodeKeyFile(nodeKeyFile, time.Now())
+	require.Error(t, err, "there is no key to rotate")
+
+	oldKey, err := p2p.LoadOrGenNodeKey(nodeKeyFile)
+	require.NoError(t, err)
+	oldContent, err := os.ReadFile(nodeKeyFile)
+	require.NoError(t, err)
+
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	rotatedOld, newKey, backupFile, err := rotateNodeKeyFile(nodeKeyFile, now)
+	require.NoError(t, err)
+	require.Equal(t, nodeKeyFile+".bak-20240506T070809Z", backupFile)
+	require.Equal(t, oldKey.ID(), rotatedOld.ID())
+	require.NotEqual(t, oldKey.ID(), newKey.ID())
+
+	// the backup is the pre-rotation key
+	backupContent, err := os.ReadFile(backupFile)
+	require.NoError(t, err)
+	require.Equal(t, oldContent, backupContent)
+	backupKey, err := p2p.LoadNodeKey(backupFile)
+	require.NoError(t, err)
+	require.Equal(t, oldKey.ID(), backupKey.ID())
+
+	// the new key is saved
+	savedKey, err := p2p.LoadNodeKey(nodeKeyFile)
+	require.NoError(t, err)
+	require.Equal(t, newKey.ID(), savedKey.ID())
+
+	// an existing backup is not overwritten, and the key is left untouched
+	_, _, _, err = rotateNodeKeyFile(nodeKeyFile, now)
+	require.ErrorContains(t, err, "already exists")
+	backupContent, err = os.ReadFile(backupFile)
+	require.NoError(t, err)
+	require.Equal(t, oldContent, backupContent)
+	savedKey, err = p2p.LoadNodeKey(nodeKeyFile)
+	require.NoError(t, err)
+	require.Equal(t, newKey.ID(), savedKey.ID())
+}
@@ -17,9 +17,10 @@ var ShowNodeIDCmd = &cobra.Command{
 }
 
 func showNodeID(*cobra.Command, []string) error {
-	nodeKey, err := p2p.LoadNodeKey(config.NodeKeyFile())
+	nodeKeyFile := config.NodeKeyFile()
+	nodeKey, err := p2p.LoadNodeKey(nodeKeyFile)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to load node key at %s: %w", nodeKeyFile, err)
 	}
 
 	fmt.Println(nodeKey.ID())
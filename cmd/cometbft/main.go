@@ -27,6 +27,9 @@ func main() {
 		cmd.ShowNodeIDCmd,
 		cmd.ReIndexEventCmd,
 		cmd.GenNodeKeyCmd,
+		cmd.RotateNodeKeyCmd,
+		cmd.ImportEvidenceCmd,
+		cmd.ExportEvidenceCmd,
 		cmd.VersionCmd,
 		cmd.RollbackStateCmd,
 		cmd.CompactGoLevelDBCmd,
@@ -397,6 +397,24 @@ type RPCConfig struct {
 	// See https://github.com/tendermint/tendermint/issues/3435
 	TimeoutBroadcastTxCommit time.Duration `mapstructure:"timeout_broadcast_tx_commit"`
 
+	// How long to wait for the application to answer /abci_query and
+	// /abci_info before giving up. 0 means no timeout.
+	ABCIQueryTimeout time.Duration `mapstructure:"abci_query_timeout"`
+
+	// How long /abci_info serves a cached response of the application
+	// before querying it again. The cache is also cleared on every new
+	// block. 0 disables the cache.
+	ABCIInfoCacheTTL time.Duration `mapstructure:"abci_info_cache_ttl"`
+
+	// Query paths (or path prefixes) reported by the abci_query latency
+	// metric. Any other path is reported as "other", to bound the number of
+	// label values.
+	ABCIQueryMetricsPaths []string `mapstructure:"abci_query_metrics_paths"`
+
+	// Maximum size, in bytes, of the value and proof of an /abci_query
+	// response. Larger responses are replaced by an error. 0 means no limit.
+	MaxQueryResponseBytes int64 `mapstructure:"max_query_response_bytes"`
+
 	// Maximum number of requests that can be sent in a batch
 	// https://www.jsonrpc.org/specification#batch
 	MaxRequestBatchSize int `mapstructure:"max_request_batch_size"`
@@ -435,6 +453,7 @@ func DefaultRPCConfig() *RPCConfig {
 	return &RPCConfig{
 		ListenAddress:          "tcp://127.0.0.1:26657",
 		CORSAllowedOrigins:     []string{},
+		ABCIQueryMetricsPaths:  []string{},
 		CORSAllowedMethods:     []string{http.MethodHead, http.MethodGet, http.MethodPost},
 		CORSAllowedHeaders:     []string{"Origin", "Accept", "Content-Type", "X-Requested-With", "X-Server-Time"},
 		GRPCListenAddress:      "",
@@ -453,6 +472,8 @@ func DefaultRPCConfig() *RPCConfig {
 		MaxBodyBytes:        int64(1000000), // 1MB
 		MaxHeaderBytes:      1 << 20,        // same as the net/http default
 
+		MaxQueryResponseBytes: int64(100000000), // 100MB
+
 		TLSCertFile: "",
 		TLSKeyFile:  "",
 	}
@@ -495,6 +516,15 @@ func (cfg *RPCConfig) ValidateBasic() error {
 	if cfg.TimeoutBroadcastTxCommit < 0 {
 		return cmterrors.ErrNegativeField{Field: "timeout_broadcast_tx_commit"}
 	}
+	if cfg.ABCIQueryTimeout < 0 {
+		return cmterrors.ErrNegativeField{Field: "abci_query_timeout"}
+	}
+	if cfg.ABCIInfoCacheTTL < 0 {
+		return cmterrors.ErrNegativeField{Field: "abci_info_cache_ttl"}
+	}
+	if cfg.MaxQueryResponseBytes < 0 {
+		return cmterrors.ErrNegativeField{Field: "max_query_response_bytes"}
+	}
 	if cfg.MaxRequestBatchSize < 0 {
 		return errors.New("max_request_batch_size can't be negative")
 	}
@@ -80,6 +80,9 @@ func TestRPCConfigValidateBasic(t *testing.T) {
 		"MaxSubscriptionClients",
 		"MaxSubscriptionsPerClient",
 		"TimeoutBroadcastTxCommit",
+		"ABCIQueryTimeout",
+		"ABCIInfoCacheTTL",
+		"MaxQueryResponseBytes",
 		"MaxBodyBytes",
 		"MaxHeaderBytes",
 		"MaxRequestBatchSize",
@@ -232,6 +232,27 @@ experimental_close_on_slow_client = {{ .RPC.CloseOnSlowClient }}
 # See https://github.com/tendermint/tendermint/issues/3435
 timeout_broadcast_tx_commit = "{{ .RPC.TimeoutBroadcastTxCommit }}"
 
+# How long to wait for the application to answer /abci_query and /abci_info.
+# If the value is set to '0' (zero-value), the request waits until the
+# client disconnects.
+abci_query_timeout = "{{ .RPC.ABCIQueryTimeout }}"
+
+# How long /abci_info serves a cached response of the application before
+# querying it again. The cache is also cleared on every new block.
+# If the value is set to '0' (zero-value), the cache is disabled.
+abci_info_cache_ttl = "{{ .RPC.ABCIInfoCacheTTL }}"
+
+# Query paths (or path prefixes) reported individually by the abci_query
+# latency metric, e.g. ["/store/bank", "/app/version"]. The longest matching
+# prefix is used; any other path is reported as "other".
+abci_query_metrics_paths = [{{ range .RPC.ABCIQueryMetricsPaths }}{{ printf "%q, " . }}{{end}}]
+
+# Maximum size, in bytes, of the value and proof of an /abci_query response.
+# Larger responses are replaced by an error, so that the application cannot
+# make the node hold and send arbitrarily large responses.
+# If the value is set to '0' (zero-value), the size is not limited.
+max_query_response_bytes = {{ .RPC.MaxQueryResponseBytes }}
+
 # Maximum number of requests that can be sent in a batch
 # If the value is set to '0' (zero-value), then no maximum batch size will be
 # enforced for a JSON-RPC batch request.
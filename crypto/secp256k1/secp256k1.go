@@ -23,6 +23,8 @@ const (
 
 	KeyType     = "secp256k1"
 	PrivKeySize = 32
+	// SignatureSize is the size of a signature in the R || S form.
+	SignatureSize = 64
 )
 
 func init() {
@@ -188,7 +190,7 @@ func (pubKey PubKey) Type() string {
 // VerifySignature verifies a signature of the form R || S.
 // It rejects signatures which are not in lower-S form.
 func (pubKey PubKey) VerifySignature(msg []byte, sigStr []byte) bool {
-	if len(sigStr) != 64 {
+	if len(sigStr) != SignatureSize {
 		return false
 	}
 
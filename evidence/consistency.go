@@ -0,0 +1,143 @@
+package evidence
+
+import (
+	"fmt"
+	"strconv"
+
+	dbm "github.com/cometbft/cometbft-db"
+)
+
+// CommittedEvidenceRef identifies committed evidence by its height and hash,
+// along with the height of the block which includes it, if known.
+type CommittedEvidenceRef struct {
+	Height      int64
+	Hash        []byte
+	BlockHeight int64 // 0 if the evidence was not found in the block store
+}
+
+// ConsistencyReport lists the differences between the committed evidence
+// recorded in the evidence store and the evidence included in the blocks.
+type ConsistencyReport struct {
+	// NotMarkedCommitted is the evidence included in a block of the range
+	// which the evidence store does not record as committed.
+	NotMarkedCommitted []CommittedEvidenceRef
+	// NotInBlockStore is the evidence recorded as committed in the evidence
+	// store, with a height in the range, which no block includes.
+	NotInBlockStore []CommittedEvidenceRef
+	// MissingBlocks are the heights of the blocks which were looked for but
+	// not found in the block store, e.g. because they were pruned. Evidence
+	// committed in those blocks may be reported in NotInBlockStore.
+	MissingBlocks []int64
+	// PrunedBelow is the height below which the committed evidence entries
+	// were removed by WithCommittedEvidenceRetention, or 0. The evidence of
+	// those heights is not reported in NotMarkedCommitted.
+	PrunedBelow int64
+}
+
+// Consistent returns true if no differences were found.
+func (r *ConsistencyReport) Consistent() bool {
+	return len(r.NotMarkedCommitted) == 0 && len(r.NotInBlockStore) == 0
+}
+
+// CheckConsistency cross-checks, without modifying them, the committed
+// evidence recorded in evidenceDB and the evidence included in the blocks of
+// blockStore from fromHeight to toHeight (inclusive):
+//   - every evidence included in those blocks must be recorded as committed,
+//     under the key markEvidenceAsCommitted uses;
+//   - every evidence recorded as committed with a height in the range must be
+//     included in a block. Evidence is included in a block after its own
+//     height, so the blocks past toHeight are searched as well, up to the
+//     block store height, until all of it is found.
+//
+// The evidence of the heights whose committed entries were removed by
+// WithCommittedEvidenceRetention is not expected to be recorded.
+func CheckConsistency(evidenceDB dbm.DB, blockStore BlockStore, fromHeight, toHeight int64) (*ConsistencyReport, error) {
+	if fromHeight <= 0 || fromHeight > toHeight {
+		return nil, fmt.Errorf("invalid height range [%d, %d]", fromHeight, toHeight)
+	}
+	storeHeight := blockStore.Height()
+	if toHeight > storeHeight {
+		return nil, fmt.Errorf("height %d is above the block store height %d", toHeight, storeHeight)
+	}
+
+	prunedBelow, err := committedPrunedHeight(evidenceDB)
+	if err != nil {
+		return nil, err
+	}
+
+	// the committed evidence with a height in the range, by hash
+	committed := make(map[string]*CommittedEvidenceRef)
+	var order []*CommittedEvidenceRef
+	iter, err := dbm.IteratePrefix(evidenceDB, []byte{baseKeyCommitted})
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		height, hash, err := parseKeySuffix(iter.Key()[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid committed evidence key %q: %w", iter.Key(), err)
+		}
+		if height < fromHeight || height > toHeight {
+			continue
+		}
+		ref := &CommittedEvidenceRef{Height: height, Hash: hash}
+		committed[string(hash)] = ref
+		order = append(order, ref)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	report := &ConsistencyReport{PrunedBelow: prunedBelow}
+	remaining := len(committed)
+	for height := fromHeight; height <= toHeight || (remaining > 0 && height <= storeHeight); height++ {
+		block := blockStore.LoadBlock(height)
+		if block == nil {
+			report.MissingBlocks = append(report.MissingBlocks, height)
+			continue
+		}
+		for _, ev := range block.Evidence.Evidence {
+			hash := ev.Hash()
+			if ref, ok := committed[string(hash)]; ok && ref.BlockHeight == 0 {
+				ref.BlockHeight = height
+				remaining--
+			}
+			if height > toHeight || ev.Height() < prunedBelow {
+				continue
+			}
+			ok, err := evidenceDB.Has(keyCommittedWithHash(ev.Height(), hash))
+			if err != nil {
+				return nil, fmt.Errorf("database error: %v", err)
+			}
+			if !ok {
+				report.NotMarkedCommitted = append(report.NotMarkedCommitted,
+					CommittedEvidenceRef{Height: ev.Height(), Hash: hash, BlockHeight: height})
+			}
+		}
+	}
+
+	for _, ref := range order {
+		if ref.BlockHeight == 0 {
+			report.NotInBlockStore = append(report.NotInBlockStore, *ref)
+		}
+	}
+	return report, nil
+}
+
+// committedPrunedHeight returns the height below which the committed evidence
+// entries were removed by WithCommittedEvidenceRetention, or 0 if none were.
+func committedPrunedHeight(evidenceDB dbm.DB) (int64, error) {
+	bz, err := evidenceDB.Get([]byte{keyCommittedPruned})
+	if err != nil {
+		return 0, fmt.Errorf("database error: %v", err)
+	}
+	if bz == nil {
+		return 0, nil
+	}
+	height, err := strconv.ParseInt(string(bz), 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid pruned committed evidence height %q: %w", bz, err)
+	}
+	return height, nil
+}
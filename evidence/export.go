@@ -0,0 +1,135 @@
+package evidence
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	dbm "github.com/cometbft/cometbft-db"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+// ExportEvidence reads the evidence stored in evidenceDB without modifying it:
+// the pending evidence if pending is set, followed by the committed evidence
+// if committed is set, each from oldest to newest.
+//
+// The evidence database only records the height and hash of committed
+// evidence, so committed evidence is looked up in the blocks of blockStore
+// following its height. The hashes of the committed evidence which could not
+// be found, e.g. because the blocks were pruned, are returned in missing.
+// blockStore is only used, and can be nil, if committed is not set.
+func ExportEvidence(
+	evidenceDB dbm.DB,
+	blockStore BlockStore,
+	pending, committed bool,
+) (evList []types.Evidence, missing [][]byte, err error) {
+	if pending {
+		iter, err := dbm.IteratePrefix(evidenceDB, []byte{baseKeyPending})
+		if err != nil {
+			return nil, nil, fmt.Errorf("database error: %v", err)
+		}
+		defer iter.Close()
+		for ; iter.Valid(); iter.Next() {
+			ev, err := bytesToEv(iter.Value())
+			if err != nil {
+				return nil, nil, fmt.Errorf("decoding pending evidence %q: %w", iter.Key(), err)
+			}
+			evList = append(evList, ev)
+		}
+		if err := iter.Error(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if committed {
+		committedEv, missing, err := exportCommittedEvidence(evidenceDB, blockStore)
+		if err != nil {
+			return nil, nil, err
+		}
+		return append(evList, committedEv...), missing, nil
+	}
+	return evList, nil, nil
+}
+
+// exportCommittedEvidence loads the committed evidence recorded in evidenceDB
+// from blockStore, in a single pass over the blocks following the height of
+// the oldest committed evidence.
+func exportCommittedEvidence(evidenceDB dbm.DB, blockStore BlockStore) ([]types.Evidence, [][]byte, error) {
+	var (
+		hashes    [][]byte
+		minHeight int64
+	)
+	iter, err := dbm.IteratePrefix(evidenceDB, []byte{baseKeyCommitted})
+	if err != nil {
+		return nil, nil, fmt.Errorf("database error: %v", err)
+	}
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		height, hash, err := parseKeySuffix(iter.Key()[1:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid committed evidence key %q: %w", iter.Key(), err)
+		}
+		if len(hashes) == 0 || height < minHeight {
+			minHeight = height
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+	if len(hashes) == 0 {
+		return nil, nil, nil
+	}
+
+	found := make(map[string]types.Evidence, len(hashes))
+	for _, hash := range hashes {
+		found[string(hash)] = nil
+	}
+	// evidence is committed in a block after its own height
+	remaining := len(hashes)
+	for h := minHeight + 1; h <= blockStore.Height() && remaining > 0; h++ {
+		block := blockStore.LoadBlock(h)
+		if block == nil {
+			continue
+		}
+		for _, ev := range block.Evidence.Evidence {
+			if prev, ok := found[string(ev.Hash())]; ok && prev == nil {
+				found[string(ev.Hash())] = ev
+				remaining--
+			}
+		}
+	}
+
+	var (
+		evList  []types.Evidence
+		missing [][]byte
+	)
+	for _, hash := range hashes {
+		if ev := found[string(hash)]; ev != nil {
+			evList = append(evList, ev)
+		} else {
+			missing = append(missing, hash)
+		}
+	}
+	return evList, missing, nil
+}
+
+// parseKeySuffix parses the height and hash of the evidence from a key suffix
+// created by keySuffix.
+func parseKeySuffix(suffix []byte) (int64, []byte, error) {
+	heightHex, hashHex, ok := bytes.Cut(suffix, []byte("/"))
+	if !ok {
+		return 0, nil, fmt.Errorf("missing separator")
+	}
+	height, err := strconv.ParseInt(string(heightHex), 16, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid height: %w", err)
+	}
+	hash, err := hex.DecodeString(string(hashHex))
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid hash: %w", err)
+	}
+	return height, hash, nil
+}
@@ -0,0 +1,26 @@
+package evidence
+
+import (
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	// MetricsSubsystem is a subsystem shared by all metrics exposed by this
+	// package.
+	MetricsSubsystem = "evidence"
+
+	// Values of the reason label of EvidenceVerificationFailures.
+	failureAlreadyCommitted = "already-committed"
+	failureDuplicate        = "duplicate"
+	failureVerifyFailed     = "verify-failed"
+)
+
+//go:generate go run ../scripts/metricsgen -struct=Metrics
+
+// Metrics contains the metrics exposed by the evidence pool.
+type Metrics struct {
+	// Number of evidence rejected by CheckEvidence or AddEvidence, by reason:
+	// already-committed (often a peer which is behind), duplicate (the same
+	// evidence twice in a block) or verify-failed (invalid evidence).
+	EvidenceVerificationFailures metrics.Counter `metrics_name:"verification_failures" metrics_labels:"reason"`
+}
@@ -2,8 +2,11 @@ package evidence
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -23,10 +26,25 @@ import (
 )
 
 const (
-	baseKeyCommitted = byte(0x00)
-	baseKeyPending   = byte(0x01)
+	baseKeyCommitted   = byte(0x00)
+	baseKeyPending     = byte(0x01)
+	baseKeyQuarantined = byte(0x02)
+	// keyCommittedPruned holds the height below which the committed evidence
+	// entries were removed by WithCommittedEvidenceRetention.
+	keyCommittedPruned = byte(0x03)
+	// baseKeyBufferedVotes prefixes the conflicting votes buffered from
+	// consensus which CloseAndFlush could not form into evidence yet.
+	baseKeyBufferedVotes = byte(0x04)
 )
 
+// ErrEvidencePoolFull is returned by AddEvidence when the pool already holds
+// the maximum amount of pending evidence set with WithMaxPendingEvidence.
+var ErrEvidencePoolFull = errors.New("evidence pool is full")
+
+// ErrCustomEvidenceNotStorable is returned by AddEvidence for custom evidence
+// (see TypedEvidence), which the pool can verify but has no way to persist.
+var ErrCustomEvidenceNotStorable = errors.New("custom evidence can be verified but not added to the pool")
+
 // Pool maintains a pool of valid evidence to be broadcasted and committed
 type Pool struct {
 	logger log.Logger
@@ -35,6 +53,14 @@ type Pool struct {
 	evidenceList  *clist.CList // concurrent linked-list of evidence
 	evidenceSize  uint32       // amount of pending evidence
 
+	// listMtx serializes the changes to the pending evidence and evidenceList
+	// with loading evidenceList, which WithLazyLoad defers until it is first
+	// used. Until then, listUnloaded is set and evidenceList stays empty.
+	lazyLoad     bool
+	listMtx      sync.Mutex
+	listUnloaded bool
+	listLoadOnce sync.Once
+
 	// needed to load validators to verify evidence
 	stateDB sm.Store
 	// needed to load headers and commits to verify evidence
@@ -48,13 +74,129 @@ type Pool struct {
 	// evidence before the height with which the evidence happened is finished.
 	consensusBuffer []duplicateVoteSet
 
+	// pendingMtx serializes the removals of pending evidence (when it is
+	// committed or expires) and guards pruningHeight and pruningTime.
+	pendingMtx    sync.Mutex
 	pruningHeight int64
 	pruningTime   time.Time
+
+	// optional source of light blocks for heights missing from the block store
+	lightBlockFetcher LightBlockFetcher
+	lightBlockCache   *lightBlockCache
+
+	// maximum amount of pending evidence, 0 means unlimited
+	maxPendingEvidence uint32
+
+	// maximum amount of evidence flushed from the consensus buffer per
+	// Update, 0 means unlimited
+	maxEvidencePerHeight int
+
+	// local cap on the bytes of evidence returned by PendingEvidence for a
+	// proposal, 0 means the caller's maxBytes is used
+	maxProposalEvidenceBytes int64
+
+	// number of blocks the committed evidence entries are kept for once
+	// expired, 0 means forever
+	committedRetention int64
+
+	// verifiers for custom evidence types, keyed by type URL (guarded by mtx)
+	verifiers map[string]EvidenceVerifier
+
+	// onByzantineValidator is called the first time a validator appears in
+	// pending evidence; byzantineSeen holds the addresses it was called for
+	// (both guarded by byzantineMtx)
+	byzantineMtx         sync.Mutex
+	onByzantineValidator func(types.Address, types.Evidence)
+	byzantineSeen        map[string]struct{}
+
+	metrics *Metrics
+}
+
+// PoolOption sets an optional parameter on the Pool.
+type PoolOption func(*Pool)
+
+// WithLightBlockFetcher sets a LightBlockFetcher, which is used to obtain the
+// headers and validator sets needed to verify evidence when the local stores
+// no longer have them. Fetched light blocks are cached briefly.
+func WithLightBlockFetcher(fetcher LightBlockFetcher) PoolOption {
+	return func(pool *Pool) {
+		pool.lightBlockFetcher = fetcher
+	}
+}
+
+// WithMetrics sets the metrics of the pool.
+func WithMetrics(metrics *Metrics) PoolOption {
+	return func(pool *Pool) {
+		pool.metrics = metrics
+	}
+}
+
+// WithMaxEvidencePerHeight caps how much DuplicateVoteEvidence formed from
+// the conflicting votes reported by consensus is added to the pool on each
+// Update. The remaining votes are kept in the buffer and flushed on the
+// following Updates, using the block time and validator set of their own
+// height. Zero (the default) means unlimited.
+func WithMaxEvidencePerHeight(n int) PoolOption {
+	return func(pool *Pool) {
+		pool.maxEvidencePerHeight = n
+	}
+}
+
+// WithMaxProposalEvidenceBytes caps the bytes of evidence returned by
+// PendingEvidence and PendingEvidenceWithPriority, which are used to fill
+// proposals, to n when it is below the maxBytes of the caller. This lets an
+// operator propose less evidence than the consensus params allow. A maxBytes
+// of -1, which requests all the pending evidence, is not capped. Zero (the
+// default) means the caller's maxBytes is used.
+func WithMaxProposalEvidenceBytes(n int64) PoolOption {
+	return func(pool *Pool) {
+		pool.maxProposalEvidenceBytes = n
+	}
+}
+
+// WithMaxPendingEvidence caps the amount of pending evidence. Once Size()
+// reaches n, AddEvidence rejects new evidence with ErrEvidencePoolFull.
+// Zero (the default) means unlimited.
+//
+// Evidence included in a block is still accepted by CheckEvidence when the pool
+// is full, as block validity must not depend on local state; it is simply not
+// added to the pending list.
+func WithMaxPendingEvidence(n uint32) PoolOption {
+	return func(pool *Pool) {
+		pool.maxPendingEvidence = n
+	}
+}
+
+// WithCommittedEvidenceRetention removes, on each Update, the committed
+// evidence entries of the heights more than maxAgeBlocks below the last block
+// height. Committed evidence is recorded by height and hash only, so that it
+// is not added to the pool again, but on long-running chains the entries
+// otherwise accumulate forever. Entries are only removed once evidence of
+// their height has expired under the evidence consensus params, as it is then
+// rejected anyway; until then, they are kept past maxAgeBlocks. Zero (the
+// default) means committed evidence entries are kept forever. The height below
+// which entries were removed is recorded, so that CheckConsistency does not
+// report them as missing.
+func WithCommittedEvidenceRetention(maxAgeBlocks int64) PoolOption {
+	return func(pool *Pool) {
+		pool.committedRetention = maxAgeBlocks
+	}
+}
+
+// WithLazyLoad defers loading the pending evidence of an existing evidence
+// store into the list of evidence to gossip until the list is first used,
+// through EvidenceFront, EvidenceWaitChan or PendingEvidence. The evidence is
+// then loaded in the background. Size is accurate from the start.
+func WithLazyLoad() PoolOption {
+	return func(pool *Pool) {
+		pool.lazyLoad = true
+	}
 }
 
 // NewPool creates an evidence pool. If using an existing evidence store,
-// it will add all pending evidence to the concurrent list.
-func NewPool(evidenceDB dbm.DB, stateDB sm.Store, blockStore BlockStore) (*Pool, error) {
+// it will add all pending evidence to the concurrent list, unless the pool
+// is created WithLazyLoad.
+func NewPool(evidenceDB dbm.DB, stateDB sm.Store, blockStore BlockStore, options ...PoolOption) (*Pool, error) {
 	state, err := stateDB.Load()
 	if err != nil {
 		return nil, fmt.Errorf("cannot load state: %w", err)
@@ -68,11 +210,35 @@ func NewPool(evidenceDB dbm.DB, stateDB sm.Store, blockStore BlockStore) (*Pool,
 		evidenceStore:   evidenceDB,
 		evidenceList:    clist.New(),
 		consensusBuffer: make([]duplicateVoteSet, 0),
+		lightBlockCache: newLightBlockCache(),
+		metrics:         NopMetrics(),
+	}
+
+	for _, option := range options {
+		option(pool)
+	}
+
+	if err := pool.loadBufferedVotes(); err != nil {
+		return nil, err
 	}
 
 	// if pending evidence already in db, in event of prior failure, then check for expiration,
 	// update the size and load it back to the evidenceList
-	pool.pruningHeight, pool.pruningTime = pool.removeExpiredPendingEvidence()
+	_, pool.pruningHeight, pool.pruningTime = pool.removeExpiredPendingEvidence()
+	if pool.lazyLoad {
+		var count uint32
+		err := iteratePrefix(evidenceDB, baseKeyPending, func(_, _ []byte) error {
+			count++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		atomic.StoreUint32(&pool.evidenceSize, count)
+		pool.listUnloaded = true
+		return pool, nil
+	}
+
 	evList, _, err := pool.listEvidence(baseKeyPending, -1)
 	if err != nil {
 		return nil, err
@@ -85,15 +251,94 @@ func NewPool(evidenceDB dbm.DB, stateDB sm.Store, blockStore BlockStore) (*Pool,
 	return pool, nil
 }
 
+// loadEvidenceList starts loading the pending evidence into evidenceList in
+// the background, if the pool was created WithLazyLoad and it wasn't
+// started yet.
+func (evpool *Pool) loadEvidenceList() {
+	if !evpool.lazyLoad {
+		return
+	}
+	evpool.listLoadOnce.Do(func() {
+		go func() {
+			evpool.listMtx.Lock()
+			defer evpool.listMtx.Unlock()
+
+			evList, _, err := evpool.listEvidence(baseKeyPending, -1)
+			if err != nil {
+				evpool.logger.Error("Unable to load pending evidence", "err", err)
+			}
+			for _, ev := range evList {
+				evpool.evidenceList.PushBack(ev)
+			}
+			evpool.listUnloaded = false
+			evpool.logger.Info("Loaded pending evidence", "count", len(evList))
+		}()
+	})
+}
+
+// pushEvidence adds ev, which was just stored as pending evidence, to
+// evidenceList. If the list is not loaded yet, ev is left for
+// loadEvidenceList to find in the store instead. The caller must hold
+// listMtx.
+func (evpool *Pool) pushEvidence(ev types.Evidence) {
+	if evpool.listUnloaded {
+		return
+	}
+	evpool.evidenceList.PushBack(ev)
+}
+
 // PendingEvidence is used primarily as part of block proposal and returns up to maxNum of uncommitted evidence.
+// The evidence is returned from oldest to newest.
 func (evpool *Pool) PendingEvidence(maxBytes int64) ([]types.Evidence, int64) {
-	if evpool.Size() == 0 {
+	evpool.loadEvidenceList()
+	return evpool.PendingEvidenceWithPriority(maxBytes, func(types.Evidence) int { return 0 })
+}
+
+// PendingEvidenceWithPriority is like PendingEvidence, but orders the pending
+// evidence by decreasing priority before applying the maxBytes cap, so that a
+// proposer short of space includes the evidence it cares most about (e.g.
+// LightClientAttackEvidence over DuplicateVoteEvidence). Evidence of equal
+// priority stays ordered from oldest to newest.
+//
+// priority must be deterministic and depend only on the evidence it is given,
+// so that the order does not depend on the node or on when it is called.
+func (evpool *Pool) PendingEvidenceWithPriority(
+	maxBytes int64,
+	priority func(types.Evidence) int,
+) ([]types.Evidence, int64) {
+	if evpool.isEmpty() {
 		return []types.Evidence{}, 0
 	}
-	evidence, size, err := evpool.listEvidence(baseKeyPending, maxBytes)
+	if evpool.maxProposalEvidenceBytes > 0 && maxBytes > evpool.maxProposalEvidenceBytes {
+		maxBytes = evpool.maxProposalEvidenceBytes
+	}
+	candidates, _, err := evpool.listEvidence(baseKeyPending, -1)
 	if err != nil {
 		evpool.logger.Error("Unable to retrieve pending evidence", "err", err)
 	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return priority(candidates[i]) > priority(candidates[j])
+	})
+
+	var (
+		evidence []types.Evidence
+		size     int64
+		evList   cmtproto.EvidenceList // used for calculating the bytes size
+	)
+	for _, ev := range candidates {
+		evpb, err := types.EvidenceToProto(ev)
+		if err != nil {
+			evpool.logger.Error("Unable to convert pending evidence to protobuf", "err", err)
+			continue
+		}
+		evList.Evidence = append(evList.Evidence, *evpb)
+		evSize := int64(evList.Size())
+		if maxBytes != -1 && evSize > maxBytes {
+			break
+		}
+		size = evSize
+		evidence = append(evidence, ev)
+	}
 	return evidence, size
 }
 
@@ -122,47 +367,134 @@ func (evpool *Pool) Update(state sm.State, ev types.EvidenceList) {
 	// update state
 	evpool.updateState(state)
 
+	evpool.pendingMtx.Lock()
+	defer evpool.pendingMtx.Unlock()
+
 	// move committed evidence out from the pending pool and into the committed pool
 	evpool.markEvidenceAsCommitted(ev)
 
 	// prune pending evidence when it has expired. This also updates when the next evidence will expire
-	if evpool.Size() > 0 && state.LastBlockHeight > evpool.pruningHeight &&
+	if !evpool.isEmpty() && state.LastBlockHeight > evpool.pruningHeight &&
 		state.LastBlockTime.After(evpool.pruningTime) {
-		evpool.pruningHeight, evpool.pruningTime = evpool.removeExpiredPendingEvidence()
+		_, evpool.pruningHeight, evpool.pruningTime = evpool.removeExpiredPendingEvidence()
+	}
+
+	// prune the committed evidence entries past their retention
+	if evpool.committedRetention > 0 {
+		evpool.pruneCommittedEvidence(state.LastBlockHeight - evpool.committedRetention)
+	}
+}
+
+// PruneExpired immediately removes all expired pending evidence, instead of
+// waiting for Update to do so once the next pruning height and time have
+// passed. It returns the number of pruned evidence and the height and time
+// at which the oldest remaining evidence will expire.
+func (evpool *Pool) PruneExpired() (prunedCount int, nextHeight int64, nextTime time.Time) {
+	evpool.pendingMtx.Lock()
+	defer evpool.pendingMtx.Unlock()
+	if evpool.isEmpty() {
+		// same as removeExpiredPendingEvidence finding no evidence, without
+		// iterating over the store
+		params := evpool.expiryParams()
+		evpool.pruningHeight, evpool.pruningTime = params.lastBlockHeight, params.lastBlockTime
+		return 0, evpool.pruningHeight, evpool.pruningTime
 	}
+	prunedCount, evpool.pruningHeight, evpool.pruningTime = evpool.removeExpiredPendingEvidence()
+	return prunedCount, evpool.pruningHeight, evpool.pruningTime
+}
+
+// ReplayFromBlockStore marks the evidence included in the blocks from
+// fromHeight to toHeight (inclusive) as committed, removing it from the pending
+// evidence if needed. It is used to rebuild the committed evidence index when
+// the evidence database fell behind the block store, e.g. after state sync.
+// Blocks missing from the block store (pruned or not yet synced) are skipped,
+// and evidence that is already marked as committed is left untouched, so it is
+// safe to replay over a partially populated database. It may be called while
+// the node is running.
+func (evpool *Pool) ReplayFromBlockStore(fromHeight, toHeight int64) error {
+	if fromHeight <= 0 || fromHeight > toHeight {
+		return fmt.Errorf("invalid height range [%d, %d]", fromHeight, toHeight)
+	}
+	if storeHeight := evpool.blockStore.Height(); toHeight > storeHeight {
+		return fmt.Errorf("height %d is above the block store height %d", toHeight, storeHeight)
+	}
+
+	evpool.pendingMtx.Lock()
+	defer evpool.pendingMtx.Unlock()
+
+	var skipped, replayed int
+	for height := fromHeight; height <= toHeight; height++ {
+		block := evpool.blockStore.LoadBlock(height)
+		if block == nil {
+			skipped++
+			continue
+		}
+		if len(block.Evidence.Evidence) == 0 {
+			continue
+		}
+		evpool.markEvidenceAsCommitted(block.Evidence.Evidence)
+		replayed += len(block.Evidence.Evidence)
+	}
+
+	evpool.logger.Info("Replayed evidence from the block store", "from", fromHeight, "to", toHeight,
+		"evidence", replayed, "skipped_blocks", skipped)
+	return nil
 }
 
 // AddEvidence checks the evidence is valid and adds it to the pool.
 func (evpool *Pool) AddEvidence(ev types.Evidence) error {
 	evpool.logger.Info("Attempting to add evidence", "ev", ev)
 
+	// Only evidence with a protobuf representation can be stored, so reject
+	// custom evidence before spending time verifying it.
+	if isCustomEvidence(ev) {
+		return types.NewErrInvalidEvidence(ev, ErrCustomEvidenceNotStorable)
+	}
+
 	// We have already verified this piece of evidence - no need to do it again
 	if evpool.isPending(ev) {
 		evpool.logger.Info("Evidence already pending, ignoring this one", "ev", ev)
 		return nil
 	}
 
+	// evidence held back by Quarantine is not added back by peers
+	if evpool.isQuarantined(ev) {
+		evpool.logger.Info("Evidence is quarantined, ignoring this one", "ev", ev)
+		return nil
+	}
+
 	// check that the evidence isn't already committed
 	if evpool.isCommitted(ev) {
 		// this can happen if the peer that sent us the evidence is behind so we shouldn't
 		// punish the peer.
 		evpool.logger.Info("Evidence was already committed, ignoring this one", "ev", ev)
+		evpool.countFailure(failureAlreadyCommitted)
 		return nil
 	}
 
+	if evpool.isFull() {
+		return ErrEvidencePoolFull
+	}
+
 	// 1) Verify against state.
 	err := evpool.verify(ev)
 	if err != nil {
+		evpool.logger.Info("Rejected invalid evidence", "ev", ev, "err", err)
+		evpool.countFailure(failureVerifyFailed)
 		return types.NewErrInvalidEvidence(ev, err)
 	}
 
+	evpool.listMtx.Lock()
+	defer evpool.listMtx.Unlock()
+
 	// 2) Save to store.
 	if err := evpool.addPendingEvidence(ev); err != nil {
 		return fmt.Errorf("can't add evidence to pending list: %w", err)
 	}
 
 	// 3) Add evidence to clist.
-	evpool.evidenceList.PushBack(ev)
+	evpool.pushEvidence(ev)
+	evpool.notifyByzantineValidators(ev)
 
 	evpool.logger.Info("Verified new evidence of byzantine behavior", "evidence", ev)
 
@@ -192,25 +524,63 @@ func (evpool *Pool) ReportConflictingVotes(voteA, voteB *types.Vote) {
 // evidence has already been committed or is being proposed twice. It also adds any
 // evidence that it doesn't currently have so that it can quickly form ABCI Evidence later.
 func (evpool *Pool) CheckEvidence(evList types.EvidenceList) error {
-	hashes := make([][]byte, len(evList))
-	for idx, ev := range evList {
+	return evpool.CheckEvidenceContext(context.Background(), evList)
+}
+
+// CheckEvidenceContext is like CheckEvidence, but stops and returns the
+// context's error as soon as ctx is done. The context is checked before each
+// piece of evidence.
+func (evpool *Pool) CheckEvidenceContext(ctx context.Context, evList types.EvidenceList) error {
+	// the hash of each evidence is computed once, and so is its encoding when
+	// it is added to the pending evidence
+	cache := make(marshalCache, len(evList))
+	for _, ev := range evList {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// check for duplicate evidence before doing any work on it
+		hash := ev.Hash()
+		if cache.has(hash) {
+			evpool.logger.Info("Check evidence: rejected duplicate evidence", "ev", ev)
+			evpool.countFailure(failureDuplicate)
+			return &types.ErrInvalidEvidence{Evidence: ev, Reason: errors.New("duplicate evidence")}
+		}
+		cache.add(hash)
 
 		_, isLightEv := ev.(*types.LightClientAttackEvidence)
 
 		// We must verify light client attack evidence regardless because there could be a
 		// different conflicting block with the same hash.
-		if isLightEv || !evpool.isPending(ev) {
+		pendingKey := keyPendingWithHash(ev.Height(), hash)
+		if isLightEv || !evpool.hasKey(pendingKey, "pending") {
+			// blocks are validated before their evidence is checked, but evidence
+			// may also come from elsewhere, e.g. be built in memory
+			if err := ev.ValidateBasic(); err != nil {
+				evpool.logger.Info("Check evidence: rejected invalid evidence", "ev", ev, "err", err)
+				evpool.countFailure(failureVerifyFailed)
+				return types.NewErrInvalidEvidence(ev, err)
+			}
+
 			// check that the evidence isn't already committed
-			if evpool.isCommitted(ev) {
+			if evpool.hasKey(keyCommittedWithHash(ev.Height(), hash), "committed") {
+				evpool.logger.Info("Check evidence: rejected already committed evidence", "ev", ev)
+				evpool.countFailure(failureAlreadyCommitted)
 				return &types.ErrInvalidEvidence{Evidence: ev, Reason: errors.New("evidence was already committed")}
 			}
 
 			err := evpool.verify(ev)
 			if err != nil {
+				evpool.logger.Info("Check evidence: rejected invalid evidence", "ev", ev, "err", err)
+				evpool.countFailure(failureVerifyFailed)
 				return err
 			}
 
-			if err := evpool.addPendingEvidence(ev); err != nil {
+			if evpool.isFull() {
+				evpool.logger.Info("Evidence pool is full, not adding evidence to pending list", "ev", ev)
+			} else if evBytes, err := cache.evidenceBytes(ev, hash); err != nil {
+				evpool.logger.Error("Can't add evidence to pending list", "err", err, "ev", ev)
+			} else if err := evpool.storePendingEvidence(pendingKey, evBytes); err != nil {
 				// Something went wrong with adding the evidence but we already know it is valid
 				// hence we log an error and continue
 				evpool.logger.Error("Can't add evidence to pending list", "err", err, "ev", ev)
@@ -218,26 +588,52 @@ func (evpool *Pool) CheckEvidence(evList types.EvidenceList) error {
 
 			evpool.logger.Info("Check evidence: verified evidence of byzantine behavior", "evidence", ev)
 		}
-
-		// check for duplicate evidence. We cache hashes so we don't have to work them out again.
-		hashes[idx] = ev.Hash()
-		for i := idx - 1; i >= 0; i-- {
-			if bytes.Equal(hashes[i], hashes[idx]) {
-				return &types.ErrInvalidEvidence{Evidence: ev, Reason: errors.New("duplicate evidence")}
-			}
-		}
 	}
 
 	return nil
 }
 
+// countFailure counts evidence rejected for the given reason.
+func (evpool *Pool) countFailure(reason string) {
+	evpool.metrics.EvidenceVerificationFailures.With("reason", reason).Add(1)
+}
+
+// marshalCache holds the evidence seen during a single CheckEvidence call,
+// by hash, along with their protobuf encoding once it has been computed.
+type marshalCache map[string][]byte
+
+func (c marshalCache) has(hash []byte) bool {
+	_, ok := c[string(hash)]
+	return ok
+}
+
+func (c marshalCache) add(hash []byte) {
+	c[string(hash)] = nil
+}
+
+// evidenceBytes returns the protobuf encoding of ev, whose hash is hash,
+// marshalling it only if it wasn't already.
+func (c marshalCache) evidenceBytes(ev types.Evidence, hash []byte) ([]byte, error) {
+	if evBytes := c[string(hash)]; evBytes != nil {
+		return evBytes, nil
+	}
+	evBytes, err := marshalEvidence(ev)
+	if err != nil {
+		return nil, err
+	}
+	c[string(hash)] = evBytes
+	return evBytes, nil
+}
+
 // EvidenceFront goes to the first evidence in the clist
 func (evpool *Pool) EvidenceFront() *clist.CElement {
+	evpool.loadEvidenceList()
 	return evpool.evidenceList.Front()
 }
 
 // EvidenceWaitChan is a channel that closes once the first evidence in the list is there. i.e Front is not nil
 func (evpool *Pool) EvidenceWaitChan() <-chan struct{} {
+	evpool.loadEvidenceList()
 	return evpool.evidenceList.WaitChan()
 }
 
@@ -246,11 +642,249 @@ func (evpool *Pool) SetLogger(l log.Logger) {
 	evpool.logger = l
 }
 
+// EvidenceByValidator returns the pending evidence against the validator with
+// the given address: DuplicateVoteEvidence where it cast the conflicting votes
+// and LightClientAttackEvidence listing it as a byzantine validator.
+//
+// There is no secondary index by validator, so this is O(n) over the pending
+// evidence in the store. Committed evidence is not included: the pool only
+// records its height and hash, the evidence itself is stored in the block
+// that committed it.
+func (evpool *Pool) EvidenceByValidator(addr types.Address) ([]types.Evidence, error) {
+	pending, _, err := evpool.listEvidence(baseKeyPending, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	var evidence []types.Evidence
+	for _, ev := range pending {
+		if evidenceAgainst(ev, addr) {
+			evidence = append(evidence, ev)
+		}
+	}
+	return evidence, nil
+}
+
+// SetOnByzantineValidator sets fn to be called the first time a validator
+// appears in evidence added to the pending evidence, either with AddEvidence
+// or formed from the conflicting votes reported by consensus. fn is called
+// once per validator address, with the evidence it first appeared in, e.g.
+// to raise an alert without polling the pool.
+//
+// This is best effort: the validators seen are only kept in memory, so fn is
+// called again for them after a restart, and evidence added to the pending
+// evidence by CheckEvidence or loaded from the store does not trigger it. fn
+// is called synchronously while the pool holds its locks, so it must return
+// quickly and must not call the pool.
+func (evpool *Pool) SetOnByzantineValidator(fn func(addr types.Address, ev types.Evidence)) {
+	evpool.byzantineMtx.Lock()
+	defer evpool.byzantineMtx.Unlock()
+	evpool.onByzantineValidator = fn
+}
+
+// notifyByzantineValidators calls onByzantineValidator for the validators
+// which ev is against and which were not seen before.
+func (evpool *Pool) notifyByzantineValidators(ev types.Evidence) {
+	evpool.byzantineMtx.Lock()
+	defer evpool.byzantineMtx.Unlock()
+	if evpool.onByzantineValidator == nil {
+		return
+	}
+	if evpool.byzantineSeen == nil {
+		evpool.byzantineSeen = make(map[string]struct{})
+	}
+	for _, addr := range byzantineAddresses(ev) {
+		if _, ok := evpool.byzantineSeen[string(addr)]; ok {
+			continue
+		}
+		evpool.byzantineSeen[string(addr)] = struct{}{}
+		evpool.onByzantineValidator(addr, ev)
+	}
+}
+
+// RegisterEvidenceVerifier registers v as the verifier of the custom evidence
+// type identified by typeURL (see TypedEvidence). A verifier registered
+// for the same type URL before is replaced. DuplicateVoteEvidence and
+// LightClientAttackEvidence are always verified by the pool itself.
+//
+// NOTE: the pool can only store and gossip evidence with a protobuf
+// representation in types.EvidenceFromProto; custom evidence is checked by
+// CheckEvidence but rejected by AddEvidence with ErrCustomEvidenceNotStorable.
+func (evpool *Pool) RegisterEvidenceVerifier(typeURL string, v EvidenceVerifier) {
+	evpool.mtx.Lock()
+	defer evpool.mtx.Unlock()
+	if evpool.verifiers == nil {
+		evpool.verifiers = make(map[string]EvidenceVerifier)
+	}
+	evpool.verifiers[typeURL] = v
+}
+
+// isCustomEvidence returns true if ev is not one of the natively supported
+// evidence types.
+func isCustomEvidence(ev types.Evidence) bool {
+	switch ev.(type) {
+	case *types.DuplicateVoteEvidence, *types.LightClientAttackEvidence:
+		return false
+	default:
+		return true
+	}
+}
+
+// evidenceVerifier returns the verifier registered for the type URL of the
+// evidence, or nil if there is none.
+func (evpool *Pool) evidenceVerifier(ev types.Evidence) EvidenceVerifier {
+	typed, ok := ev.(TypedEvidence)
+	if !ok {
+		return nil
+	}
+	evpool.mtx.Lock()
+	defer evpool.mtx.Unlock()
+	return evpool.verifiers[typed.TypeURL()]
+}
+
 // Size returns the number of evidence in the pool.
 func (evpool *Pool) Size() uint32 {
 	return atomic.LoadUint32(&evpool.evidenceSize)
 }
 
+// Verify checks that the evidence store is consistent with the pool: that
+// every pending and committed entry decodes, and that the amount of pending
+// evidence in the store matches Size and the list of evidence being gossiped.
+// It returns an error describing the first inconsistency found.
+//
+// Verify is meant for diagnostics on a pool which is not in use, e.g. at
+// startup: evidence added concurrently may be reported as a mismatch.
+func (evpool *Pool) Verify() error {
+	evpool.pendingMtx.Lock()
+	defer evpool.pendingMtx.Unlock()
+
+	var pending uint32
+	err := iteratePrefix(evpool.evidenceStore, baseKeyPending, func(key, value []byte) error {
+		ev, err := bytesToEv(value)
+		if err != nil {
+			return fmt.Errorf("pending evidence %q does not decode: %w", key, err)
+		}
+		if !bytes.Equal(key, keyPending(ev)) {
+			return fmt.Errorf("pending evidence %q is stored under the wrong key", key)
+		}
+		pending++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if size := evpool.Size(); pending != size {
+		return fmt.Errorf("store holds %d pending evidence, but the pool size is %d", pending, size)
+	}
+	evpool.listMtx.Lock()
+	listUnloaded, listLen := evpool.listUnloaded, evpool.evidenceList.Len()
+	evpool.listMtx.Unlock()
+	if !listUnloaded && int(pending) != listLen {
+		return fmt.Errorf("store holds %d pending evidence, but %d are being gossiped", pending, listLen)
+	}
+
+	return iteratePrefix(evpool.evidenceStore, baseKeyCommitted, func(key, value []byte) error {
+		height, _, err := parseKeySuffix(key[1:])
+		if err != nil {
+			return fmt.Errorf("committed evidence key %q is invalid: %w", key, err)
+		}
+		var h gogotypes.Int64Value
+		if err := proto.Unmarshal(value, &h); err != nil {
+			return fmt.Errorf("committed evidence %q does not decode: %w", key, err)
+		}
+		if h.Value != height {
+			return fmt.Errorf("committed evidence %q records height %d", key, h.Value)
+		}
+		return nil
+	})
+}
+
+// iteratePrefix calls fn with each key and value under the prefix in db,
+// stopping at the first error.
+func iteratePrefix(db dbm.DB, prefix byte, fn func(key, value []byte) error) error {
+	iter, err := dbm.IteratePrefix(db, []byte{prefix})
+	if err != nil {
+		return fmt.Errorf("database error: %v", err)
+	}
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		if err := fn(iter.Key(), iter.Value()); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// PendingEvidenceStats returns the amount of pending evidence along with the
+// heights of the oldest and newest pending evidence. Both heights are zero if
+// there is no pending evidence.
+//
+// Pending evidence is keyed by height, so this only needs to look at the first
+// and last keys under the pending prefix.
+func (evpool *Pool) PendingEvidenceStats() (count uint32, oldestHeight int64, newestHeight int64) {
+	count = evpool.Size()
+	if count == 0 {
+		return 0, 0, 0
+	}
+
+	start, end := []byte{baseKeyPending}, []byte{baseKeyPending + 1}
+	var err error
+	oldestHeight, err = evpool.pendingKeyHeight(evpool.evidenceStore.Iterator(start, end))
+	if err != nil {
+		evpool.logger.Error("Unable to read oldest pending evidence", "err", err)
+	}
+	newestHeight, err = evpool.pendingKeyHeight(evpool.evidenceStore.ReverseIterator(start, end))
+	if err != nil {
+		evpool.logger.Error("Unable to read newest pending evidence", "err", err)
+	}
+	return count, oldestHeight, newestHeight
+}
+
+// HasPendingEvidenceBefore returns whether there is pending evidence at or
+// before height. Pending evidence is keyed by height, so this only needs to
+// look at the first key under the pending prefix. DB errors are passed to the
+// logger.
+func (evpool *Pool) HasPendingEvidenceBefore(height int64) bool {
+	if evpool.Size() == 0 {
+		return false
+	}
+	oldestHeight, err := evpool.pendingKeyHeight(
+		evpool.evidenceStore.Iterator([]byte{baseKeyPending}, []byte{baseKeyPending + 1}))
+	if err != nil {
+		evpool.logger.Error("Unable to read oldest pending evidence", "err", err)
+		return false
+	}
+	return oldestHeight > 0 && oldestHeight <= height
+}
+
+// pendingKeyHeight returns the height encoded in the key the iterator points
+// to, or zero if the iterator is not valid.
+func (*Pool) pendingKeyHeight(iter dbm.Iterator, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+	if !iter.Valid() {
+		return 0, iter.Error()
+	}
+	key := iter.Key()
+	if len(key) < 17 {
+		return 0, fmt.Errorf("malformed pending evidence key %X", key)
+	}
+	return strconv.ParseInt(string(key[1:17]), 16, 64)
+}
+
+// DBStats returns the stats reported by the evidence store's database backend
+// (e.g. key counts, size and compaction state). If the backend doesn't report
+// any stats, an empty map is returned.
+func (evpool *Pool) DBStats() map[string]string {
+	stats := evpool.evidenceStore.Stats()
+	if stats == nil {
+		return map[string]string{}
+	}
+	return stats
+}
+
 // State returns the current state of the evpool.
 func (evpool *Pool) State() sm.State {
 	evpool.mtx.Lock()
@@ -258,65 +892,255 @@ func (evpool *Pool) State() sm.State {
 	return evpool.state
 }
 
+// Close closes the evidence store. The conflicting votes reported by
+// consensus which were not formed into evidence yet are discarded; use
+// CloseAndFlush to keep them.
 func (evpool *Pool) Close() error {
 	return evpool.evidenceStore.Close()
 }
 
+// CloseAndFlush forms evidence from the conflicting votes buffered from
+// consensus and adds it to the pending evidence, as Update does, so that it
+// survives a restart, then closes the evidence store. The votes are formed
+// using the block time and validators of state for its LastBlockHeight, and
+// those loaded from the stores for lower heights, so the state and block
+// stores must still be open.
+//
+// The votes above state.LastBlockHeight, usually those of the height in
+// progress, cannot be formed into evidence yet, as the block time is not
+// known. They are stored as they are, and put back in the buffer by NewPool,
+// so that the first Update after the restart forms them into evidence.
+func (evpool *Pool) CloseAndFlush(state sm.State) error {
+	above := evpool.takeVotesAbove(state.LastBlockHeight)
+	for {
+		buffered := evpool.consensusBufferLen()
+		if buffered == 0 {
+			break
+		}
+		evpool.processConsensusBuffer(state)
+		// with WithMaxEvidencePerHeight, processConsensusBuffer flushes the
+		// buffer in batches
+		if evpool.consensusBufferLen() >= buffered {
+			break
+		}
+	}
+	// the votes left over by WithMaxEvidencePerHeight are stored as well
+	evpool.mtx.Lock()
+	above = append(above, evpool.consensusBuffer...)
+	evpool.consensusBuffer = make([]duplicateVoteSet, 0)
+	evpool.mtx.Unlock()
+	if err := evpool.storeBufferedVotes(above); err != nil {
+		evpool.logger.Error("Unable to store the buffered conflicting votes", "err", err)
+	}
+	return evpool.Close()
+}
+
+// takeVotesAbove removes from the consensus buffer the conflicting votes above
+// height and returns them.
+func (evpool *Pool) takeVotesAbove(height int64) []duplicateVoteSet {
+	evpool.mtx.Lock()
+	defer evpool.mtx.Unlock()
+	var above []duplicateVoteSet
+	kept := make([]duplicateVoteSet, 0, len(evpool.consensusBuffer))
+	for _, voteSet := range evpool.consensusBuffer {
+		if voteSet.VoteA.Height > height {
+			above = append(above, voteSet)
+			continue
+		}
+		kept = append(kept, voteSet)
+	}
+	evpool.consensusBuffer = kept
+	return above
+}
+
+// storeBufferedVotes stores the conflicting votes, in order, for
+// loadBufferedVotes to put back in the consensus buffer. Each pair is stored
+// as a DuplicateVoteEvidence with only its votes set.
+func (evpool *Pool) storeBufferedVotes(voteSets []duplicateVoteSet) error {
+	if len(voteSets) == 0 {
+		return nil
+	}
+	batch := evpool.evidenceStore.NewBatch()
+	defer batch.Close()
+	for i, voteSet := range voteSets {
+		pair := cmtproto.DuplicateVoteEvidence{VoteA: voteSet.VoteA.ToProto(), VoteB: voteSet.VoteB.ToProto()}
+		bz, err := pair.Marshal()
+		if err != nil {
+			return fmt.Errorf("unable to marshal conflicting votes: %w", err)
+		}
+		if err := batch.Set(keyBufferedVotes(i), bz); err != nil {
+			return err
+		}
+	}
+	return batch.WriteSync()
+}
+
+// loadBufferedVotes moves the conflicting votes stored by CloseAndFlush back
+// to the consensus buffer.
+func (evpool *Pool) loadBufferedVotes() error {
+	var keys [][]byte
+	err := iteratePrefix(evpool.evidenceStore, baseKeyBufferedVotes, func(key, value []byte) error {
+		keys = append(keys, bytes.Clone(key))
+		var pair cmtproto.DuplicateVoteEvidence
+		if err := pair.Unmarshal(value); err != nil || pair.VoteA == nil || pair.VoteB == nil {
+			evpool.logger.Error("Unable to decode buffered conflicting votes", "key", key, "err", err)
+			return nil
+		}
+		voteA, errA := types.VoteFromProto(pair.VoteA)
+		voteB, errB := types.VoteFromProto(pair.VoteB)
+		if errA != nil || errB != nil {
+			evpool.logger.Error("Unable to decode buffered conflicting votes", "err", errors.Join(errA, errB))
+			return nil
+		}
+		evpool.consensusBuffer = append(evpool.consensusBuffer, duplicateVoteSet{VoteA: voteA, VoteB: voteB})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	batch := evpool.evidenceStore.NewBatch()
+	defer batch.Close()
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	if err := batch.WriteSync(); err != nil {
+		return err
+	}
+	evpool.logger.Info("Loaded buffered conflicting votes", "count", len(evpool.consensusBuffer))
+	return nil
+}
+
+// consensusBufferLen returns the number of conflicting votes buffered from
+// consensus.
+func (evpool *Pool) consensusBufferLen() int {
+	evpool.mtx.Lock()
+	defer evpool.mtx.Unlock()
+	return len(evpool.consensusBuffer)
+}
+
+// isFull returns true if the pool holds the maximum amount of pending evidence.
+func (evpool *Pool) isFull() bool {
+	return evpool.maxPendingEvidence > 0 && evpool.Size() >= evpool.maxPendingEvidence
+}
+
+// isEmpty returns true if the pool holds no pending evidence, in which case
+// there is nothing to iterate over in the store. Quarantined evidence is not
+// pending.
+func (evpool *Pool) isEmpty() bool {
+	return evpool.Size() == 0
+}
+
 // IsExpired checks whether evidence or a polc is expired by checking whether a height and time is older
 // than set by the evidence consensus parameters
 func (evpool *Pool) isExpired(height int64, time time.Time) bool {
+	return isExpiredWith(evpool.expiryParams(), height, time)
+}
+
+// expiryParams holds the parts of the state which tell whether evidence has
+// expired, so that they can be read once for a batch of evidence.
+type expiryParams struct {
+	lastBlockHeight int64
+	lastBlockTime   time.Time
+	maxAgeNumBlocks int64
+	maxAgeDuration  time.Duration
+}
+
+// expiryParams returns the expiry parameters of the current state.
+func (evpool *Pool) expiryParams() expiryParams {
+	evpool.mtx.Lock()
+	defer evpool.mtx.Unlock()
+	return expiryParams{
+		lastBlockHeight: evpool.state.LastBlockHeight,
+		lastBlockTime:   evpool.state.LastBlockTime,
+		maxAgeNumBlocks: evpool.state.ConsensusParams.Evidence.MaxAgeNumBlocks,
+		maxAgeDuration:  evpool.state.ConsensusParams.Evidence.MaxAgeDuration,
+	}
+}
+
+// isExpiredWith returns true if evidence of the given height and time has
+// expired according to params. Evidence expires once it is both older than
+// MaxAgeNumBlocks and MaxAgeDuration.
+func isExpiredWith(params expiryParams, height int64, time time.Time) bool {
 	var (
-		params       = evpool.State().ConsensusParams.Evidence
-		ageDuration  = evpool.State().LastBlockTime.Sub(time)
-		ageNumBlocks = evpool.State().LastBlockHeight - height
+		ageDuration  = params.lastBlockTime.Sub(time)
+		ageNumBlocks = params.lastBlockHeight - height
 	)
-	return ageNumBlocks > params.MaxAgeNumBlocks &&
-		ageDuration > params.MaxAgeDuration
+	return ageNumBlocks > params.maxAgeNumBlocks &&
+		ageDuration > params.maxAgeDuration
 }
 
 // IsCommitted returns true if we have already seen this exact evidence and it is already marked as committed.
 func (evpool *Pool) isCommitted(evidence types.Evidence) bool {
-	key := keyCommitted(evidence)
-	ok, err := evpool.evidenceStore.Has(key)
-	if err != nil {
-		evpool.logger.Error("Unable to find committed evidence", "err", err)
-	}
-	return ok
+	return evpool.hasKey(keyCommitted(evidence), "committed")
 }
 
 // IsPending checks whether the evidence is already pending. DB errors are passed to the logger.
 func (evpool *Pool) isPending(evidence types.Evidence) bool {
-	key := keyPending(evidence)
+	return evpool.hasKey(keyPending(evidence), "pending")
+}
+
+// hasKey checks whether the evidence store has key, which is a key of the
+// given kind of evidence. DB errors are passed to the logger.
+func (evpool *Pool) hasKey(key []byte, kind string) bool {
 	ok, err := evpool.evidenceStore.Has(key)
 	if err != nil {
-		evpool.logger.Error("Unable to find pending evidence", "err", err)
+		evpool.logger.Error("Unable to find "+kind+" evidence", "err", err)
 	}
 	return ok
 }
 
 func (evpool *Pool) addPendingEvidence(ev types.Evidence) error {
-	evpb, err := types.EvidenceToProto(ev)
+	evBytes, err := marshalEvidence(ev)
 	if err != nil {
-		return cmterrors.ErrMsgToProto{MessageName: "Evidence", Err: err}
+		return err
 	}
+	return evpool.storePendingEvidence(keyPending(ev), evBytes)
+}
 
-	evBytes, err := evpb.Marshal()
-	if err != nil {
-		return fmt.Errorf("unable to marshal evidence: %w", err)
+// storePendingEvidence stores evBytes, the encoding of an evidence, as
+// pending evidence under key.
+func (evpool *Pool) storePendingEvidence(key, evBytes []byte) error {
+	if err := evpool.evidenceStore.Set(key, evBytes); err != nil {
+		return fmt.Errorf("can't persist evidence: %w", err)
 	}
+	atomic.AddUint32(&evpool.evidenceSize, 1)
+	return nil
+}
 
-	key := keyPending(ev)
+// marshalEvidence returns the protobuf encoding of ev, as stored in the
+// evidence store.
+func marshalEvidence(ev types.Evidence) ([]byte, error) {
+	evpb, err := types.EvidenceToProto(ev)
+	if err != nil {
+		return nil, cmterrors.ErrMsgToProto{MessageName: "Evidence", Err: err}
+	}
 
-	err = evpool.evidenceStore.Set(key, evBytes)
+	evBytes, err := evpb.Marshal()
 	if err != nil {
-		return fmt.Errorf("can't persist evidence: %w", err)
+		return nil, fmt.Errorf("unable to marshal evidence: %w", err)
 	}
-	atomic.AddUint32(&evpool.evidenceSize, 1)
-	return nil
+	return evBytes, nil
 }
 
+// removePendingEvidence deletes the evidence from the pending evidence, if it
+// is there. The caller must hold pendingMtx.
 func (evpool *Pool) removePendingEvidence(evidence types.Evidence) {
 	key := keyPending(evidence)
+	ok, err := evpool.evidenceStore.Has(key)
+	if err != nil {
+		evpool.logger.Error("Unable to check for pending evidence", "err", err)
+		return
+	}
+	if !ok {
+		// already removed, don't decrement the size again
+		return
+	}
 	if err := evpool.evidenceStore.Delete(key); err != nil {
 		evpool.logger.Error("Unable to delete pending evidence", "err", err)
 	} else {
@@ -328,12 +1152,20 @@ func (evpool *Pool) removePendingEvidence(evidence types.Evidence) {
 // markEvidenceAsCommitted processes all the evidence in the block, marking it as
 // committed and removing it from the pending database.
 func (evpool *Pool) markEvidenceAsCommitted(evidence types.EvidenceList) {
+	evpool.listMtx.Lock()
+	defer evpool.listMtx.Unlock()
+
 	blockEvidenceMap := make(map[string]struct{}, len(evidence))
 	for _, ev := range evidence {
 		if evpool.isPending(ev) {
 			evpool.removePendingEvidence(ev)
 			blockEvidenceMap[evMapKey(ev)] = struct{}{}
 		}
+		if evpool.isQuarantined(ev) {
+			if err := evpool.evidenceStore.Delete(keyQuarantined(ev)); err != nil {
+				evpool.logger.Error("Unable to delete quarantined evidence", "err", err)
+			}
+		}
 
 		// Add evidence to the committed list. As the evidence is stored in the block store
 		// we only need to record the height that it was saved at.
@@ -402,11 +1234,21 @@ func (evpool *Pool) listEvidence(prefixKey byte, maxBytes int64) ([]types.Eviden
 	return evidence, totalSize, nil
 }
 
-func (evpool *Pool) removeExpiredPendingEvidence() (int64, time.Time) {
+// removeExpiredPendingEvidence removes expired evidence from the pending list
+// and returns the number of removed evidence together with the height and time
+// at which the next pruning should happen.
+func (evpool *Pool) removeExpiredPendingEvidence() (int, int64, time.Time) {
+	// the state is read once, rather than for each evidence, and before
+	// taking listMtx as processConsensusBuffer takes mtx then listMtx
+	params := evpool.expiryParams()
+
+	evpool.listMtx.Lock()
+	defer evpool.listMtx.Unlock()
+
 	iter, err := dbm.IteratePrefix(evpool.evidenceStore, []byte{baseKeyPending})
 	if err != nil {
 		evpool.logger.Error("Unable to iterate over pending evidence", "err", err)
-		return evpool.State().LastBlockHeight, evpool.State().LastBlockTime
+		return 0, params.lastBlockHeight, params.lastBlockTime
 	}
 	defer iter.Close()
 	blockEvidenceMap := make(map[string]struct{})
@@ -416,14 +1258,15 @@ func (evpool *Pool) removeExpiredPendingEvidence() (int64, time.Time) {
 			evpool.logger.Error("Error in transition evidence from protobuf", "err", err)
 			continue
 		}
-		if !evpool.isExpired(ev.Height(), ev.Time()) {
+		if !isExpiredWith(params, ev.Height(), ev.Time()) {
 			if len(blockEvidenceMap) != 0 {
 				evpool.removeEvidenceFromList(blockEvidenceMap)
 			}
 
 			// return the height and time with which this evidence will have expired so we know when to prune next
-			return ev.Height() + evpool.State().ConsensusParams.Evidence.MaxAgeNumBlocks + 1,
-				ev.Time().Add(evpool.State().ConsensusParams.Evidence.MaxAgeDuration).Add(time.Second)
+			return len(blockEvidenceMap),
+				ev.Height() + params.maxAgeNumBlocks + 1,
+				ev.Time().Add(params.maxAgeDuration).Add(time.Second)
 		}
 		evpool.removePendingEvidence(ev)
 		blockEvidenceMap[evMapKey(ev)] = struct{}{}
@@ -432,7 +1275,76 @@ func (evpool *Pool) removeExpiredPendingEvidence() (int64, time.Time) {
 	if len(blockEvidenceMap) != 0 {
 		evpool.removeEvidenceFromList(blockEvidenceMap)
 	}
-	return evpool.State().LastBlockHeight, evpool.State().LastBlockTime
+	return len(blockEvidenceMap), params.lastBlockHeight, params.lastBlockTime
+}
+
+// pruneCommittedEvidence removes the committed evidence entries of the heights
+// below belowHeight, if evidence of height belowHeight has expired. Evidence
+// takes the time of the block at its height, so the older evidence has then
+// expired as well. The caller must hold pendingMtx.
+func (evpool *Pool) pruneCommittedEvidence(belowHeight int64) {
+	if belowHeight <= 1 {
+		return
+	}
+	keys, err := evpool.committedKeysBelow(belowHeight)
+	if err != nil {
+		evpool.logger.Error("Unable to list committed evidence", "err", err)
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	blockMeta := evpool.blockStore.LoadBlockMeta(belowHeight)
+	if blockMeta == nil {
+		evpool.logger.Debug("Not pruning committed evidence: block not found", "height", belowHeight)
+		return
+	}
+	if !evpool.isExpired(belowHeight, blockMeta.Header.Time) {
+		return
+	}
+
+	batch := evpool.evidenceStore.NewBatch()
+	defer batch.Close()
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			evpool.logger.Error("Unable to delete committed evidence", "err", err)
+			return
+		}
+	}
+	if err := batch.Set([]byte{keyCommittedPruned}, []byte(bE(belowHeight))); err != nil {
+		evpool.logger.Error("Unable to record the pruned committed evidence height", "err", err)
+		return
+	}
+	if err := batch.WriteSync(); err != nil {
+		evpool.logger.Error("Unable to delete committed evidence", "err", err)
+		return
+	}
+	evpool.logger.Debug("Pruned committed evidence", "count", len(keys), "below_height", belowHeight)
+}
+
+// committedKeysBelow returns the keys of the committed evidence entries of the
+// heights below height. The keys are ordered by height, so only those entries
+// are read.
+func (evpool *Pool) committedKeysBelow(height int64) ([][]byte, error) {
+	iter, err := dbm.IteratePrefix(evpool.evidenceStore, []byte{baseKeyCommitted})
+	if err != nil {
+		return nil, fmt.Errorf("database error: %v", err)
+	}
+	defer iter.Close()
+	var keys [][]byte
+	for ; iter.Valid(); iter.Next() {
+		evHeight, _, err := parseKeySuffix(iter.Key()[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid committed evidence key %q: %w", iter.Key(), err)
+		}
+		if evHeight >= height {
+			break
+		}
+		// the iterator may reuse its buffers once it moves on
+		keys = append(keys, bytes.Clone(iter.Key()))
+	}
+	return keys, iter.Error()
 }
 
 func (evpool *Pool) removeEvidenceFromList(
@@ -458,11 +1370,47 @@ func (evpool *Pool) updateState(state sm.State) {
 // into DuplicateVoteEvidence. It sets the evidence timestamp to the block height
 // from the most recently committed block.
 // Evidence is then added to the pool so as to be ready to be broadcasted and proposed.
+//
+// The evidence is formed under mtx, which is released before taking listMtx
+// to store it: removeExpiredPendingEvidence takes mtx while holding listMtx.
 func (evpool *Pool) processConsensusBuffer(state sm.State) {
+	pending := evpool.takeConsensusBuffer(state)
+
+	flushed := 0
+	for i, p := range pending {
+		if evpool.maxEvidencePerHeight > 0 && flushed >= evpool.maxEvidencePerHeight {
+			// carry the remaining votes forward to the next height
+			remaining := make([]duplicateVoteSet, 0, len(pending)-i)
+			for _, p := range pending[i:] {
+				remaining = append(remaining, p.voteSet)
+			}
+			evpool.mtx.Lock()
+			evpool.consensusBuffer = append(remaining, evpool.consensusBuffer...)
+			evpool.mtx.Unlock()
+			evpool.logger.Info("deferring duplicate votes to the next height", "count", len(remaining))
+			return
+		}
+		if evpool.addConsensusEvidence(p.dve) {
+			flushed++
+		}
+	}
+}
+
+// bufferedEvidence is the evidence formed from a pair of buffered conflicting
+// votes.
+type bufferedEvidence struct {
+	voteSet duplicateVoteSet
+	dve     *types.DuplicateVoteEvidence
+}
+
+// takeConsensusBuffer empties the consensus buffer, returning the evidence
+// formed from the conflicting votes it held. The votes from which no evidence
+// can be formed are dropped.
+func (evpool *Pool) takeConsensusBuffer(state sm.State) []bufferedEvidence {
 	evpool.mtx.Lock()
 	defer evpool.mtx.Unlock()
+	pending := make([]bufferedEvidence, 0, len(evpool.consensusBuffer))
 	for _, voteSet := range evpool.consensusBuffer {
-
 		// Check the height of the conflicting votes and fetch the corresponding time and validator set
 		// to produce the valid evidence
 		var (
@@ -512,30 +1460,40 @@ func (evpool *Pool) processConsensusBuffer(state sm.State) {
 			evpool.logger.Error("error in generating evidence from votes", "err", err)
 			continue
 		}
+		pending = append(pending, bufferedEvidence{voteSet: voteSet, dve: dve})
+	}
+	// reset consensus buffer
+	evpool.consensusBuffer = make([]duplicateVoteSet, 0)
+	return pending
+}
 
-		// check if we already have this evidence
-		if evpool.isPending(dve) {
-			evpool.logger.Info("evidence already pending; ignoring", "evidence", dve)
-			continue
-		}
-
-		// check that the evidence is not already committed on chain
-		if evpool.isCommitted(dve) {
-			evpool.logger.Info("evidence already committed; ignoring", "evidence", dve)
-			continue
-		}
-
-		if err := evpool.addPendingEvidence(dve); err != nil {
-			evpool.logger.Error("failed to flush evidence from consensus buffer to pending list", "err", err)
-			continue
-		}
+// addConsensusEvidence adds dve, formed from votes seen by consensus, to the
+// pending evidence. It returns false if it was not added.
+func (evpool *Pool) addConsensusEvidence(dve *types.DuplicateVoteEvidence) bool {
+	// check if we already have this evidence
+	if evpool.isPending(dve) {
+		evpool.logger.Info("evidence already pending; ignoring", "evidence", dve)
+		return false
+	}
 
-		evpool.evidenceList.PushBack(dve)
+	// check that the evidence is not already committed on chain
+	if evpool.isCommitted(dve) {
+		evpool.logger.Info("evidence already committed; ignoring", "evidence", dve)
+		return false
+	}
 
-		evpool.logger.Info("verified new evidence of byzantine behavior", "evidence", dve)
+	evpool.listMtx.Lock()
+	if err := evpool.addPendingEvidence(dve); err != nil {
+		evpool.listMtx.Unlock()
+		evpool.logger.Error("failed to flush evidence from consensus buffer to pending list", "err", err)
+		return false
 	}
-	// reset consensus buffer
-	evpool.consensusBuffer = make([]duplicateVoteSet, 0)
+	evpool.pushEvidence(dve)
+	evpool.listMtx.Unlock()
+	evpool.notifyByzantineValidators(dve)
+
+	evpool.logger.Info("verified new evidence of byzantine behavior", "evidence", dve)
+	return true
 }
 
 type duplicateVoteSet struct {
@@ -553,6 +1511,34 @@ func bytesToEv(evBytes []byte) (types.Evidence, error) {
 	return types.EvidenceFromProto(&evpb)
 }
 
+// evidenceAgainst returns true if addr is one of the misbehaving validators of
+// the evidence.
+func evidenceAgainst(ev types.Evidence, addr types.Address) bool {
+	for _, byzantine := range byzantineAddresses(ev) {
+		if bytes.Equal(byzantine, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// byzantineAddresses returns the addresses of the validators ev is against:
+// the one which cast the conflicting votes of DuplicateVoteEvidence, or the
+// byzantine validators of LightClientAttackEvidence.
+func byzantineAddresses(ev types.Evidence) []types.Address {
+	switch ev := ev.(type) {
+	case *types.DuplicateVoteEvidence:
+		return []types.Address{ev.VoteA.ValidatorAddress}
+	case *types.LightClientAttackEvidence:
+		addrs := make([]types.Address, len(ev.ByzantineValidators))
+		for i, val := range ev.ByzantineValidators {
+			addrs[i] = val.Address
+		}
+		return addrs
+	}
+	return nil
+}
+
 func evMapKey(ev types.Evidence) string {
 	return string(ev.Hash())
 }
@@ -563,13 +1549,35 @@ func bE(h int64) string {
 }
 
 func keyCommitted(evidence types.Evidence) []byte {
-	return append([]byte{baseKeyCommitted}, keySuffix(evidence)...)
+	return keyCommittedWithHash(evidence.Height(), evidence.Hash())
 }
 
 func keyPending(evidence types.Evidence) []byte {
-	return append([]byte{baseKeyPending}, keySuffix(evidence)...)
+	return keyPendingWithHash(evidence.Height(), evidence.Hash())
+}
+
+func keyQuarantined(evidence types.Evidence) []byte {
+	return append([]byte{baseKeyQuarantined}, keySuffix(evidence.Height(), evidence.Hash())...)
+}
+
+// keyCommittedWithHash is keyCommitted for evidence whose height and hash are
+// already known.
+func keyCommittedWithHash(height int64, hash []byte) []byte {
+	return append([]byte{baseKeyCommitted}, keySuffix(height, hash)...)
+}
+
+// keyPendingWithHash is keyPending for evidence whose height and hash are
+// already known.
+func keyPendingWithHash(height int64, hash []byte) []byte {
+	return append([]byte{baseKeyPending}, keySuffix(height, hash)...)
+}
+
+// keyBufferedVotes returns the key of the i-th pair of conflicting votes
+// stored by CloseAndFlush.
+func keyBufferedVotes(i int) []byte {
+	return append([]byte{baseKeyBufferedVotes}, bE(int64(i))...)
 }
 
-func keySuffix(evidence types.Evidence) []byte {
-	return []byte(fmt.Sprintf("%s/%X", bE(evidence.Height()), evidence.Hash()))
+func keySuffix(height int64, hash []byte) []byte {
+	return []byte(fmt.Sprintf("%s/%X", bE(height), hash))
 }
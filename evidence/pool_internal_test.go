@@ -0,0 +1,73 @@
+package evidence
+
+import (
+	"testing"
+	"time"
+
+	sm "github.com/cometbft/cometbft/state"
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestIsExpiredWith(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	params := expiryParams{
+		lastBlockHeight: 100,
+		lastBlockTime:   now,
+		maxAgeNumBlocks: 10,
+		maxAgeDuration:  time.Hour,
+	}
+	testCases := []struct {
+		name    string
+		height  int64
+		time    time.Time
+		expired bool
+	}{
+		{"recent", 95, now.Add(-time.Minute), false},
+		{"too many blocks only", 80, now.Add(-time.Minute), false},
+		{"too old only", 95, now.Add(-2 * time.Hour), false},
+		{"at the limits", 90, now.Add(-time.Hour), false},
+		{"past both limits", 89, now.Add(-time.Hour - time.Second), true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isExpiredWith(params, tc.height, tc.time); got != tc.expired {
+				t.Errorf("isExpiredWith(%d, %v) = %t, want %t", tc.height, tc.time, got, tc.expired)
+			}
+		})
+	}
+}
+
+// BenchmarkEvidenceExpiry compares reading the state for each evidence, as
+// isExpired does, with reading it once for all of them, as
+// removeExpiredPendingEvidence does.
+func BenchmarkEvidenceExpiry(b *testing.B) {
+	const numEvidence = 1000
+	now := time.Now()
+	valSet, _ := types.RandValidatorSet(100, 10)
+	evpool := &Pool{state: sm.State{
+		LastBlockHeight: 2 * numEvidence,
+		LastBlockTime:   now,
+		Validators:      valSet,
+		NextValidators:  valSet,
+		LastValidators:  valSet,
+		ConsensusParams: *types.DefaultConsensusParams(),
+	}}
+
+	b.Run("isExpired", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for h := int64(1); h <= numEvidence; h++ {
+				evpool.isExpired(h, now)
+			}
+		}
+	})
+	b.Run("isExpiredWith", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			params := evpool.expiryParams()
+			for h := int64(1); h <= numEvidence; h++ {
+				isExpiredWith(params, h, now)
+			}
+		}
+	})
+}
@@ -1,10 +1,14 @@
 package evidence_test
 
 import (
+	"bytes"
+	"context"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -15,6 +19,7 @@ import (
 	"github.com/cometbft/cometbft/evidence/mocks"
 	"github.com/cometbft/cometbft/internal/test"
 	"github.com/cometbft/cometbft/libs/log"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
 	cmtversion "github.com/cometbft/cometbft/proto/tendermint/version"
 	sm "github.com/cometbft/cometbft/state"
 	smmocks "github.com/cometbft/cometbft/state/mocks"
@@ -182,6 +187,102 @@ func TestReportConflictingVotes(t *testing.T) {
 	require.NotNil(t, next)
 }
 
+func TestEvidencePoolCloseAndFlush(t *testing.T) {
+	var height int64 = 10
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	evidenceDB, err := dbm.NewDB("evidence", dbm.GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(height, defaultEvidenceTime, val, evidenceChainID)
+	require.NoError(t, err)
+	pool.ReportConflictingVotes(ev.VoteA, ev.VoteB)
+	// votes of the height in progress cannot be formed into evidence yet,
+	// so they are stored as they are
+	evAbove, err := types.NewMockDuplicateVoteEvidenceWithValidator(height+1, defaultEvidenceTime, val, evidenceChainID)
+	require.NoError(t, err)
+	pool.ReportConflictingVotes(evAbove.VoteA, evAbove.VoteB)
+
+	state = pool.State()
+	state.LastBlockTime = ev.Time()
+	state.LastValidators = types.NewValidatorSet([]*types.Validator{types.NewValidator(val.PrivKey.PubKey(), 10)})
+	require.NoError(t, pool.CloseAndFlush(state))
+
+	evidenceDB, err = dbm.NewDB("evidence", dbm.GoLevelDBBackend, dir)
+	require.NoError(t, err)
+	pool, err = evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	defer pool.Close()
+	evList, _ := pool.PendingEvidence(-1)
+	require.Equal(t, []types.Evidence{ev}, evList)
+
+	// the votes are back in the buffer, and formed into evidence once their
+	// height is committed
+	state.LastBlockHeight++
+	state.LastBlockTime = evAbove.Time()
+	pool.Update(state, nil)
+	evList, _ = pool.PendingEvidence(-1)
+	require.Equal(t, []types.Evidence{ev, evAbove}, evList)
+}
+
+func TestReportConflictingVotesMaxEvidencePerHeight(t *testing.T) {
+	const (
+		height     int64 = 10
+		voteHeight int64 = 5
+	)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore, evidence.WithMaxEvidencePerHeight(2))
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	// a burst of conflicting votes from an earlier height
+	voteTime := blockStore.LoadBlockMeta(voteHeight).Header.Time
+	var reported []*types.DuplicateVoteEvidence
+	for i := 0; i < 5; i++ {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(voteHeight, voteTime, val, evidenceChainID)
+		require.NoError(t, err)
+		pool.ReportConflictingVotes(ev.VoteA, ev.VoteB)
+		reported = append(reported, ev)
+	}
+
+	// at most 2 evidence are flushed per height, the rest is carried forward
+	for _, size := range []uint32{2, 4, 5, 5} {
+		state.LastBlockHeight++
+		state.LastBlockTime = state.LastBlockTime.Add(time.Minute)
+		pool.Update(state, []types.Evidence{})
+		require.Equal(t, size, pool.Size())
+	}
+
+	// none of the votes was lost, and the evidence carries the time of the
+	// block at the height of the votes
+	evList, _ := pool.PendingEvidence(-1)
+	require.Len(t, evList, len(reported))
+	for _, ev := range reported {
+		found := false
+		for _, pending := range evList {
+			if bytes.Equal(pending.Hash(), ev.Hash()) {
+				require.Equal(t, voteTime, pending.Time())
+				found = true
+			}
+		}
+		require.True(t, found, "evidence %v is not pending", ev)
+	}
+}
+
 func TestEvidencePoolUpdate(t *testing.T) {
 	height := int64(21)
 	pool, val := defaultTestPool(t, height)
@@ -217,6 +318,201 @@ func TestEvidencePoolUpdate(t *testing.T) {
 	}
 }
 
+func TestEvidencePoolPruneExpired(t *testing.T) {
+	height := int64(10)
+	pool, val := defaultTestPool(t, height)
+
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(1, defaultEvidenceTime.Add(1*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	require.NoError(t, pool.AddEvidence(ev))
+
+	// the evidence is not expired yet, so Update schedules the next pruning
+	// for when it expires under the current parameters
+	state := pool.State()
+	state.LastBlockHeight = height + 1
+	state.LastBlockTime = defaultEvidenceTime.Add(11 * time.Minute)
+	pool.Update(state, nil)
+	require.EqualValues(t, 1, pool.Size())
+
+	// shorter max age: the evidence is now expired but the next automatic
+	// pruning is still scheduled for later
+	state.LastBlockHeight++
+	state.LastBlockTime = state.LastBlockTime.Add(1 * time.Minute)
+	state.ConsensusParams.Evidence.MaxAgeNumBlocks = 5
+	state.ConsensusParams.Evidence.MaxAgeDuration = 5 * time.Minute
+	pool.Update(state, nil)
+	require.EqualValues(t, 1, pool.Size())
+
+	pruned, nextHeight, nextTime := pool.PruneExpired()
+	assert.Equal(t, 1, pruned)
+	assert.Equal(t, state.LastBlockHeight, nextHeight)
+	assert.Equal(t, state.LastBlockTime, nextTime)
+	assert.Zero(t, pool.Size())
+	evList, _ := pool.PendingEvidence(defaultEvidenceMaxBytes)
+	assert.Empty(t, evList)
+
+	// nothing left to prune
+	pruned, nextHeight, nextTime = pool.PruneExpired()
+	assert.Zero(t, pruned)
+	assert.Equal(t, state.LastBlockHeight, nextHeight)
+	assert.Equal(t, state.LastBlockTime, nextTime)
+}
+
+func TestEvidencePoolCommittedEvidenceRetention(t *testing.T) {
+	height := int64(60)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	evidenceDB := dbm.NewMemDB()
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore, evidence.WithCommittedEvidenceRetention(30))
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	var evList types.EvidenceList
+	for _, h := range []int64{5, 25, 50} {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, defaultEvidenceTime.Add(time.Duration(h)*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		evList = append(evList, ev)
+	}
+	// the evidence is not in the blocks, so all the committed entries are
+	// reported as missing
+	committedHashes := func() [][]byte {
+		_, missing, err := evidence.ExportEvidence(evidenceDB, blockStore, false, true)
+		require.NoError(t, err)
+		return missing
+	}
+
+	// evidence below the retention is kept as long as it has not expired
+	state.LastBlockHeight = height + 1
+	state.LastBlockTime = defaultEvidenceTime.Add(time.Duration(height+1) * time.Minute)
+	state.ConsensusParams.Evidence.MaxAgeDuration = 10 * time.Hour
+	pool.Update(state, evList)
+	require.Len(t, committedHashes(), 3)
+
+	// once it has expired, the entries more than 30 blocks old are removed
+	state.LastBlockHeight++
+	state.LastBlockTime = state.LastBlockTime.Add(time.Minute)
+	state.ConsensusParams.Evidence.MaxAgeDuration = 20 * time.Minute
+	pool.Update(state, nil)
+	assert.Equal(t, [][]byte{evList[2].Hash()}, committedHashes())
+	// and the evidence is rejected as expired rather than as committed
+	err = pool.CheckEvidence(types.EvidenceList{evList[0]})
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "already committed")
+}
+
+func TestEvidencePoolPruneExpiredConcurrentWithUpdate(t *testing.T) {
+	height := int64(10)
+	for i := 0; i < 20; i++ {
+		pool, val := defaultTestPool(t, height)
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(1, defaultEvidenceTime.Add(1*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		require.NoError(t, pool.AddEvidence(ev))
+
+		// the evidence is both expired and committed in the new block
+		state := pool.State()
+		state.LastBlockHeight = height + 1
+		state.LastBlockTime = defaultEvidenceTime.Add(11 * time.Minute)
+		state.ConsensusParams.Evidence.MaxAgeNumBlocks = 5
+		state.ConsensusParams.Evidence.MaxAgeDuration = 5 * time.Minute
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			pool.Update(state, types.EvidenceList{ev})
+		}()
+		go func() {
+			defer wg.Done()
+			pool.PruneExpired()
+		}()
+		wg.Wait()
+
+		// the evidence is removed only once
+		require.EqualValues(t, 0, pool.Size())
+	}
+}
+
+func TestEvidencePoolPruneExpiredConcurrentWithConsensusBuffer(t *testing.T) {
+	const (
+		height     int64 = 10
+		voteHeight int64 = 8
+	)
+	for i := 0; i < 5; i++ {
+		val := types.NewMockPV()
+		stateStore := initializeValidatorState(val, height)
+		state, err := stateStore.Load()
+		require.NoError(t, err)
+		blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+		require.NoError(t, err)
+		// MemDB cannot delete the expired evidence while iterating over many
+		// pending evidence, so a persistent database is used
+		evidenceDB, err := dbm.NewDB("evidence", dbm.GoLevelDBBackend, t.TempDir())
+		require.NoError(t, err)
+		pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+		require.NoError(t, err)
+		pool.SetLogger(log.TestingLogger())
+
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(1, defaultEvidenceTime.Add(1*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		require.NoError(t, pool.AddEvidence(ev))
+
+		// conflicting votes buffered from consensus, which Update turns into
+		// evidence while PruneExpired removes the expired evidence
+		voteTime := defaultEvidenceTime.Add(time.Duration(voteHeight) * time.Minute)
+		for j := 0; j < 100; j++ {
+			vev, err := types.NewMockDuplicateVoteEvidenceWithValidator(voteHeight, voteTime, val, evidenceChainID)
+			require.NoError(t, err)
+			pool.ReportConflictingVotes(vev.VoteA, vev.VoteB)
+		}
+
+		state = pool.State()
+		state.LastBlockHeight = height + 1
+		state.LastBlockTime = defaultEvidenceTime.Add(11 * time.Minute)
+		state.ConsensusParams.Evidence.MaxAgeNumBlocks = 5
+		state.ConsensusParams.Evidence.MaxAgeDuration = 5 * time.Minute
+
+		// PruneExpired is called repeatedly until Update returns, so that it
+		// runs while the evidence is formed from the votes
+		updated := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(updated)
+			pool.Update(state, nil)
+		}()
+		go func() {
+			defer close(done)
+			for {
+				select {
+				case <-updated:
+					return
+				default:
+					pool.PruneExpired()
+				}
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("Update and PruneExpired deadlocked")
+		}
+
+		// PruneExpired may only have seen the previous state, in which the
+		// evidence had not expired yet
+		pool.PruneExpired()
+		// the expired evidence is gone, the evidence from the votes is pending
+		require.EqualValues(t, 100, pool.Size())
+		require.NoError(t, pool.Close())
+	}
+}
+
 func TestVerifyPendingEvidencePasses(t *testing.T) {
 	var height int64 = 1
 	pool, val := defaultTestPool(t, height)
@@ -230,6 +526,52 @@ func TestVerifyPendingEvidencePasses(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestCheckEvidenceContext(t *testing.T) {
+	var height int64 = 1
+	pool, val := defaultTestPool(t, height)
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(height, defaultEvidenceTime.Add(1*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = pool.CheckEvidenceContext(ctx, types.EvidenceList{ev})
+	require.ErrorIs(t, err, context.Canceled)
+	// nothing was checked, so nothing was added
+	assert.Zero(t, pool.Size())
+
+	require.NoError(t, pool.CheckEvidenceContext(context.Background(), types.EvidenceList{ev}))
+	assert.EqualValues(t, 1, pool.Size())
+}
+
+func TestCheckEvidenceSwappedVotesIsRejected(t *testing.T) {
+	var height int64 = 1
+	pool, val := defaultTestPool(t, height)
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(height, defaultEvidenceTime.Add(1*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+
+	// the same equivocation encoded with the votes swapped doesn't decode
+	pb := ev.ToProto()
+	pb.VoteA, pb.VoteB = pb.VoteB, pb.VoteA
+	_, err = types.DuplicateVoteEvidenceFromProto(pb)
+	require.EqualError(t, err, "duplicate votes in invalid order")
+
+	// nor is it accepted when built in memory
+	swapped := &types.DuplicateVoteEvidence{
+		VoteA:            ev.VoteB,
+		VoteB:            ev.VoteA,
+		TotalVotingPower: ev.TotalVotingPower,
+		ValidatorPower:   ev.ValidatorPower,
+		Timestamp:        ev.Timestamp,
+	}
+	err = pool.CheckEvidence(types.EvidenceList{swapped})
+	if assert.Error(t, err) {
+		assert.Equal(t, "duplicate votes in invalid order", err.(*types.ErrInvalidEvidence).Reason.Error())
+	}
+	assert.EqualValues(t, 0, pool.Size())
+}
+
 func TestVerifyDuplicatedEvidenceFails(t *testing.T) {
 	var height int64 = 1
 	pool, val := defaultTestPool(t, height)
@@ -240,6 +582,61 @@ func TestVerifyDuplicatedEvidenceFails(t *testing.T) {
 	if assert.Error(t, err) {
 		assert.Equal(t, "duplicate evidence", err.(*types.ErrInvalidEvidence).Reason.Error())
 	}
+	// the first one was added, only once
+	assert.EqualValues(t, 1, pool.Size())
+}
+
+func BenchmarkCheckEvidence(b *testing.B) {
+	const (
+		height      int64 = 10
+		numEvidence       = 1000
+	)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(b, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(b, err)
+
+	evList := make(types.EvidenceList, numEvidence)
+	for i := range evList {
+		evList[i], err = types.NewMockDuplicateVoteEvidenceWithValidator(height,
+			defaultEvidenceTime.Add(time.Duration(height)*time.Minute), val, evidenceChainID)
+		require.NoError(b, err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+		require.NoError(b, err)
+		b.StartTimer()
+
+		require.NoError(b, pool.CheckEvidence(evList))
+	}
+}
+
+// BenchmarkUpdateEmptyPool measures Update on a pool without pending evidence,
+// the common case at each height.
+func BenchmarkUpdateEmptyPool(b *testing.B) {
+	const height int64 = 10
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(b, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(b, err)
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		state.LastBlockHeight++
+		state.LastBlockTime = state.LastBlockTime.Add(time.Second)
+		pool.Update(state, nil)
+	}
 }
 
 // check that valid light client evidence is correctly validated and stored in
@@ -303,6 +700,164 @@ func TestLightClientAttackEvidenceLifecycle(t *testing.T) {
 	require.Empty(t, remaindingEv)
 }
 
+func TestPendingEvidenceWithPriority(t *testing.T) {
+	var (
+		height       int64 = 100
+		commonHeight int64 = 90
+	)
+
+	lcaEv, trusted, common := makeLunaticEvidence(t, height, commonHeight,
+		10, 5, 5, defaultEvidenceTime, defaultEvidenceTime.Add(1*time.Hour))
+
+	state := sm.State{
+		LastBlockTime:   defaultEvidenceTime.Add(2 * time.Hour),
+		LastBlockHeight: 110,
+		ChainID:         evidenceChainID,
+		ConsensusParams: *types.DefaultConsensusParams(),
+	}
+	stateStore := &smmocks.Store{}
+	stateStore.On("LoadValidators", height).Return(trusted.ValidatorSet, nil)
+	stateStore.On("LoadValidators", commonHeight).Return(common.ValidatorSet, nil)
+	stateStore.On("Load").Return(state, nil)
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("LoadBlockMeta", height).Return(&types.BlockMeta{Header: *trusted.Header})
+	blockStore.On("LoadBlockMeta", commonHeight).Return(&types.BlockMeta{Header: *common.Header})
+	blockStore.On("LoadBlockCommit", height).Return(trusted.Commit)
+	blockStore.On("LoadBlockCommit", commonHeight).Return(common.Commit)
+
+	// duplicate votes, older than the light client attack (whose height is the
+	// common height)
+	val := types.NewMockPV()
+	valSet := types.NewValidatorSet([]*types.Validator{val.ExtractIntoValidator(10)})
+	var dveEvs []types.Evidence
+	for _, h := range []int64{80, 81} {
+		evTime := defaultEvidenceTime.Add(time.Duration(h) * time.Minute)
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, evTime, val, evidenceChainID)
+		require.NoError(t, err)
+		stateStore.On("LoadValidators", h).Return(valSet, nil)
+		blockStore.On("LoadBlockMeta", h).Return(&types.BlockMeta{Header: types.Header{Time: evTime}})
+		dveEvs = append(dveEvs, ev)
+	}
+
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+	for _, ev := range []types.Evidence{dveEvs[0], lcaEv, dveEvs[1]} {
+		require.NoError(t, pool.AddEvidence(ev))
+	}
+
+	evSize := func(evList ...types.Evidence) int64 {
+		var pbList cmtproto.EvidenceList
+		for _, ev := range evList {
+			evpb, err := types.EvidenceToProto(ev)
+			require.NoError(t, err)
+			pbList.Evidence = append(pbList.Evidence, *evpb)
+		}
+		return int64(pbList.Size())
+	}
+	hashes := func(evList []types.Evidence) []string {
+		hs := make([]string, len(evList))
+		for i, ev := range evList {
+			hs[i] = string(ev.Hash())
+		}
+		return hs
+	}
+	lcaFirst := func(ev types.Evidence) int {
+		if _, ok := ev.(*types.LightClientAttackEvidence); ok {
+			return 1
+		}
+		return 0
+	}
+
+	// without a cap, all the evidence is returned in priority order, ties
+	// being ordered from oldest to newest
+	evList, size := pool.PendingEvidenceWithPriority(-1, lcaFirst)
+	require.Equal(t, hashes([]types.Evidence{lcaEv, dveEvs[0], dveEvs[1]}), hashes(evList))
+	require.Equal(t, evSize(evList...), size)
+
+	// PendingEvidence keeps returning the evidence from oldest to newest
+	evList, _ = pool.PendingEvidence(-1)
+	require.Equal(t, hashes([]types.Evidence{dveEvs[0], dveEvs[1], lcaEv}), hashes(evList))
+
+	// with room for the light client attack and one duplicate vote only
+	maxBytes := evSize(lcaEv, dveEvs[0])
+	evList, size = pool.PendingEvidenceWithPriority(maxBytes, lcaFirst)
+	require.Equal(t, hashes([]types.Evidence{lcaEv, dveEvs[0]}), hashes(evList))
+	require.Equal(t, maxBytes, size)
+	evList, _ = pool.PendingEvidence(maxBytes)
+	require.Equal(t, hashes([]types.Evidence{dveEvs[0], dveEvs[1]}), hashes(evList))
+
+	// with room for the light client attack only, the duplicate votes are left out
+	evList, size = pool.PendingEvidenceWithPriority(evSize(lcaEv), lcaFirst)
+	require.Equal(t, hashes([]types.Evidence{lcaEv}), hashes(evList))
+	require.Equal(t, evSize(lcaEv), size)
+}
+
+func TestEvidencePoolVerify(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	evidenceDB := dbm.NewMemDB()
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	require.NoError(t, pool.Verify())
+
+	for h := int64(1); h <= 2; h++ {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, defaultEvidenceTime.Add(time.Duration(h)*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		require.NoError(t, pool.AddEvidence(ev))
+	}
+	// commit the first evidence
+	evList, _ := pool.PendingEvidence(-1)
+	state.LastBlockHeight++
+	pool.Update(state, evList[:1])
+	require.NoError(t, pool.Verify())
+
+	// the keys of the pending (0x01) and committed (0x00) evidence
+	keys := func(prefix byte) [][]byte {
+		iter, err := dbm.IteratePrefix(evidenceDB, []byte{prefix})
+		require.NoError(t, err)
+		defer iter.Close()
+		var keys [][]byte
+		for ; iter.Valid(); iter.Next() {
+			keys = append(keys, iter.Key())
+		}
+		return keys
+	}
+	pendingKey, committedKey := keys(0x01)[0], keys(0x00)[0]
+
+	t.Run("undecodable pending evidence", func(t *testing.T) {
+		value, err := evidenceDB.Get(pendingKey)
+		require.NoError(t, err)
+		require.NoError(t, evidenceDB.Set(pendingKey, []byte("garbage")))
+		defer func() { require.NoError(t, evidenceDB.Set(pendingKey, value)) }()
+		require.ErrorContains(t, pool.Verify(), "does not decode")
+	})
+
+	t.Run("undecodable committed evidence", func(t *testing.T) {
+		value, err := evidenceDB.Get(committedKey)
+		require.NoError(t, err)
+		require.NoError(t, evidenceDB.Set(committedKey, []byte("garbage")))
+		defer func() { require.NoError(t, evidenceDB.Set(committedKey, value)) }()
+		require.ErrorContains(t, pool.Verify(), "does not decode")
+	})
+
+	t.Run("pending count mismatch", func(t *testing.T) {
+		value, err := evidenceDB.Get(pendingKey)
+		require.NoError(t, err)
+		require.NoError(t, evidenceDB.Delete(pendingKey))
+		defer func() { require.NoError(t, evidenceDB.Set(pendingKey, value)) }()
+		require.ErrorContains(t, pool.Verify(), "store holds 0 pending evidence, but the pool size is 1")
+	})
+
+	require.NoError(t, pool.Verify())
+}
+
 // Tests that restarting the evidence pool after a potential failure will recover the
 // pending evidence and continue to gossip it
 func TestRecoverPendingEvidence(t *testing.T) {
@@ -355,73 +910,753 @@ func TestRecoverPendingEvidence(t *testing.T) {
 	assert.Equal(t, goodEvidence, next.Value.(types.Evidence))
 }
 
-func initializeStateFromValidatorSet(valSet *types.ValidatorSet, height int64) sm.Store {
-	stateDB := dbm.NewMemDB()
-	stateStore := sm.NewStore(stateDB, sm.StoreOptions{
-		DiscardABCIResponses: false,
-	})
-	state := sm.State{
-		ChainID:                     evidenceChainID,
-		InitialHeight:               1,
-		LastBlockHeight:             height,
-		LastBlockTime:               defaultEvidenceTime,
-		Validators:                  valSet,
-		NextValidators:              valSet.CopyIncrementProposerPriority(1),
-		LastValidators:              valSet,
-		LastHeightValidatorsChanged: 1,
-		ConsensusParams: types.ConsensusParams{
-			Block: types.BlockParams{
-				MaxBytes: 22020096,
-				MaxGas:   -1,
-			},
-			Evidence: types.EvidenceParams{
-				MaxAgeNumBlocks: 20,
-				MaxAgeDuration:  20 * time.Minute,
-				MaxBytes:        1000,
-			},
-		},
-	}
-
-	// save all states up to height
-	for i := int64(0); i <= height; i++ {
-		state.LastBlockHeight = i
-		if err := stateStore.Save(state); err != nil {
-			panic(err)
-		}
-	}
-
-	return stateStore
-}
+func TestEvidencePoolDBStats(t *testing.T) {
+	var height int64 = 1
+	pool, val := defaultTestPool(t, height)
 
-func initializeValidatorState(privVal types.PrivValidator, height int64) sm.Store {
-	pubKey, _ := privVal.GetPubKey()
-	validator := &types.Validator{Address: pubKey.Address(), VotingPower: 10, PubKey: pubKey}
+	stats := pool.DBStats()
+	assert.Equal(t, "memDB", stats["database.type"])
+	assert.Equal(t, "0", stats["database.size"])
 
-	// create validator set and state
-	valSet := &types.ValidatorSet{
-		Validators: []*types.Validator{validator},
-		Proposer:   validator,
-	}
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(height, defaultEvidenceTime.Add(1*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	require.NoError(t, pool.AddEvidence(ev))
 
-	return initializeStateFromValidatorSet(valSet, height)
+	stats = pool.DBStats()
+	assert.Equal(t, "1", stats["database.size"])
 }
 
-// initializeBlockStore creates a block storage and populates it w/ a dummy
-// block at +height+.
-func initializeBlockStore(db dbm.DB, state sm.State, valAddr []byte) (*store.BlockStore, error) {
-	blockStore := store.NewBlockStore(db)
+func TestEvidenceByValidator(t *testing.T) {
+	var (
+		height       int64 = 100
+		commonHeight int64 = 90
+		dveHeight    int64 = 95
+	)
 
-	for i := int64(1); i <= state.LastBlockHeight; i++ {
-		lastCommit := makeExtCommit(i-1, valAddr)
-		block := state.MakeBlock(i, test.MakeNTxs(i, 1), lastCommit.ToCommit(), nil, state.Validators.Proposer.Address)
-		block.Time = defaultEvidenceTime.Add(time.Duration(i) * time.Minute)
-		block.Version = cmtversion.Consensus{Block: version.BlockProtocol, App: 1}
-		partSet, err := block.MakePartSet(types.BlockPartSizeBytes)
-		if err != nil {
+	lcaEv, trusted, common := makeLunaticEvidence(t, height, commonHeight,
+		10, 5, 5, defaultEvidenceTime, defaultEvidenceTime.Add(1*time.Hour))
+	dveValSet, dvePrivVals := types.RandValidatorSet(1, 10)
+	dveEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(dveHeight, defaultEvidenceTime,
+		dvePrivVals[0], evidenceChainID)
+	require.NoError(t, err)
+
+	stateStore := &smmocks.Store{}
+	stateStore.On("LoadValidators", height).Return(trusted.ValidatorSet, nil)
+	stateStore.On("LoadValidators", commonHeight).Return(common.ValidatorSet, nil)
+	stateStore.On("LoadValidators", dveHeight).Return(dveValSet, nil)
+	stateStore.On("Load").Return(sm.State{
+		ChainID:         evidenceChainID,
+		LastBlockTime:   defaultEvidenceTime.Add(2 * time.Hour),
+		LastBlockHeight: 110,
+		ConsensusParams: *types.DefaultConsensusParams(),
+	}, nil)
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("LoadBlockMeta", height).Return(&types.BlockMeta{Header: *trusted.Header})
+	blockStore.On("LoadBlockMeta", commonHeight).Return(&types.BlockMeta{Header: *common.Header})
+	blockStore.On("LoadBlockMeta", dveHeight).Return(&types.BlockMeta{Header: types.Header{Time: defaultEvidenceTime}})
+	blockStore.On("LoadBlockCommit", height).Return(trusted.Commit)
+	blockStore.On("LoadBlockCommit", commonHeight).Return(common.Commit)
+
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+	require.NoError(t, pool.AddEvidence(lcaEv))
+	require.NoError(t, pool.AddEvidence(dveEv))
+
+	evs, err := pool.EvidenceByValidator(dveValSet.Validators[0].Address)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Evidence{dveEv}, evs)
+
+	for _, val := range lcaEv.ByzantineValidators {
+		evs, err = pool.EvidenceByValidator(val.Address)
+		require.NoError(t, err)
+		assert.Equal(t, []types.Evidence{lcaEv}, evs)
+	}
+
+	evs, err = pool.EvidenceByValidator(types.Address("not a validator"))
+	require.NoError(t, err)
+	assert.Empty(t, evs)
+}
+
+func TestEvidencePoolOnByzantineValidator(t *testing.T) {
+	var (
+		height       int64 = 100
+		commonHeight int64 = 90
+		dveHeight    int64 = 95
+	)
+
+	lcaEv, trusted, common := makeLunaticEvidence(t, height, commonHeight,
+		10, 5, 5, defaultEvidenceTime, defaultEvidenceTime.Add(1*time.Hour))
+	dveValSet, dvePrivVals := types.RandValidatorSet(1, 10)
+	dveEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(dveHeight, defaultEvidenceTime,
+		dvePrivVals[0], evidenceChainID)
+	require.NoError(t, err)
+	dveEv2, err := types.NewMockDuplicateVoteEvidenceWithValidator(dveHeight+1, defaultEvidenceTime,
+		dvePrivVals[0], evidenceChainID)
+	require.NoError(t, err)
+
+	stateStore := &smmocks.Store{}
+	stateStore.On("LoadValidators", height).Return(trusted.ValidatorSet, nil)
+	stateStore.On("LoadValidators", commonHeight).Return(common.ValidatorSet, nil)
+	stateStore.On("LoadValidators", mock.AnythingOfType("int64")).Return(dveValSet, nil)
+	stateStore.On("Load").Return(sm.State{
+		ChainID:         evidenceChainID,
+		LastBlockTime:   defaultEvidenceTime.Add(2 * time.Hour),
+		LastBlockHeight: 110,
+		ConsensusParams: *types.DefaultConsensusParams(),
+	}, nil)
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("LoadBlockMeta", height).Return(&types.BlockMeta{Header: *trusted.Header})
+	blockStore.On("LoadBlockMeta", commonHeight).Return(&types.BlockMeta{Header: *common.Header})
+	blockStore.On("LoadBlockMeta", mock.AnythingOfType("int64")).Return(
+		&types.BlockMeta{Header: types.Header{Time: defaultEvidenceTime}})
+	blockStore.On("LoadBlockCommit", height).Return(trusted.Commit)
+	blockStore.On("LoadBlockCommit", commonHeight).Return(common.Commit)
+
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	type call struct {
+		addr types.Address
+		ev   types.Evidence
+	}
+	var calls []call
+	pool.SetOnByzantineValidator(func(addr types.Address, ev types.Evidence) {
+		calls = append(calls, call{addr, ev})
+	})
+
+	require.NoError(t, pool.AddEvidence(lcaEv))
+	require.NoError(t, pool.AddEvidence(dveEv))
+	// neither the same evidence again nor new evidence against a validator
+	// already seen trigger the callback
+	require.NoError(t, pool.AddEvidence(dveEv))
+	require.NoError(t, pool.AddEvidence(dveEv2))
+
+	var want []call
+	for _, val := range lcaEv.ByzantineValidators {
+		want = append(want, call{val.Address, lcaEv})
+	}
+	want = append(want, call{dveValSet.Validators[0].Address, dveEv})
+	assert.Equal(t, want, calls)
+
+	// evidence formed from the votes reported by consensus triggers it too
+	var consensusHeight int64 = 10
+	pool, pv := defaultTestPool(t, consensusHeight)
+	var addrs []types.Address
+	pool.SetOnByzantineValidator(func(addr types.Address, _ types.Evidence) {
+		addrs = append(addrs, addr)
+	})
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(consensusHeight+1, defaultEvidenceTime, pv, evidenceChainID)
+	require.NoError(t, err)
+	pool.ReportConflictingVotes(ev.VoteA, ev.VoteB)
+	state := pool.State()
+	state.LastBlockHeight++
+	state.LastBlockTime = ev.Time()
+	state.LastValidators = types.NewValidatorSet([]*types.Validator{types.NewValidator(pv.PrivKey.PubKey(), 10)})
+	pool.Update(state, []types.Evidence{})
+	assert.Equal(t, []types.Address{pv.PrivKey.PubKey().Address()}, addrs)
+}
+
+func TestEvidencePoolLazyLoad(t *testing.T) {
+	const (
+		height      int64 = 10
+		numEvidence       = 5
+	)
+	val := types.NewMockPV()
+	evidenceDB := dbm.NewMemDB()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+
+	newEvidence := func() types.Evidence {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(height,
+			defaultEvidenceTime.Add(time.Duration(height)*time.Minute), val, evidenceChainID)
+		require.NoError(t, err)
+		return ev
+	}
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	for i := 0; i < numEvidence; i++ {
+		require.NoError(t, pool.AddEvidence(newEvidence()))
+	}
+
+	countListed := func(pool *evidence.Pool) int {
+		n := 0
+		for e := pool.EvidenceFront(); e != nil; e = e.Next() {
+			n++
+		}
+		return n
+	}
+
+	// the size is known right away, but the evidence is only listed once
+	// the list is used
+	pool, err = evidence.NewPool(evidenceDB, stateStore, blockStore, evidence.WithLazyLoad())
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+	assert.EqualValues(t, numEvidence, pool.Size())
+
+	// evidence added before the list is loaded is listed once
+	require.NoError(t, pool.AddEvidence(newEvidence()))
+	assert.EqualValues(t, numEvidence+1, pool.Size())
+
+	select {
+	case <-pool.EvidenceWaitChan():
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending evidence was not loaded")
+	}
+	require.Eventually(t, func() bool {
+		return countListed(pool) == numEvidence+1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, pool.Verify())
+
+	// and so is evidence added once it is loaded
+	require.NoError(t, pool.AddEvidence(newEvidence()))
+	assert.EqualValues(t, numEvidence+2, pool.Size())
+	assert.Equal(t, numEvidence+2, countListed(pool))
+	require.NoError(t, pool.Verify())
+}
+
+func TestEvidencePoolMaxPendingEvidence(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore, evidence.WithMaxPendingEvidence(1))
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	ev1, err := types.NewMockDuplicateVoteEvidenceWithValidator(1, defaultEvidenceTime.Add(1*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	ev2, err := types.NewMockDuplicateVoteEvidenceWithValidator(2, defaultEvidenceTime.Add(2*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+
+	require.NoError(t, pool.AddEvidence(ev1))
+	require.ErrorIs(t, pool.AddEvidence(ev2), evidence.ErrEvidencePoolFull)
+	// evidence that is already pending is still accepted
+	require.NoError(t, pool.AddEvidence(ev1))
+	assert.EqualValues(t, 1, pool.Size())
+
+	// evidence in a block is verified but not added to the full pool
+	require.NoError(t, pool.CheckEvidence(types.EvidenceList{ev2}))
+	assert.EqualValues(t, 1, pool.Size())
+}
+
+func TestEvidencePoolQuarantine(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	evidenceDB := dbm.NewMemDB()
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	ev1, err := types.NewMockDuplicateVoteEvidenceWithValidator(1, defaultEvidenceTime.Add(1*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	ev2, err := types.NewMockDuplicateVoteEvidenceWithValidator(2, defaultEvidenceTime.Add(2*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	require.NoError(t, pool.AddEvidence(ev1))
+	require.NoError(t, pool.AddEvidence(ev2))
+
+	// quarantined evidence is neither proposed nor gossiped
+	require.NoError(t, pool.Quarantine(ev1.Hash()))
+	assert.EqualValues(t, 1, pool.Size())
+	evList, _ := pool.PendingEvidence(-1)
+	assert.Equal(t, []types.Evidence{ev2}, evList)
+	require.NotNil(t, pool.EvidenceFront())
+	assert.Equal(t, ev2, pool.EvidenceFront().Value)
+	assert.Nil(t, pool.EvidenceFront().Next())
+	require.NoError(t, pool.Verify())
+
+	require.ErrorIs(t, pool.Quarantine(ev1.Hash()), evidence.ErrEvidenceNotPending)
+	require.ErrorIs(t, pool.Release(ev2.Hash()), evidence.ErrEvidenceNotQuarantined)
+
+	// nor added back when received again
+	require.NoError(t, pool.AddEvidence(ev1))
+	assert.EqualValues(t, 1, pool.Size())
+
+	// it stays quarantined across restarts
+	pool, err = evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+	evList, _ = pool.PendingEvidence(-1)
+	assert.Equal(t, []types.Evidence{ev2}, evList)
+
+	// until it is released
+	require.NoError(t, pool.Release(ev1.Hash()))
+	assert.EqualValues(t, 2, pool.Size())
+	evList, _ = pool.PendingEvidence(-1)
+	assert.Equal(t, []types.Evidence{ev1, ev2}, evList)
+	require.NoError(t, pool.Verify())
+	require.ErrorIs(t, pool.Release(ev1.Hash()), evidence.ErrEvidenceNotQuarantined)
+
+	// committing quarantined evidence removes it from the quarantine
+	require.NoError(t, pool.Quarantine(ev1.Hash()))
+	state.LastBlockHeight++
+	state.LastBlockTime = state.LastBlockTime.Add(time.Minute)
+	pool.Update(state, types.EvidenceList{ev1})
+	require.ErrorIs(t, pool.Release(ev1.Hash()), evidence.ErrEvidenceNotQuarantined)
+	require.NoError(t, pool.CheckEvidence(types.EvidenceList{ev2}))
+	require.Error(t, pool.CheckEvidence(types.EvidenceList{ev1}))
+}
+
+// labelCounter is a metrics.Counter recording the label values of each
+// increment.
+type labelCounter struct {
+	lvs     []string
+	counted *[][]string
+}
+
+func (c labelCounter) With(labelValues ...string) metrics.Counter {
+	return labelCounter{lvs: append(append([]string{}, c.lvs...), labelValues...), counted: c.counted}
+}
+
+func (c labelCounter) Add(float64) {
+	*c.counted = append(*c.counted, c.lvs)
+}
+
+func TestEvidencePoolVerificationFailureMetrics(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	var failures [][]string
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore, evidence.WithMetrics(&evidence.Metrics{
+		EvidenceVerificationFailures: labelCounter{counted: &failures},
+	}))
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(1, defaultEvidenceTime.Add(1*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	require.Error(t, pool.CheckEvidence(types.EvidenceList{ev, ev}))
+
+	// evidence from a validator which is not in the validator set
+	invalidEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(1, defaultEvidenceTime.Add(1*time.Minute),
+		types.NewMockPV(), evidenceChainID)
+	require.NoError(t, err)
+	require.Error(t, pool.AddEvidence(invalidEv))
+	require.Error(t, pool.CheckEvidence(types.EvidenceList{invalidEv}))
+
+	// accepted evidence is not counted
+	require.NoError(t, pool.AddEvidence(ev))
+	require.NoError(t, pool.CheckEvidence(types.EvidenceList{ev}))
+
+	state.LastBlockHeight++
+	state.LastBlockTime = state.LastBlockTime.Add(time.Minute)
+	pool.Update(state, types.EvidenceList{ev})
+	require.NoError(t, pool.AddEvidence(ev))
+	require.Error(t, pool.CheckEvidence(types.EvidenceList{ev}))
+
+	assert.Equal(t, [][]string{
+		{"reason", "duplicate"},
+		{"reason", "verify-failed"},
+		{"reason", "verify-failed"},
+		{"reason", "already-committed"},
+		{"reason", "already-committed"},
+	}, failures)
+}
+
+func TestEvidencePoolMaxProposalEvidenceBytes(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	var evs []types.Evidence
+	for h := int64(1); h <= 3; h++ {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, defaultEvidenceTime.Add(time.Duration(h)*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		evs = append(evs, ev)
+	}
+	sizeOf := func(evs ...types.Evidence) int64 {
+		var evList cmtproto.EvidenceList
+		for _, ev := range evs {
+			evpb, err := types.EvidenceToProto(ev)
+			require.NoError(t, err)
+			evList.Evidence = append(evList.Evidence, *evpb)
+		}
+		return int64(evList.Size())
+	}
+
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore,
+		evidence.WithMaxProposalEvidenceBytes(sizeOf(evs[:2]...)))
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+	for _, ev := range evs {
+		require.NoError(t, pool.AddEvidence(ev))
+	}
+
+	// the local cap is tighter than the caller's
+	evList, size := pool.PendingEvidence(sizeOf(evs...))
+	assert.Equal(t, evs[:2], evList)
+	assert.Equal(t, sizeOf(evs[:2]...), size)
+
+	// the caller's cap is tighter than the local one
+	evList, _ = pool.PendingEvidence(sizeOf(evs[0]))
+	assert.Equal(t, evs[:1], evList)
+
+	// requesting all the pending evidence is not capped
+	evList, _ = pool.PendingEvidence(-1)
+	assert.Equal(t, evs, evList)
+}
+
+func TestEvidencePoolPendingEvidenceStats(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	count, oldest, newest := pool.PendingEvidenceStats()
+	assert.Zero(t, count)
+	assert.Zero(t, oldest)
+	assert.Zero(t, newest)
+
+	for _, h := range []int64{5, 2, 8} {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, defaultEvidenceTime.Add(time.Duration(h)*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		require.NoError(t, pool.AddEvidence(ev))
+	}
+
+	count, oldest, newest = pool.PendingEvidenceStats()
+	assert.EqualValues(t, 3, count)
+	assert.EqualValues(t, 2, oldest)
+	assert.EqualValues(t, 8, newest)
+}
+
+func TestEvidencePoolHasPendingEvidenceBefore(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStore(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address())
+	require.NoError(t, err)
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	// empty pool
+	assert.False(t, pool.HasPendingEvidenceBefore(height))
+
+	addEvidence := func(h int64) {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, defaultEvidenceTime.Add(time.Duration(h)*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		require.NoError(t, pool.AddEvidence(ev))
+	}
+
+	// all the evidence is after the height
+	addEvidence(8)
+	addEvidence(5)
+	assert.False(t, pool.HasPendingEvidenceBefore(1))
+	assert.False(t, pool.HasPendingEvidenceBefore(4))
+
+	// evidence at and before the height
+	assert.True(t, pool.HasPendingEvidenceBefore(5))
+	assert.True(t, pool.HasPendingEvidenceBefore(6))
+	assert.True(t, pool.HasPendingEvidenceBefore(height))
+	addEvidence(2)
+	assert.True(t, pool.HasPendingEvidenceBefore(2))
+	assert.False(t, pool.HasPendingEvidenceBefore(1))
+}
+
+func TestEvidencePoolReplayFromBlockStore(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+
+	pendingEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(3, defaultEvidenceTime.Add(3*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	unknownEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(5, defaultEvidenceTime.Add(5*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStoreWithEvidence(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address(),
+		map[int64][]types.Evidence{4: {pendingEv}, 6: {unknownEv}})
+	require.NoError(t, err)
+
+	// the evidence database knows nothing about the committed evidence
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+	require.NoError(t, pool.AddEvidence(pendingEv))
+	require.EqualValues(t, 1, pool.Size())
+
+	require.Error(t, pool.ReplayFromBlockStore(5, 4))
+	require.Error(t, pool.ReplayFromBlockStore(1, height+1))
+
+	// replaying a part of the range, then all of it, is fine
+	require.NoError(t, pool.ReplayFromBlockStore(1, 4))
+	assert.EqualValues(t, 0, pool.Size())
+	require.NoError(t, pool.ReplayFromBlockStore(1, height))
+
+	// committed evidence is neither pending nor accepted again
+	for _, ev := range []types.Evidence{pendingEv, unknownEv} {
+		require.NoError(t, pool.AddEvidence(ev))
+		assert.EqualValues(t, 0, pool.Size())
+		assert.Error(t, pool.CheckEvidence(types.EvidenceList{ev}))
+	}
+}
+
+func TestCheckConsistency(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+
+	newEvidence := func(h int64) types.Evidence {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, defaultEvidenceTime.Add(time.Duration(h)*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		return ev
+	}
+	// committedEv is in a block and marked committed, unmarkedEv is only in a
+	// block and strayEv is only marked committed
+	committedEv, unmarkedEv, strayEv := newEvidence(2), newEvidence(5), newEvidence(6)
+	blockStore, err := initializeBlockStoreWithEvidence(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address(),
+		map[int64][]types.Evidence{3: {committedEv}, 7: {unmarkedEv}})
+	require.NoError(t, err)
+
+	evidenceDB := dbm.NewMemDB()
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	require.NoError(t, pool.ReplayFromBlockStore(1, 4))
+
+	_, err = evidence.CheckConsistency(evidenceDB, blockStore, 0, 4)
+	require.Error(t, err)
+	_, err = evidence.CheckConsistency(evidenceDB, blockStore, 1, height+1)
+	require.Error(t, err)
+
+	report, err := evidence.CheckConsistency(evidenceDB, blockStore, 1, height)
+	require.NoError(t, err)
+	assert.False(t, report.Consistent())
+	assert.Empty(t, report.MissingBlocks)
+	assert.Equal(t, []evidence.CommittedEvidenceRef{{Height: 5, Hash: unmarkedEv.Hash(), BlockHeight: 7}},
+		report.NotMarkedCommitted)
+	assert.Empty(t, report.NotInBlockStore)
+
+	state.LastBlockHeight++
+	pool.Update(state, types.EvidenceList{strayEv})
+	report, err = evidence.CheckConsistency(evidenceDB, blockStore, 1, height)
+	require.NoError(t, err)
+	assert.Equal(t, []evidence.CommittedEvidenceRef{{Height: 6, Hash: strayEv.Hash()}}, report.NotInBlockStore)
+
+	// evidence of height 2, committed at height 3, is found past the range
+	report, err = evidence.CheckConsistency(evidenceDB, blockStore, 1, 2)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+
+	require.NoError(t, pool.ReplayFromBlockStore(1, height))
+	report, err = evidence.CheckConsistency(evidenceDB, blockStore, 1, 5)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+}
+
+func TestCheckConsistencyWithCommittedEvidenceRetention(t *testing.T) {
+	height := int64(60)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+
+	newEvidence := func(h int64) types.Evidence {
+		ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(h, defaultEvidenceTime.Add(time.Duration(h)*time.Minute),
+			val, evidenceChainID)
+		require.NoError(t, err)
+		return ev
+	}
+	// unmarkedEv is above the retention, so it is still reported
+	oldEv, newEv, unmarkedEv := newEvidence(5), newEvidence(50), newEvidence(52)
+	blockStore, err := initializeBlockStoreWithEvidence(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address(),
+		map[int64][]types.Evidence{6: {oldEv}, 51: {newEv}, 53: {unmarkedEv}})
+	require.NoError(t, err)
+
+	evidenceDB := dbm.NewMemDB()
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore, evidence.WithCommittedEvidenceRetention(30))
+	require.NoError(t, err)
+	require.NoError(t, pool.ReplayFromBlockStore(1, 52))
+
+	// the committed entries below height 31 are pruned
+	state.LastBlockHeight = height + 1
+	state.LastBlockTime = defaultEvidenceTime.Add(time.Duration(height+1) * time.Minute)
+	state.ConsensusParams.Evidence.MaxAgeDuration = 20 * time.Minute
+	pool.Update(state, nil)
+
+	report, err := evidence.CheckConsistency(evidenceDB, blockStore, 1, height)
+	require.NoError(t, err)
+	assert.EqualValues(t, 31, report.PrunedBelow)
+	assert.Equal(t, []evidence.CommittedEvidenceRef{{Height: 52, Hash: unmarkedEv.Hash(), BlockHeight: 53}},
+		report.NotMarkedCommitted)
+	assert.Empty(t, report.NotInBlockStore)
+
+	require.NoError(t, pool.ReplayFromBlockStore(52, height))
+	report, err = evidence.CheckConsistency(evidenceDB, blockStore, 1, height)
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+}
+
+func TestEvidencePoolReplayFromBlockStoreConcurrentWithUpdate(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(3, defaultEvidenceTime.Add(3*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStoreWithEvidence(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address(),
+		map[int64][]types.Evidence{4: {ev}})
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+		require.NoError(t, err)
+		require.NoError(t, pool.AddEvidence(ev))
+
+		// the evidence is committed in the new block, expired, and in the
+		// replayed range
+		newState := pool.State()
+		newState.LastBlockHeight = height + 1
+		newState.LastBlockTime = defaultEvidenceTime.Add(11 * time.Minute)
+		newState.ConsensusParams.Evidence.MaxAgeNumBlocks = 5
+		newState.ConsensusParams.Evidence.MaxAgeDuration = 5 * time.Minute
+
+		var wg sync.WaitGroup
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			pool.Update(newState, types.EvidenceList{ev})
+		}()
+		go func() {
+			defer wg.Done()
+			pool.PruneExpired()
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, pool.ReplayFromBlockStore(1, height))
+		}()
+		wg.Wait()
+
+		require.EqualValues(t, 0, pool.Size())
+	}
+}
+
+func initializeStateFromValidatorSet(valSet *types.ValidatorSet, height int64) sm.Store {
+	stateDB := dbm.NewMemDB()
+	stateStore := sm.NewStore(stateDB, sm.StoreOptions{
+		DiscardABCIResponses: false,
+	})
+	state := sm.State{
+		ChainID:                     evidenceChainID,
+		InitialHeight:               1,
+		LastBlockHeight:             height,
+		LastBlockTime:               defaultEvidenceTime,
+		Validators:                  valSet,
+		NextValidators:              valSet.CopyIncrementProposerPriority(1),
+		LastValidators:              valSet,
+		LastHeightValidatorsChanged: 1,
+		ConsensusParams: types.ConsensusParams{
+			Block: types.BlockParams{
+				MaxBytes: 22020096,
+				MaxGas:   -1,
+			},
+			Evidence: types.EvidenceParams{
+				MaxAgeNumBlocks: 20,
+				MaxAgeDuration:  20 * time.Minute,
+				MaxBytes:        1000,
+			},
+		},
+	}
+
+	// save all states up to height
+	for i := int64(0); i <= height; i++ {
+		state.LastBlockHeight = i
+		if err := stateStore.Save(state); err != nil {
+			panic(err)
+		}
+	}
+
+	return stateStore
+}
+
+func initializeValidatorState(privVal types.PrivValidator, height int64) sm.Store {
+	pubKey, _ := privVal.GetPubKey()
+	validator := &types.Validator{Address: pubKey.Address(), VotingPower: 10, PubKey: pubKey}
+
+	// create validator set and state
+	valSet := &types.ValidatorSet{
+		Validators: []*types.Validator{validator},
+		Proposer:   validator,
+	}
+
+	return initializeStateFromValidatorSet(valSet, height)
+}
+
+// initializeBlockStore creates a block storage and populates it w/ a dummy
+// block at +height+.
+func initializeBlockStore(db dbm.DB, state sm.State, valAddr []byte) (*store.BlockStore, error) {
+	return initializeBlockStoreWithEvidence(db, state, valAddr, nil)
+}
+
+// initializeBlockStoreWithEvidence is like initializeBlockStore, but includes
+// the given evidence in the blocks at the corresponding heights.
+func initializeBlockStoreWithEvidence(
+	db dbm.DB,
+	state sm.State,
+	valAddr []byte,
+	evidence map[int64][]types.Evidence,
+) (*store.BlockStore, error) {
+	blockStore := store.NewBlockStore(db)
+
+	var lastBlockID types.BlockID
+	for i := int64(1); i <= state.LastBlockHeight; i++ {
+		lastCommit := makeExtCommit(i-1, valAddr)
+		lastCommit.BlockID = lastBlockID
+		block := state.MakeBlock(i, test.MakeNTxs(i, 1), lastCommit.ToCommit(), evidence[i], state.Validators.Proposer.Address)
+		block.Time = defaultEvidenceTime.Add(time.Duration(i) * time.Minute)
+		block.Version = cmtversion.Consensus{Block: version.BlockProtocol, App: 1}
+		partSet, err := block.MakePartSet(types.BlockPartSizeBytes)
+		if err != nil {
 			return nil, err
 		}
 
+		// the commits must be for the stored blocks for LoadBlock to succeed
+		lastBlockID = types.BlockID{Hash: block.Hash(), PartSetHeader: partSet.Header()}
 		seenCommit := makeExtCommit(i, valAddr)
+		seenCommit.BlockID = lastBlockID
 		blockStore.SaveBlockWithExtendedCommit(block, partSet, seenCommit)
 	}
 
@@ -469,3 +1704,46 @@ func createState(height int64, valSet *types.ValidatorSet) sm.State {
 		ConsensusParams: *types.DefaultConsensusParams(),
 	}
 }
+
+func TestExportEvidence(t *testing.T) {
+	height := int64(10)
+	val := types.NewMockPV()
+	stateStore := initializeValidatorState(val, height)
+	state, err := stateStore.Load()
+	require.NoError(t, err)
+
+	committedEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(3, defaultEvidenceTime.Add(3*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	prunedEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(8, defaultEvidenceTime.Add(8*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	pendingEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(7, defaultEvidenceTime.Add(7*time.Minute),
+		val, evidenceChainID)
+	require.NoError(t, err)
+	blockStore, err := initializeBlockStoreWithEvidence(dbm.NewMemDB(), state, val.PrivKey.PubKey().Address(),
+		map[int64][]types.Evidence{4: {committedEv}})
+	require.NoError(t, err)
+
+	evidenceDB := dbm.NewMemDB()
+	pool, err := evidence.NewPool(evidenceDB, stateStore, blockStore)
+	require.NoError(t, err)
+	require.NoError(t, pool.ReplayFromBlockStore(1, height))
+	require.NoError(t, pool.AddEvidence(pendingEv))
+	// prunedEv is committed in a block which is not in the block store
+	state.LastBlockHeight = height + 1
+	state.LastBlockTime = defaultEvidenceTime.Add(11 * time.Minute)
+	pool.Update(state, types.EvidenceList{prunedEv})
+
+	evList, missing, err := evidence.ExportEvidence(evidenceDB, nil, true, false)
+	require.NoError(t, err)
+	assert.Equal(t, []types.Evidence{pendingEv}, evList)
+	assert.Empty(t, missing)
+
+	evList, missing, err = evidence.ExportEvidence(evidenceDB, blockStore, true, true)
+	require.NoError(t, err)
+	require.Len(t, evList, 2)
+	assert.Equal(t, pendingEv.Hash(), evList[0].Hash())
+	assert.Equal(t, committedEv.Hash(), evList[1].Hash())
+	assert.Equal(t, [][]byte{prunedEv.Hash()}, missing)
+}
@@ -0,0 +1,128 @@
+package evidence
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	dbm "github.com/cometbft/cometbft-db"
+
+	"github.com/cometbft/cometbft/types"
+)
+
+var (
+	// ErrEvidenceNotPending is returned by Quarantine when the pool has no
+	// pending evidence with the given hash.
+	ErrEvidenceNotPending = errors.New("evidence is not pending")
+	// ErrEvidenceNotQuarantined is returned by Release when the pool has no
+	// quarantined evidence with the given hash.
+	ErrEvidenceNotQuarantined = errors.New("evidence is not quarantined")
+)
+
+// Quarantine moves the pending evidence with the given hash out of the
+// pending evidence, e.g. to hold it back from proposals while it is reviewed.
+// Quarantined evidence is kept in the evidence store, but is neither proposed
+// nor gossiped, and is not added back when received from peers. It doesn't
+// expire either: it stays quarantined until it is released with Release or
+// committed in a block.
+func (evpool *Pool) Quarantine(hash []byte) error {
+	evpool.pendingMtx.Lock()
+	defer evpool.pendingMtx.Unlock()
+	evpool.listMtx.Lock()
+	defer evpool.listMtx.Unlock()
+
+	key, value, err := evpool.findEvidence(baseKeyPending, hash)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return ErrEvidenceNotPending
+	}
+	ev, err := bytesToEv(value)
+	if err != nil {
+		return fmt.Errorf("decoding pending evidence %q: %w", key, err)
+	}
+	if err := evpool.moveEvidence(key, keyQuarantined(ev), value); err != nil {
+		return err
+	}
+	atomic.AddUint32(&evpool.evidenceSize, ^uint32(0))
+	evpool.removeEvidenceFromList(map[string]struct{}{evMapKey(ev): {}})
+
+	evpool.logger.Info("Quarantined evidence", "evidence", ev)
+	return nil
+}
+
+// Release moves the quarantined evidence with the given hash back to the
+// pending evidence, from which it is proposed and gossiped again. Evidence
+// which has expired in the meantime is pruned on the next Update.
+func (evpool *Pool) Release(hash []byte) error {
+	evpool.pendingMtx.Lock()
+	defer evpool.pendingMtx.Unlock()
+	evpool.listMtx.Lock()
+	defer evpool.listMtx.Unlock()
+
+	key, value, err := evpool.findEvidence(baseKeyQuarantined, hash)
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return ErrEvidenceNotQuarantined
+	}
+	ev, err := bytesToEv(value)
+	if err != nil {
+		return fmt.Errorf("decoding quarantined evidence %q: %w", key, err)
+	}
+	if err := evpool.moveEvidence(key, keyPending(ev), value); err != nil {
+		return err
+	}
+	atomic.AddUint32(&evpool.evidenceSize, 1)
+	evpool.pushEvidence(ev)
+
+	evpool.logger.Info("Released evidence from quarantine", "evidence", ev)
+	return nil
+}
+
+// isQuarantined checks whether the evidence is quarantined. DB errors are
+// passed to the logger.
+func (evpool *Pool) isQuarantined(evidence types.Evidence) bool {
+	return evpool.hasKey(keyQuarantined(evidence), "quarantined")
+}
+
+// findEvidence returns the key and value of the evidence with the given hash
+// under prefix, or a nil key if there is none. Keys start with the height of
+// the evidence, so this scans all the evidence under prefix.
+func (evpool *Pool) findEvidence(prefix byte, hash []byte) (key, value []byte, err error) {
+	iter, err := dbm.IteratePrefix(evpool.evidenceStore, []byte{prefix})
+	if err != nil {
+		return nil, nil, fmt.Errorf("database error: %v", err)
+	}
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		_, evHash, err := parseKeySuffix(iter.Key()[1:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid evidence key %q: %w", iter.Key(), err)
+		}
+		if bytes.Equal(evHash, hash) {
+			// the iterator may reuse its buffers once it moves on
+			return bytes.Clone(iter.Key()), bytes.Clone(iter.Value()), nil
+		}
+	}
+	return nil, nil, iter.Error()
+}
+
+// moveEvidence atomically moves the evidence stored under from to to.
+func (evpool *Pool) moveEvidence(from, to, value []byte) error {
+	batch := evpool.evidenceStore.NewBatch()
+	defer batch.Close()
+	if err := batch.Set(to, value); err != nil {
+		return err
+	}
+	if err := batch.Delete(from); err != nil {
+		return err
+	}
+	if err := batch.WriteSync(); err != nil {
+		return fmt.Errorf("can't persist evidence: %w", err)
+	}
+	return nil
+}
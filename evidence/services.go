@@ -1,13 +1,40 @@
 package evidence
 
 import (
+	sm "github.com/cometbft/cometbft/state"
 	"github.com/cometbft/cometbft/types"
 )
 
 //go:generate ../scripts/mockery_generate.sh BlockStore
 
 type BlockStore interface {
+	LoadBlock(height int64) *types.Block
 	LoadBlockMeta(height int64) *types.BlockMeta
 	LoadBlockCommit(height int64) *types.Commit
 	Height() int64
 }
+
+// LightBlockFetcher retrieves light blocks from a source other than the local
+// block store, e.g. an archive node. It is used by the pool to verify
+// LightClientAttackEvidence whose common height has been pruned locally.
+// The source is trusted: fetched light blocks are only checked for basic
+// validity, i.e. that they belong to the chain and that their validator set
+// matches their header.
+type LightBlockFetcher interface {
+	LightBlock(height int64) (*types.LightBlock, error)
+}
+
+// TypedEvidence is implemented by evidence types that are not natively
+// supported by the pool. TypeURL identifies the EvidenceVerifier registered
+// for the type with Pool.RegisterEvidenceVerifier.
+type TypedEvidence interface {
+	types.Evidence
+	TypeURL() string
+}
+
+// EvidenceVerifier verifies evidence of a custom type. It is called after the
+// pool has checked that the evidence time matches the block at its height and
+// that it has not expired, and must perform all type-specific checks.
+type EvidenceVerifier interface {
+	Verify(ev types.Evidence, state sm.State) error
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/cometbft/cometbft/light"
@@ -24,11 +25,10 @@ func (evpool *Pool) verify(evidence types.Evidence) error {
 	)
 
 	// verify the time of the evidence
-	blockMeta := evpool.blockStore.LoadBlockMeta(evidence.Height())
-	if blockMeta == nil {
-		return fmt.Errorf("don't have header #%d", evidence.Height())
+	evTime, err := evpool.headerTime(evidence.Height())
+	if err != nil {
+		return err
 	}
-	evTime := blockMeta.Header.Time
 	if evidence.Time() != evTime {
 		return fmt.Errorf("evidence has a different time to the block it is associated with (%v != %v)",
 			evidence.Time(), evTime)
@@ -48,18 +48,18 @@ func (evpool *Pool) verify(evidence types.Evidence) error {
 	// apply the evidence-specific verification logic
 	switch ev := evidence.(type) {
 	case *types.DuplicateVoteEvidence:
-		valSet, err := evpool.stateDB.LoadValidators(evidence.Height())
+		valSet, err := evpool.validators(evidence.Height())
 		if err != nil {
 			return err
 		}
 		return VerifyDuplicateVote(ev, state.ChainID, valSet)
 
 	case *types.LightClientAttackEvidence:
-		commonHeader, err := getSignedHeader(evpool.blockStore, evidence.Height())
+		commonHeader, err := evpool.signedHeader(evidence.Height())
 		if err != nil {
 			return err
 		}
-		commonVals, err := evpool.stateDB.LoadValidators(evidence.Height())
+		commonVals, err := evpool.validators(evidence.Height())
 		if err != nil {
 			return err
 		}
@@ -93,6 +93,9 @@ func (evpool *Pool) verify(evidence types.Evidence) error {
 		}
 		return nil
 	default:
+		if verifier := evpool.evidenceVerifier(evidence); verifier != nil {
+			return verifier.Verify(evidence, state)
+		}
 		return fmt.Errorf("unrecognized evidence type: %T", evidence)
 	}
 }
@@ -214,6 +217,14 @@ func VerifyDuplicateVote(e *types.DuplicateVoteEvidence, chainID string, valSet
 			e.TotalVotingPower, valSet.TotalVotingPower())
 	}
 
+	// Signatures must have the size of the validator's key type
+	if err := types.ValidateSignatureSize(pubKey, e.VoteA.Signature); err != nil {
+		return fmt.Errorf("verifying VoteA: %w", err)
+	}
+	if err := types.ValidateSignatureSize(pubKey, e.VoteB.Signature); err != nil {
+		return fmt.Errorf("verifying VoteB: %w", err)
+	}
+
 	va := e.VoteA.ToProto()
 	vb := e.VoteB.ToProto()
 	// Signatures must be valid
@@ -276,6 +287,75 @@ func validateABCIEvidence(
 	return nil
 }
 
+// headerTime returns the time of the header at the given height. If the block
+// store no longer has the header (e.g. because it was pruned), the light block
+// fetcher is used, if one is configured.
+func (evpool *Pool) headerTime(height int64) (time.Time, error) {
+	if blockMeta := evpool.blockStore.LoadBlockMeta(height); blockMeta != nil {
+		return blockMeta.Header.Time, nil
+	}
+	if evpool.lightBlockFetcher == nil {
+		return time.Time{}, fmt.Errorf("don't have header #%d", height)
+	}
+	lb, err := evpool.fetchLightBlock(height)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("don't have header #%d: %w", height, err)
+	}
+	return lb.Time, nil
+}
+
+// signedHeader loads the signed header at the given height from the block
+// store, falling back to the light block fetcher, if one is configured.
+func (evpool *Pool) signedHeader(height int64) (*types.SignedHeader, error) {
+	sh, err := getSignedHeader(evpool.blockStore, height)
+	if err == nil || evpool.lightBlockFetcher == nil {
+		return sh, err
+	}
+	lb, fetchErr := evpool.fetchLightBlock(height)
+	if fetchErr != nil {
+		return nil, fmt.Errorf("%w (fetching light block failed: %v)", err, fetchErr)
+	}
+	return lb.SignedHeader, nil
+}
+
+// validators loads the validator set at the given height from the state
+// store, falling back to the light block fetcher, if one is configured.
+func (evpool *Pool) validators(height int64) (*types.ValidatorSet, error) {
+	vals, err := evpool.stateDB.LoadValidators(height)
+	if err == nil || evpool.lightBlockFetcher == nil {
+		return vals, err
+	}
+	lb, fetchErr := evpool.fetchLightBlock(height)
+	if fetchErr != nil {
+		return nil, fmt.Errorf("%w (fetching light block failed: %v)", err, fetchErr)
+	}
+	return lb.ValidatorSet, nil
+}
+
+// fetchLightBlock returns the light block at the given height, either from the
+// cache or from the light block fetcher.
+func (evpool *Pool) fetchLightBlock(height int64) (*types.LightBlock, error) {
+	if lb, ok := evpool.lightBlockCache.get(height); ok {
+		return lb, nil
+	}
+	lb, err := evpool.lightBlockFetcher.LightBlock(height)
+	if err != nil {
+		return nil, err
+	}
+	if lb == nil || lb.SignedHeader == nil || lb.Header == nil || lb.ValidatorSet == nil {
+		return nil, fmt.Errorf("light block fetcher returned an incomplete light block for height %d", height)
+	}
+	if lb.Height != height {
+		return nil, fmt.Errorf("light block fetcher returned a light block for height %d, expected %d", lb.Height, height)
+	}
+	// checks the chain ID and that the validator set matches the header
+	if err := lb.ValidateBasic(evpool.State().ChainID); err != nil {
+		return nil, fmt.Errorf("light block fetcher returned an invalid light block for height %d: %w", height, err)
+	}
+	evpool.lightBlockCache.put(lb)
+	return lb, nil
+}
+
 func getSignedHeader(blockStore BlockStore, height int64) (*types.SignedHeader, error) {
 	blockMeta := blockStore.LoadBlockMeta(height)
 	if blockMeta == nil {
@@ -301,3 +381,51 @@ func IsEvidenceExpired(heightNow int64, timeNow time.Time, heightEv int64, timeE
 	}
 	return false
 }
+
+// lightBlockCacheTTL is how long light blocks obtained from the
+// LightBlockFetcher are kept around.
+const lightBlockCacheTTL = time.Minute
+
+type cachedLightBlock struct {
+	lightBlock *types.LightBlock
+	expiresAt  time.Time
+}
+
+// lightBlockCache briefly caches fetched light blocks so that verifying
+// several pieces of evidence referencing the same height (or the same
+// evidence arriving from multiple peers) doesn't result in repeated fetches.
+type lightBlockCache struct {
+	mtx     sync.Mutex
+	entries map[int64]cachedLightBlock
+}
+
+func newLightBlockCache() *lightBlockCache {
+	return &lightBlockCache{entries: make(map[int64]cachedLightBlock)}
+}
+
+func (c *lightBlockCache) get(height int64) (*types.LightBlock, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	entry, ok := c.entries[height]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, height)
+		return nil, false
+	}
+	return entry.lightBlock, true
+}
+
+func (c *lightBlockCache) put(lb *types.LightBlock) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	now := time.Now()
+	// drop expired entries so the cache doesn't grow unbounded
+	for height, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, height)
+		}
+	}
+	c.entries[lb.Height] = cachedLightBlock{lightBlock: lb, expiresAt: now.Add(lightBlockCacheTTL)}
+}
@@ -2,6 +2,8 @@ package evidence_test
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -10,7 +12,11 @@ import (
 
 	dbm "github.com/cometbft/cometbft-db"
 
+	abci "github.com/cometbft/cometbft/abci/types"
 	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/bls12381"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/secp256k1"
 	"github.com/cometbft/cometbft/crypto/tmhash"
 	"github.com/cometbft/cometbft/evidence"
 	"github.com/cometbft/cometbft/evidence/mocks"
@@ -191,6 +197,104 @@ func TestVerify_ForwardLunaticAttack(t *testing.T) {
 	assert.Error(t, pool.CheckEvidence(types.EvidenceList{ev}))
 }
 
+type fakeLightBlockFetcher struct {
+	lightBlocks map[int64]*types.LightBlock
+	calls       int
+}
+
+func (f *fakeLightBlockFetcher) LightBlock(height int64) (*types.LightBlock, error) {
+	f.calls++
+	lb, ok := f.lightBlocks[height]
+	if !ok {
+		return nil, fmt.Errorf("no light block at height %d", height)
+	}
+	return lb, nil
+}
+
+func TestVerify_LunaticAttackWithPrunedCommonHeight(t *testing.T) {
+	const (
+		height       int64 = 10
+		commonHeight int64 = 4
+		totalVals          = 10
+		byzVals            = 4
+	)
+	attackTime := defaultEvidenceTime.Add(1 * time.Hour)
+	ev, trusted, common := makeLunaticEvidence(
+		t, height, commonHeight, totalVals, byzVals, totalVals-byzVals, defaultEvidenceTime, attackTime)
+
+	state := sm.State{
+		ChainID:         evidenceChainID,
+		LastBlockTime:   defaultEvidenceTime.Add(2 * time.Hour),
+		LastBlockHeight: height + 1,
+		ConsensusParams: *types.DefaultConsensusParams(),
+	}
+	// the node has pruned everything at the common height
+	stateStore := &smmocks.Store{}
+	stateStore.On("LoadValidators", commonHeight).Return(nil, errors.New("pruned"))
+	stateStore.On("Load").Return(state, nil)
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("LoadBlockMeta", commonHeight).Return(nil)
+	blockStore.On("LoadBlockMeta", height).Return(&types.BlockMeta{Header: *trusted.Header})
+	blockStore.On("LoadBlockCommit", height).Return(trusted.Commit)
+
+	// without a fetcher the evidence can't be verified
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore)
+	require.NoError(t, err)
+	assert.Error(t, pool.CheckEvidence(types.EvidenceList{ev}))
+
+	// with a fetcher the common light block is retrieved and verification succeeds
+	fetcher := &fakeLightBlockFetcher{lightBlocks: map[int64]*types.LightBlock{commonHeight: common}}
+	pool, err = evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore, evidence.WithLightBlockFetcher(fetcher))
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+	assert.NoError(t, pool.CheckEvidence(types.EvidenceList{ev}))
+	// the light block is fetched once and served from the cache afterwards
+	assert.Equal(t, 1, fetcher.calls)
+
+	pendingEvs, _ := pool.PendingEvidence(state.ConsensusParams.Evidence.MaxBytes)
+	require.Len(t, pendingEvs, 1)
+	assert.Equal(t, ev, pendingEvs[0])
+}
+
+func TestVerify_LunaticAttackWithInvalidFetchedLightBlock(t *testing.T) {
+	const (
+		height       int64 = 10
+		commonHeight int64 = 4
+		totalVals          = 10
+		byzVals            = 4
+	)
+	attackTime := defaultEvidenceTime.Add(1 * time.Hour)
+	ev, trusted, common := makeLunaticEvidence(
+		t, height, commonHeight, totalVals, byzVals, totalVals-byzVals, defaultEvidenceTime, attackTime)
+
+	state := sm.State{
+		ChainID:         evidenceChainID,
+		LastBlockTime:   defaultEvidenceTime.Add(2 * time.Hour),
+		LastBlockHeight: height + 1,
+		ConsensusParams: *types.DefaultConsensusParams(),
+	}
+	stateStore := &smmocks.Store{}
+	stateStore.On("LoadValidators", commonHeight).Return(nil, errors.New("pruned"))
+	stateStore.On("Load").Return(state, nil)
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("LoadBlockMeta", commonHeight).Return(nil)
+	blockStore.On("LoadBlockMeta", height).Return(&types.BlockMeta{Header: *trusted.Header})
+	blockStore.On("LoadBlockCommit", height).Return(trusted.Commit)
+
+	// the fetcher serves a validator set which doesn't match the header
+	otherVals, _ := types.RandValidatorSet(totalVals, defaultVotingPower)
+	forged := &types.LightBlock{SignedHeader: common.SignedHeader, ValidatorSet: otherVals}
+	fetcher := &fakeLightBlockFetcher{lightBlocks: map[int64]*types.LightBlock{commonHeight: forged}}
+	pool, err := evidence.NewPool(dbm.NewMemDB(), stateStore, blockStore, evidence.WithLightBlockFetcher(fetcher))
+	require.NoError(t, err)
+	pool.SetLogger(log.TestingLogger())
+
+	err = pool.CheckEvidence(types.EvidenceList{ev})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid light block")
+	assert.EqualValues(t, 0, pool.Size())
+}
+
 func TestVerifyLightClientAttack_Equivocation(t *testing.T) {
 	conflictingVals, conflictingPrivVals := types.RandValidatorSet(5, 10)
 	trustedHeader := makeHeaderRandom(10)
@@ -357,6 +461,70 @@ type voteData struct {
 	valid bool
 }
 
+// customEvidence is evidence of a type the pool doesn't natively support.
+type customEvidence struct {
+	height int64
+	time   time.Time
+}
+
+var _ evidence.TypedEvidence = customEvidence{}
+
+func (ev customEvidence) ABCI() []abci.Misbehavior { return nil }
+func (ev customEvidence) Bytes() []byte            { return []byte(ev.String()) }
+func (ev customEvidence) Hash() []byte             { return tmhash.Sum(ev.Bytes()) }
+func (ev customEvidence) Height() int64            { return ev.height }
+func (ev customEvidence) String() string           { return fmt.Sprintf("customEvidence{%d}", ev.height) }
+func (ev customEvidence) Time() time.Time          { return ev.time }
+func (ev customEvidence) ValidateBasic() error     { return nil }
+func (ev customEvidence) TypeURL() string          { return "/test.CustomEvidence" }
+
+type fakeEvidenceVerifier struct {
+	calls int
+	err   error
+}
+
+func (v *fakeEvidenceVerifier) Verify(types.Evidence, sm.State) error {
+	v.calls++
+	return v.err
+}
+
+func TestVerifyCustomEvidence(t *testing.T) {
+	var height int64 = 1
+	pool, _ := defaultTestPool(t, height)
+	ev := customEvidence{height: height, time: defaultEvidenceTime.Add(1 * time.Minute)}
+
+	// no verifier registered
+	require.Error(t, pool.CheckEvidence(types.EvidenceList{ev}))
+
+	verifier := &fakeEvidenceVerifier{}
+	pool.RegisterEvidenceVerifier(ev.TypeURL(), verifier)
+	require.NoError(t, pool.CheckEvidence(types.EvidenceList{ev}))
+	assert.Equal(t, 1, verifier.calls)
+
+	verifier.err = errors.New("invalid custom evidence")
+	require.ErrorIs(t, pool.CheckEvidence(types.EvidenceList{ev}), verifier.err)
+	assert.Equal(t, 2, verifier.calls)
+
+	// the pool's own checks still apply before the verifier is called
+	ev.time = ev.time.Add(time.Second)
+	require.Error(t, pool.CheckEvidence(types.EvidenceList{ev}))
+	assert.Equal(t, 2, verifier.calls)
+}
+
+func TestAddCustomEvidenceIsRejected(t *testing.T) {
+	var height int64 = 1
+	pool, _ := defaultTestPool(t, height)
+	ev := customEvidence{height: height, time: defaultEvidenceTime.Add(1 * time.Minute)}
+
+	verifier := &fakeEvidenceVerifier{}
+	pool.RegisterEvidenceVerifier(ev.TypeURL(), verifier)
+
+	err := pool.AddEvidence(ev)
+	require.ErrorIs(t, err, evidence.ErrCustomEvidenceNotStorable)
+	assert.Zero(t, verifier.calls, "evidence must be rejected before it is verified")
+	assert.Zero(t, pool.Size())
+}
+
 func TestVerifyDuplicateVoteEvidence(t *testing.T) {
 	val := types.NewMockPV()
 	val2 := types.NewMockPV()
@@ -454,6 +622,45 @@ func TestVerifyDuplicateVoteEvidence(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestVerifyDuplicateVoteEvidenceKeyTypes(t *testing.T) {
+	const chainID = "mychain"
+	for _, keyType := range []string{ed25519.KeyType, secp256k1.KeyType, bls12381.KeyType} {
+		t.Run(keyType, func(t *testing.T) {
+			if keyType == bls12381.KeyType && !bls12381.Enabled {
+				t.Skip("bls12381 is disabled")
+			}
+			val, err := types.NewMockPVWithKeyType(keyType)
+			require.NoError(t, err)
+			otherVal, err := types.NewMockPVWithKeyType(keyType)
+			require.NoError(t, err)
+			valSet := types.NewValidatorSet([]*types.Validator{val.ExtractIntoValidator(10)})
+
+			newEvidence := func() *types.DuplicateVoteEvidence {
+				ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(10, defaultEvidenceTime, val, chainID)
+				require.NoError(t, err)
+				require.NoError(t, ev.ValidateBasic())
+				return ev
+			}
+
+			require.NoError(t, evidence.VerifyDuplicateVote(newEvidence(), chainID, valSet))
+
+			// signed by another key of the same type
+			ev := newEvidence()
+			vb := ev.VoteB.ToProto()
+			require.NoError(t, otherVal.SignVote(chainID, vb))
+			ev.VoteB.Signature = vb.Signature
+			require.ErrorIs(t, evidence.VerifyDuplicateVote(ev, chainID, valSet), types.ErrVoteInvalidSignature)
+
+			// signature of the wrong size for the key type
+			ev = newEvidence()
+			ev.VoteA.Signature = append(ev.VoteA.Signature, 0)
+			err = evidence.VerifyDuplicateVote(ev, chainID, valSet)
+			require.ErrorContains(t, err, fmt.Sprintf("%s signature", keyType))
+			require.NotErrorIs(t, err, types.ErrVoteInvalidSignature)
+		})
+	}
+}
+
 func makeLunaticEvidence(
 	t *testing.T,
 	height, commonHeight int64,
@@ -501,11 +708,18 @@ func makeLunaticEvidence(
 		Timestamp:           commonTime,
 	}
 
+	// the common light block is valid on its own, as it may be served by a
+	// LightBlockFetcher
+	commonHeader.ValidatorsHash = commonValSet.Hash()
+	commonBlockID := makeBlockID(commonHeader.Hash(), 1000, []byte("partshash"))
+	commonVoteSet := types.NewVoteSet(evidenceChainID, commonHeight, 1, cmtproto.SignedMsgType(2), commonValSet)
+	commonCommit, err := test.MakeCommitFromVoteSet(commonBlockID, commonVoteSet,
+		orderPrivValsByValSet(t, commonValSet, commonPrivVals), commonTime)
+	require.NoError(t, err)
 	common = &types.LightBlock{
 		SignedHeader: &types.SignedHeader{
 			Header: commonHeader,
-			// we can leave this empty because we shouldn't be checking this
-			Commit: &types.Commit{},
+			Commit: commonCommit,
 		},
 		ValidatorSet: commonValSet,
 	}
@@ -37,6 +37,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/cometbft/cometbft/libs/service"
 	cmtsync "github.com/cometbft/cometbft/libs/sync"
@@ -47,10 +48,15 @@ type operation int
 const (
 	sub operation = iota
 	pub
+	pubSync
 	unsub
 	shutdown
 )
 
+// workerQueueCapacity is the number of published messages which can be
+// waiting to be pushed by each worker (see Workers) before the server's goroutine blocks.
+const workerQueueCapacity = 100
+
 var (
 	// ErrSubscriptionNotFound is returned when a client tries to unsubscribe
 	// from not existing subscription.
@@ -80,9 +86,23 @@ type cmd struct {
 	subscription *Subscription
 	clientID     string
 
+	// subscribe to several queries
+	queries []Query
+
 	// publish
 	msg    any
 	events map[string][]string
+
+	// synchronous publish
+	matched chan matchResult
+}
+
+// matchResult holds the subscriptions matching a synchronously published
+// message, along with the query each of them matched.
+type matchResult struct {
+	subscriptions []*Subscription
+	queries       []string
+	err           error
 }
 
 // Server allows clients to subscribe/unsubscribe for messages, publishing
@@ -96,7 +116,11 @@ type Server struct {
 	// check if we have subscription before
 	// subscribing or unsubscribing
 	mtx           cmtsync.RWMutex
-	subscriptions map[string]map[string]struct{} // subscriber -> query (string) -> empty struct
+	subscriptions map[string]map[string]*Subscription // subscriber -> query (string) -> subscription
+
+	onOutOfCapacity func(clientID string, query string)
+
+	workers int
 }
 
 // Option sets a parameter for the server.
@@ -107,7 +131,7 @@ type Option func(*Server)
 // provided, the resulting server's queue is unbuffered.
 func NewServer(options ...Option) *Server {
 	s := &Server{
-		subscriptions: make(map[string]map[string]struct{}),
+		subscriptions: make(map[string]map[string]*Subscription),
 	}
 	s.BaseService = *service.NewBaseService(nil, "PubSub", s)
 
@@ -133,6 +157,33 @@ func BufferCapacity(cap int) Option {
 	}
 }
 
+// OutOfCapacityCallback sets a function which is called whenever a
+// subscription is canceled because its client did not consume messages fast
+// enough (see ErrOutOfCapacity). The callback is invoked from the server's
+// goroutine, or from a worker's (see Workers), and therefore must not block.
+func OutOfCapacityCallback(cb func(clientID string, query string)) Option {
+	return func(s *Server) {
+		s.onOutOfCapacity = cb
+	}
+}
+
+// Workers sets the number of goroutines pushing the published messages to
+// the subscriptions. By default, or if n is not positive, the server's own
+// goroutine pushes them, which keeps it busy while there are many matching
+// subscriptions. With workers, the server's goroutine only matches the
+// messages against the queries and hands them over to the worker of each
+// subscription, blocking only once that worker has workerQueueCapacity
+// messages waiting. Each subscription is assigned a single worker, so the
+// messages of a subscription are still delivered in the order they were
+// published.
+func Workers(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.workers = n
+		}
+	}
+}
+
 // BufferCapacity returns capacity of the internal server's queue.
 func (s *Server) BufferCapacity() int {
 	return s.cmdsCap
@@ -160,21 +211,86 @@ func (s *Server) Subscribe(
 		outCap = outCapacity[0]
 	}
 
-	return s.subscribe(ctx, clientID, query, outCap)
+	return s.subscribe(ctx, clientID, []Query{query}, outCap, Cancel)
+}
+
+// SubscribeWithPolicy is like Subscribe, but sets what happens when the client
+// is not pulling messages fast enough, rather than canceling the subscription
+// with ErrOutOfCapacity: see OverflowPolicy. Panics if outCapacity is less
+// than or equal to zero.
+func (s *Server) SubscribeWithPolicy(
+	ctx context.Context,
+	clientID string,
+	query Query,
+	outCapacity int,
+	policy OverflowPolicy,
+) (*Subscription, error) {
+	if outCapacity <= 0 {
+		panic("Negative or zero capacity. Use SubscribeUnbuffered if you want an unbuffered channel")
+	}
+	return s.subscribe(ctx, clientID, []Query{query}, outCapacity, policy)
 }
 
 // SubscribeUnbuffered does the same as Subscribe, except it returns a
 // subscription with unbuffered channel. Use with caution as it can freeze the
 // server.
 func (s *Server) SubscribeUnbuffered(ctx context.Context, clientID string, query Query) (*Subscription, error) {
-	return s.subscribe(ctx, clientID, query, 0)
+	return s.subscribe(ctx, clientID, []Query{query}, 0, Block)
+}
+
+// SubscribeMulti creates a single subscription for the given client,
+// receiving the messages which match any of the queries. Each message is
+// delivered once, even if it matches several queries, and Message.Query
+// returns the first of the queries, in the given order, it matched.
+//
+// The subscription counts as one subscription per query: unsubscribing from
+// one of the queries stops the messages matching only that query, and the
+// subscription is canceled once it was unsubscribed from all of them. If the
+// client is not pulling messages fast enough, the whole subscription is
+// canceled with ErrOutOfCapacity.
+//
+// An error will be returned to the caller if the context is canceled, if no
+// query or the same query twice is given, or if a subscription already exists
+// for the client and any of the queries. Panics if outCapacity is less than
+// or equal to zero.
+func (s *Server) SubscribeMulti(
+	ctx context.Context,
+	clientID string,
+	queries []Query,
+	outCapacity int,
+) (*Subscription, error) {
+	if outCapacity <= 0 {
+		panic("Negative or zero capacity")
+	}
+	if len(queries) == 0 {
+		return nil, errors.New("no query to subscribe to")
+	}
+	seen := make(map[string]struct{}, len(queries))
+	for _, q := range queries {
+		if _, ok := seen[q.String()]; ok {
+			return nil, fmt.Errorf("duplicate query %s", q.String())
+		}
+		seen[q.String()] = struct{}{}
+	}
+	return s.subscribe(ctx, clientID, queries, outCapacity, Cancel)
 }
 
-func (s *Server) subscribe(ctx context.Context, clientID string, query Query, outCapacity int) (*Subscription, error) {
+func (s *Server) subscribe(
+	ctx context.Context,
+	clientID string,
+	queries []Query,
+	outCapacity int,
+	policy OverflowPolicy,
+) (*Subscription, error) {
 	s.mtx.RLock()
 	clientSubscriptions, ok := s.subscriptions[clientID]
 	if ok {
-		_, ok = clientSubscriptions[query.String()]
+		ok = false
+		for _, q := range queries {
+			if _, ok = clientSubscriptions[q.String()]; ok {
+				break
+			}
+		}
 	}
 	s.mtx.RUnlock()
 	if ok {
@@ -182,13 +298,19 @@ func (s *Server) subscribe(ctx context.Context, clientID string, query Query, ou
 	}
 
 	subscription := NewSubscription(outCapacity)
+	subscription.policy = policy
+	for _, q := range queries {
+		subscription.queries = append(subscription.queries, q.String())
+	}
 	select {
-	case s.cmds <- cmd{op: sub, clientID: clientID, query: query, subscription: subscription}:
+	case s.cmds <- cmd{op: sub, clientID: clientID, queries: queries, subscription: subscription}:
 		s.mtx.Lock()
 		if _, ok = s.subscriptions[clientID]; !ok {
-			s.subscriptions[clientID] = make(map[string]struct{})
+			s.subscriptions[clientID] = make(map[string]*Subscription)
+		}
+		for _, q := range queries {
+			s.subscriptions[clientID][q.String()] = subscription
 		}
-		s.subscriptions[clientID][query.String()] = struct{}{}
 		s.mtx.Unlock()
 		return subscription, nil
 	case <-ctx.Done():
@@ -265,6 +387,47 @@ func (s *Server) NumClientSubscriptions(clientID string) int {
 	return len(s.subscriptions[clientID])
 }
 
+// SubscriptionInfo describes an active subscription.
+type SubscriptionInfo struct {
+	ClientID string
+	Query    string
+	// BufferLen is the number of messages waiting to be consumed by the client.
+	BufferLen int
+	// BufferCap is the capacity of the subscription's Out channel, zero for
+	// an unbuffered subscription.
+	BufferCap int
+}
+
+// Subscriptions returns a snapshot of all active subscriptions, ordered by
+// client ID and query. Subscriptions which have been canceled (e.g. because
+// the client ran out of capacity) are not included.
+func (s *Server) Subscriptions() []SubscriptionInfo {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+
+	infos := make([]SubscriptionInfo, 0, len(s.subscriptions))
+	for clientID, clientSubscriptions := range s.subscriptions {
+		for qStr, subscription := range clientSubscriptions {
+			if subscription.Err() != nil {
+				continue
+			}
+			infos = append(infos, SubscriptionInfo{
+				ClientID:  clientID,
+				Query:     qStr,
+				BufferLen: subscription.Len(),
+				BufferCap: subscription.Cap(),
+			})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].ClientID != infos[j].ClientID {
+			return infos[i].ClientID < infos[j].ClientID
+		}
+		return infos[i].Query < infos[j].Query
+	})
+	return infos
+}
+
 // Publish publishes the given message. An error will be returned to the caller
 // if the context is canceled.
 func (s *Server) Publish(ctx context.Context, msg any) error {
@@ -285,6 +448,54 @@ func (s *Server) PublishWithEvents(ctx context.Context, msg any, events map[stri
 	}
 }
 
+// PublishWithEventsSync is like PublishWithEvents, but waits until the message
+// has been pushed to all matching clients. Unlike PublishWithEvents, clients
+// which are not pulling messages fast enough are not unsubscribed: instead,
+// ctx.Err() is returned if any of them could not accept the message before the
+// context was done. The matching subscriptions are looked up by the server,
+// but the message is pushed to them from the calling goroutine, so other
+// commands are not held up while waiting; as a consequence, messages
+// published concurrently may be delivered in a different order.
+func (s *Server) PublishWithEventsSync(ctx context.Context, msg any, events map[string][]string) error {
+	matched := make(chan matchResult, 1)
+	select {
+	case s.cmds <- cmd{op: pubSync, events: events, matched: matched}:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.Quit():
+		return nil
+	}
+
+	var res matchResult
+	select {
+	case res = <-matched:
+	case <-s.Quit():
+		return nil
+	}
+	if res.err != nil {
+		return res.err
+	}
+
+	var ctxErr error
+	for i, subscription := range res.subscriptions {
+		message := Message{data: msg, events: events, query: res.queries[i]}
+		select {
+		case subscription.out <- message:
+			continue
+		default:
+		}
+		select {
+		case subscription.out <- message:
+		case <-subscription.canceled:
+		case <-ctx.Done():
+			ctxErr = ctx.Err()
+		case <-s.Quit():
+			return nil
+		}
+	}
+	return ctxErr
+}
+
 // OnStop implements Service.OnStop by shutting down the server.
 func (s *Server) OnStop() {
 	s.cmds <- cmd{op: shutdown}
@@ -296,6 +507,27 @@ type state struct {
 	subscriptions map[string]map[string]*Subscription
 	// query string -> queryPlusRefCount
 	queries map[string]*queryPlusRefCount
+	// called when a subscription is canceled with ErrOutOfCapacity
+	onOutOfCapacity func(clientID string, query string)
+	// the queues of the workers pushing the messages, if any, and the worker
+	// the next subscription is assigned to
+	workers    []chan delivery
+	nextWorker int
+}
+
+// delivery is a published message for a worker to push to the matching
+// subscriptions it was assigned.
+type delivery struct {
+	msg     any
+	events  map[string][]string
+	targets []target
+}
+
+// target is a subscription a message is pushed to, with the query it matched.
+type target struct {
+	clientID     string
+	query        string
+	subscription *Subscription
 }
 
 // queryPlusRefCount holds a pointer to a query and reference counter. When
@@ -307,9 +539,16 @@ type queryPlusRefCount struct {
 
 // OnStart implements Service.OnStart by starting the server.
 func (s *Server) OnStart() error {
+	workers := make([]chan delivery, s.workers)
+	for i := range workers {
+		workers[i] = make(chan delivery, workerQueueCapacity)
+		go s.work(workers[i])
+	}
 	go s.loop(state{
-		subscriptions: make(map[string]map[string]*Subscription),
-		queries:       make(map[string]*queryPlusRefCount),
+		subscriptions:   make(map[string]map[string]*Subscription),
+		queries:         make(map[string]*queryPlusRefCount),
+		onOutOfCapacity: s.onOutOfCapacity,
+		workers:         workers,
 	})
 	return nil
 }
@@ -333,11 +572,40 @@ loop:
 			state.removeAll(nil)
 			break loop
 		case sub:
-			state.add(cmd.clientID, cmd.query, cmd.subscription)
+			if len(state.workers) > 0 {
+				cmd.subscription.worker = state.nextWorker
+				state.nextWorker = (state.nextWorker + 1) % len(state.workers)
+			}
+			for _, q := range cmd.queries {
+				state.add(cmd.clientID, q, cmd.subscription)
+			}
 		case pub:
 			if err := state.send(cmd.msg, cmd.events); err != nil {
 				s.Logger.Error("Error querying for events", "err", err)
 			}
+		case pubSync:
+			subscriptions, queries, err := state.match(cmd.events)
+			cmd.matched <- matchResult{subscriptions: subscriptions, queries: queries, err: err}
+		}
+	}
+	for _, deliveries := range state.workers {
+		close(deliveries)
+	}
+}
+
+// work pushes the messages from deliveries to their subscription, canceling
+// the subscriptions which are out of capacity. The server's goroutine removes
+// the canceled subscriptions the next time a message matches them.
+func (s *Server) work(deliveries <-chan delivery) {
+	for d := range deliveries {
+		for _, t := range d.targets {
+			subscription := t.subscription
+			message := Message{data: d.msg, events: d.events, query: t.query}
+			if !subscription.deliver(message) {
+				if subscription.cancel(ErrOutOfCapacity) && s.onOutOfCapacity != nil {
+					s.onOutOfCapacity(t.clientID, t.query)
+				}
+			}
 		}
 	}
 }
@@ -371,7 +639,11 @@ func (state *state) remove(clientID string, qStr string, reason error) {
 		return
 	}
 
-	subscription.cancel(reason)
+	// a subscription to several queries is only canceled once it is removed
+	// from all of them
+	if subscription.removeQuery(qStr) == 0 {
+		subscription.cancel(reason)
+	}
 
 	// remove client from query map.
 	// if query has no other clients subscribed, remove it.
@@ -388,6 +660,13 @@ func (state *state) remove(clientID string, qStr string, reason error) {
 	}
 }
 
+// removeSubscription removes the subscription from all its queries.
+func (state *state) removeSubscription(clientID string, subscription *Subscription, reason error) {
+	for _, qStr := range subscription.queries {
+		state.remove(clientID, qStr, reason)
+	}
+}
+
 func (state *state) removeClient(clientID string, reason error) {
 	for qStr, clientSubscriptions := range state.subscriptions {
 		if _, ok := clientSubscriptions[clientID]; ok {
@@ -405,6 +684,14 @@ func (state *state) removeAll(reason error) {
 }
 
 func (state *state) send(msg any, events map[string][]string) error {
+	// subscriptions to several queries which were already considered for
+	// this message
+	var multi map[*Subscription]struct{}
+	// the subscriptions assigned to each worker, if any, matching the message
+	var batches [][]target
+	if len(state.workers) > 0 {
+		batches = make([][]target, len(state.workers))
+	}
 	for qStr, clientSubscriptions := range state.subscriptions {
 		q := state.queries[qStr].q
 
@@ -415,20 +702,107 @@ func (state *state) send(msg any, events map[string][]string) error {
 
 		if match {
 			for clientID, subscription := range clientSubscriptions {
-				if cap(subscription.out) == 0 {
-					// block on unbuffered channel
-					subscription.out <- NewMessage(msg, events)
-				} else {
-					// don't block on buffered channels
-					select {
-					case subscription.out <- NewMessage(msg, events):
-					default:
-						state.remove(clientID, qStr, ErrOutOfCapacity)
+				if subscription.Err() != nil {
+					// canceled by a worker
+					state.removeSubscription(clientID, subscription, ErrOutOfCapacity)
+					continue
+				}
+				matchedQuery := qStr
+				if len(subscription.queries) > 1 {
+					if _, ok := multi[subscription]; ok {
+						continue
+					}
+					if multi == nil {
+						multi = make(map[*Subscription]struct{})
+					}
+					multi[subscription] = struct{}{}
+					if matchedQuery, err = state.firstMatch(subscription, events); err != nil {
+						return err
+					}
+				}
+				if batches != nil {
+					batches[subscription.worker] = append(batches[subscription.worker], target{
+						clientID:     clientID,
+						query:        matchedQuery,
+						subscription: subscription,
+					})
+					continue
+				}
+				message := Message{data: msg, events: events, query: matchedQuery}
+				if !subscription.deliver(message) {
+					state.removeSubscription(clientID, subscription, ErrOutOfCapacity)
+					if state.onOutOfCapacity != nil {
+						state.onOutOfCapacity(clientID, qStr)
 					}
 				}
 			}
 		}
 	}
 
+	for i, batch := range batches {
+		if len(batch) > 0 {
+			state.workers[i] <- delivery{msg: msg, events: events, targets: batch}
+		}
+	}
 	return nil
 }
+
+// match returns the subscriptions whose query matches the events, along with
+// the query each of them matched.
+func (state *state) match(events map[string][]string) ([]*Subscription, []string, error) {
+	var (
+		matched        []*Subscription
+		matchedQueries []string
+		multi          map[*Subscription]struct{}
+	)
+	for qStr, clientSubscriptions := range state.subscriptions {
+		q := state.queries[qStr].q
+
+		match, err := q.Matches(events)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to match against query %s: %w", q.String(), err)
+		}
+
+		if match {
+			for clientID, subscription := range clientSubscriptions {
+				if subscription.Err() != nil {
+					// canceled by a worker
+					state.removeSubscription(clientID, subscription, ErrOutOfCapacity)
+					continue
+				}
+				matchedQuery := qStr
+				if len(subscription.queries) > 1 {
+					if _, ok := multi[subscription]; ok {
+						continue
+					}
+					if multi == nil {
+						multi = make(map[*Subscription]struct{})
+					}
+					multi[subscription] = struct{}{}
+					if matchedQuery, err = state.firstMatch(subscription, events); err != nil {
+						return nil, nil, err
+					}
+				}
+				matched = append(matched, subscription)
+				matchedQueries = append(matchedQueries, matchedQuery)
+			}
+		}
+	}
+	return matched, matchedQueries, nil
+}
+
+// firstMatch returns the first of the queries of the subscription which
+// matches the events.
+func (state *state) firstMatch(subscription *Subscription, events map[string][]string) (string, error) {
+	for _, qStr := range subscription.queries {
+		q := state.queries[qStr].q
+		match, err := q.Matches(events)
+		if err != nil {
+			return "", fmt.Errorf("failed to match against query %s: %w", q.String(), err)
+		}
+		if match {
+			return qStr, nil
+		}
+	}
+	return "", nil
+}
@@ -149,6 +149,146 @@ func TestSlowClientIsRemovedWithErrOutOfCapacity(t *testing.T) {
 	assertCancelled(t, subscription, pubsub.ErrOutOfCapacity)
 }
 
+func TestSubscribeWithPolicy(t *testing.T) {
+	q := query.MustCompile("tm.events.type='NewBlock'")
+	matching := map[string][]string{"tm.events.type": {"NewBlock"}}
+	other := map[string][]string{"tm.events.type": {"Tx"}}
+
+	// publish publishes the messages to the subscription, which never pulls
+	// them. Publishing a message matching no subscription afterwards
+	// returns once the server is done with the previous ones.
+	publish := func(t *testing.T, s *pubsub.Server, msgs ...string) {
+		t.Helper()
+		ctx := context.Background()
+		for _, msg := range msgs {
+			require.NoError(t, s.PublishWithEvents(ctx, msg, matching))
+		}
+		require.NoError(t, s.PublishWithEvents(ctx, "ignored", other))
+	}
+	received := func(subscription *pubsub.Subscription) []any {
+		var msgs []any
+		for len(subscription.Out()) > 0 {
+			msgs = append(msgs, (<-subscription.Out()).Data())
+		}
+		return msgs
+	}
+	newServer := func(t *testing.T) *pubsub.Server {
+		t.Helper()
+		s := pubsub.NewServer()
+		s.SetLogger(log.TestingLogger())
+		require.NoError(t, s.Start())
+		t.Cleanup(func() {
+			if err := s.Stop(); err != nil {
+				t.Error(err)
+			}
+		})
+		return s
+	}
+
+	t.Run("Cancel", func(t *testing.T) {
+		s := newServer(t)
+		subscription, err := s.SubscribeWithPolicy(context.Background(), clientID, q, 2, pubsub.Cancel)
+		require.NoError(t, err)
+		publish(t, s, "a", "b", "c")
+		assertCancelled(t, subscription, pubsub.ErrOutOfCapacity)
+	})
+
+	t.Run("DropNewest", func(t *testing.T) {
+		s := newServer(t)
+		subscription, err := s.SubscribeWithPolicy(context.Background(), clientID, q, 2, pubsub.DropNewest)
+		require.NoError(t, err)
+		publish(t, s, "a", "b", "c", "d")
+		require.NoError(t, subscription.Err())
+		assert.Equal(t, []any{"a", "b"}, received(subscription))
+	})
+
+	t.Run("DropOldest", func(t *testing.T) {
+		s := newServer(t)
+		subscription, err := s.SubscribeWithPolicy(context.Background(), clientID, q, 2, pubsub.DropOldest)
+		require.NoError(t, err)
+		publish(t, s, "a", "b", "c", "d")
+		require.NoError(t, subscription.Err())
+		assert.Equal(t, []any{"c", "d"}, received(subscription))
+	})
+
+	t.Run("Block", func(t *testing.T) {
+		s := newServer(t)
+		subscription, err := s.SubscribeWithPolicy(context.Background(), clientID, q, 1, pubsub.Block)
+		require.NoError(t, err)
+		ctx := context.Background()
+		require.NoError(t, s.PublishWithEvents(ctx, "a", matching))
+		// the server accepts b, then waits for room in the buffer
+		require.NoError(t, s.PublishWithEvents(ctx, "b", matching))
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			publish(t, s, "c")
+		}()
+		select {
+		case <-done:
+			t.Fatal("expected publishing to block until the client pulls a message")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		var msgs []any
+		for len(msgs) < 3 {
+			select {
+			case msg := <-subscription.Out():
+				msgs = append(msgs, msg.Data())
+			case <-time.After(time.Second):
+				t.Fatal("expected the blocked messages to be delivered")
+			}
+		}
+		<-done
+		require.NoError(t, subscription.Err())
+		assert.Equal(t, []any{"a", "b", "c"}, msgs)
+	})
+}
+
+func TestPublishWithEventsSyncDoesNotBlockServer(t *testing.T) {
+	s := pubsub.NewServer()
+	s.SetLogger(log.TestingLogger())
+	err := s.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	stuck, err := s.SubscribeUnbuffered(ctx, "stuck", query.MustCompile("hero.name='Cable'"))
+	require.NoError(t, err)
+
+	published := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		published <- s.PublishWithEventsSync(ctx, "Cable", map[string][]string{"hero.name": {"Cable"}})
+	}()
+
+	// the server keeps processing commands while the publish is waiting
+	start := time.Now()
+	reader, err := s.Subscribe(ctx, "reader", query.MustCompile("hero.name='Domino'"))
+	require.NoError(t, err)
+	err = s.PublishWithEvents(ctx, "Domino", map[string][]string{"hero.name": {"Domino"}})
+	require.NoError(t, err)
+	assertReceive(t, "Domino", reader.Out())
+	assert.Less(t, time.Since(start), time.Second)
+
+	// unsubscribing the stuck client releases the publish
+	err = s.UnsubscribeAll(ctx, "stuck")
+	require.NoError(t, err)
+	assertCancelled(t, stuck, pubsub.ErrUnsubscribed)
+	select {
+	case err := <-published:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("expected PublishWithEventsSync to return once the subscriber is gone")
+	}
+}
+
 func TestDifferentClients(t *testing.T) {
 	s := pubsub.NewServer()
 	s.SetLogger(log.TestingLogger())
@@ -377,6 +517,134 @@ func TestUnsubscribeAll(t *testing.T) {
 	assertCancelled(t, subscription2, pubsub.ErrUnsubscribed)
 }
 
+func TestSubscriptions(t *testing.T) {
+	s := pubsub.NewServer()
+	s.SetLogger(log.TestingLogger())
+	err := s.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	assert.Empty(t, s.Subscriptions())
+
+	ctx := context.Background()
+	q1 := query.MustCompile("tm.events.type='NewBlock'")
+	q2 := query.MustCompile("tm.events.type='Tx'")
+	_, err = s.Subscribe(ctx, "client-b", q1, 10)
+	require.NoError(t, err)
+	_, err = s.Subscribe(ctx, "client-a", q2, 10)
+	require.NoError(t, err)
+	_, err = s.Subscribe(ctx, "client-a", q1, 10)
+	require.NoError(t, err)
+
+	err = s.PublishWithEvents(ctx, "Nova", map[string][]string{"tm.events.type": {"NewBlock"}})
+	require.NoError(t, err)
+
+	expected := []pubsub.SubscriptionInfo{
+		{ClientID: "client-a", Query: q1.String(), BufferLen: 1, BufferCap: 10},
+		{ClientID: "client-a", Query: q2.String(), BufferLen: 0, BufferCap: 10},
+		{ClientID: "client-b", Query: q1.String(), BufferLen: 1, BufferCap: 10},
+	}
+	require.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expected, s.Subscriptions())
+	}, time.Second, 10*time.Millisecond)
+
+	err = s.UnsubscribeAll(ctx, "client-a")
+	require.NoError(t, err)
+	assert.Equal(t, []pubsub.SubscriptionInfo{
+		{ClientID: "client-b", Query: q1.String(), BufferLen: 1, BufferCap: 10},
+	}, s.Subscriptions())
+}
+
+func TestSubscribeMulti(t *testing.T) {
+	s := pubsub.NewServer()
+	s.SetLogger(log.TestingLogger())
+	err := s.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	q1 := query.MustCompile("hero.name='Thor'")
+	q2 := query.MustCompile("hero.team='Avengers'")
+	subscription, err := s.SubscribeMulti(ctx, clientID, []pubsub.Query{q1, q2}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, s.NumClientSubscriptions(clientID))
+
+	_, err = s.Subscribe(ctx, clientID, q2)
+	require.ErrorIs(t, err, pubsub.ErrAlreadySubscribed)
+	_, err = s.SubscribeMulti(ctx, "other", []pubsub.Query{q1, q1}, 10)
+	require.Error(t, err)
+	_, err = s.SubscribeMulti(ctx, "other", nil, 10)
+	require.Error(t, err)
+
+	err = s.PublishWithEvents(ctx, "Thor", map[string][]string{"hero.name": {"Thor"}})
+	require.NoError(t, err)
+	err = s.PublishWithEvents(ctx, "Hulk", map[string][]string{"hero.team": {"Avengers"}})
+	require.NoError(t, err)
+	// matching both queries, delivered once and tagged with the first one
+	err = s.PublishWithEventsSync(ctx, "Thor", map[string][]string{"hero.name": {"Thor"}, "hero.team": {"Avengers"}})
+	require.NoError(t, err)
+	err = s.PublishWithEvents(ctx, "Thanos", map[string][]string{"hero.name": {"Thanos"}})
+	require.NoError(t, err)
+
+	assertReceiveQuery(t, "Thor", q1, subscription.Out())
+	assertReceiveQuery(t, "Hulk", q2, subscription.Out())
+	assertReceiveQuery(t, "Thor", q1, subscription.Out())
+	assert.Zero(t, len(subscription.Out()))
+
+	// unsubscribing from one of the queries keeps the other
+	err = s.Unsubscribe(ctx, clientID, q1)
+	require.NoError(t, err)
+	err = s.PublishWithEvents(ctx, "Thor", map[string][]string{"hero.name": {"Thor"}, "hero.team": {"Avengers"}})
+	require.NoError(t, err)
+	assertReceiveQuery(t, "Thor", q2, subscription.Out())
+	require.NoError(t, subscription.Err())
+
+	err = s.Unsubscribe(ctx, clientID, q2)
+	require.NoError(t, err)
+	assertCancelled(t, subscription, pubsub.ErrUnsubscribed)
+}
+
+func TestSubscribeMultiOutOfCapacity(t *testing.T) {
+	slow := make(chan string, 2)
+	s := pubsub.NewServer(pubsub.OutOfCapacityCallback(func(_, query string) {
+		slow <- query
+	}))
+	s.SetLogger(log.TestingLogger())
+	err := s.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := s.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	q1 := query.MustCompile("hero.name='Thor'")
+	q2 := query.MustCompile("hero.team='Avengers'")
+	subscription, err := s.SubscribeMulti(ctx, clientID, []pubsub.Query{q1, q2}, 1)
+	require.NoError(t, err)
+
+	err = s.PublishWithEvents(ctx, "Thor", map[string][]string{"hero.name": {"Thor"}})
+	require.NoError(t, err)
+	err = s.PublishWithEvents(ctx, "Hulk", map[string][]string{"hero.team": {"Avengers"}})
+	require.NoError(t, err)
+
+	assertCancelled(t, subscription, pubsub.ErrOutOfCapacity)
+	// the callback is called once for the whole subscription
+	assert.Equal(t, q2.String(), <-slow)
+	err = s.PublishWithEventsSync(ctx, "Thor", map[string][]string{"hero.name": {"Thor"}})
+	require.NoError(t, err)
+	assert.Empty(t, slow)
+}
+
 func TestBufferCapacity(t *testing.T) {
 	s := pubsub.NewServer(pubsub.BufferCapacity(2))
 	s.SetLogger(log.TestingLogger())
@@ -502,6 +770,17 @@ func assertReceive(t *testing.T, expected any, ch <-chan pubsub.Message, msgAndA
 	}
 }
 
+func assertReceiveQuery(t *testing.T, expected any, q pubsub.Query, ch <-chan pubsub.Message) {
+	select {
+	case actual := <-ch:
+		assert.Equal(t, expected, actual.Data())
+		assert.Equal(t, q.String(), actual.Query())
+	case <-time.After(1 * time.Second):
+		t.Errorf("expected to receive %v from the channel, got nothing after 1s", expected)
+		debug.PrintStack()
+	}
+}
+
 func assertCancelled(t *testing.T, subscription *pubsub.Subscription, err error) {
 	_, ok := <-subscription.Canceled()
 	assert.False(t, ok)
@@ -15,6 +15,25 @@ var (
 	ErrOutOfCapacity = errors.New("internal subscription event buffer is out of capacity")
 )
 
+// OverflowPolicy sets what happens to a message published to a subscription
+// whose buffer is full, i.e. whose client is not pulling messages fast enough.
+type OverflowPolicy int
+
+const (
+	// Cancel cancels the subscription with ErrOutOfCapacity. This is the
+	// policy of the subscriptions created by Subscribe and SubscribeMulti.
+	Cancel OverflowPolicy = iota
+	// Block waits until the client pulls a message, or the subscription is
+	// canceled, holding up the delivery of the following messages to every
+	// client, like for the subscriptions created by SubscribeUnbuffered.
+	Block
+	// DropNewest drops the message, keeping the messages already buffered.
+	DropNewest
+	// DropOldest drops the oldest buffered message to make room for the
+	// message, so that the client always gets the latest messages.
+	DropOldest
+)
+
 // A Subscription represents a client subscription for a particular query and
 // consists of three things:
 // 1) channel onto which messages and events are published
@@ -26,6 +45,15 @@ type Subscription struct {
 	canceled chan struct{}
 	mtx      cmtsync.RWMutex
 	err      error
+
+	// queries the subscription is for, in the order given by the client. Only
+	// modified by the server's goroutine once the subscription is added.
+	queries []string
+	// worker pushing the messages to the subscription, if the server has
+	// workers. Only used by the server's goroutine.
+	worker int
+	// what to do when the buffer of out is full
+	policy OverflowPolicy
 }
 
 // NewSubscription returns a new subscription with the given outCapacity.
@@ -43,6 +71,17 @@ func (s *Subscription) Out() <-chan Message {
 	return s.out
 }
 
+// Len returns the number of messages currently buffered in the Out channel,
+// i.e. published but not yet consumed by the client.
+func (s *Subscription) Len() int {
+	return len(s.out)
+}
+
+// Cap returns the capacity of the Out channel.
+func (s *Subscription) Cap() int {
+	return cap(s.out)
+}
+
 // Canceled returns a channel that's closed when the subscription is
 // terminated and supposed to be used in a select statement.
 func (s *Subscription) Canceled() <-chan struct{} {
@@ -63,21 +102,83 @@ func (s *Subscription) Err() error {
 	return s.err
 }
 
-func (s *Subscription) cancel(err error) {
+// deliver pushes the message to the subscription, following its overflow
+// policy if the buffer is full. It returns false if the subscription is out of
+// capacity and must be canceled with ErrOutOfCapacity.
+func (s *Subscription) deliver(msg Message) bool {
+	if cap(s.out) == 0 || s.policy == Block {
+		// block until the client pulls a message or the subscription is
+		// canceled
+		select {
+		case s.out <- msg:
+		case <-s.canceled:
+		}
+		return true
+	}
+	select {
+	case s.out <- msg:
+		return true
+	default:
+	}
+	switch s.policy {
+	case DropNewest:
+		return true
+	case DropOldest:
+		for {
+			// the client may pull the oldest message first, in which case
+			// there is room already
+			select {
+			case <-s.out:
+			default:
+			}
+			select {
+			case s.out <- msg:
+				return true
+			default:
+			}
+		}
+	default:
+		return false
+	}
+}
+
+// removeQuery removes the query from the queries of the subscription and
+// returns the number of queries left. The queries are copied rather than
+// modified in place, so that callers can range over them while removing them.
+func (s *Subscription) removeQuery(qStr string) int {
+	for i, q := range s.queries {
+		if q == qStr {
+			s.queries = append(s.queries[:i:i], s.queries[i+1:]...)
+			break
+		}
+	}
+	return len(s.queries)
+}
+
+// cancel cancels the subscription with the given error, unless it was
+// already canceled, and reports whether it did.
+func (s *Subscription) cancel(err error) bool {
 	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	select {
+	case <-s.canceled:
+		return false
+	default:
+	}
 	s.err = err
-	s.mtx.Unlock()
 	close(s.canceled)
+	return true
 }
 
 // Message glues data and events together.
 type Message struct {
 	data   any
 	events map[string][]string
+	query  string
 }
 
 func NewMessage(data any, events map[string][]string) Message {
-	return Message{data, events}
+	return Message{data: data, events: events}
 }
 
 // Data returns an original data published.
@@ -89,3 +190,10 @@ func (msg Message) Data() any {
 func (msg Message) Events() map[string][]string {
 	return msg.events
 }
+
+// Query returns the query the message matched, which tells apart the
+// messages of a subscription to several queries (see Server.SubscribeMulti).
+// It is empty for messages not delivered by a Server.
+func (msg Message) Query() string {
+	return msg.query
+}
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -96,7 +97,7 @@ func (p *Proxy) listen() (net.Listener, *http.ServeMux, error) {
 	wm := rpcserver.NewWebsocketManager(r,
 		rpcserver.OnDisconnect(func(remoteAddr string) {
 			err := p.Client.UnsubscribeAll(context.Background(), remoteAddr)
-			if err != nil && err != cmtpubsub.ErrSubscriptionNotFound {
+			if err != nil && !errors.Is(err, cmtpubsub.ErrSubscriptionNotFound) {
 				wmLogger.Error("Failed to unsubscribe addr from events", "addr", remoteAddr, "err", err)
 			}
 		}),
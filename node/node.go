@@ -3,6 +3,7 @@ package node
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -76,6 +77,7 @@ type Node struct {
 	evidencePool      *evidence.Pool          // tracking evidence
 	proxyApp          proxy.AppConns          // connection to the application
 	rpcListeners      []net.Listener          // rpc servers
+	rpcMetrics        *rpccore.Metrics
 	txIndexer         txindex.TxIndexer
 	blockIndexer      indexer.BlockIndexer
 	indexerService    *txindex.IndexerService
@@ -325,7 +327,7 @@ func NewNodeWithContext(
 		return nil, err
 	}
 
-	csMetrics, p2pMetrics, memplMetrics, smMetrics, abciMetrics, bsMetrics, ssMetrics := metricsProvider(genDoc.ChainID)
+	csMetrics, p2pMetrics, memplMetrics, smMetrics, abciMetrics, bsMetrics, ssMetrics, eventBusMetrics, rpcMetrics, evidenceMetrics := metricsProvider(genDoc.ChainID)
 
 	// Create the proxyApp and establish connections to the ABCI app (consensus, mempool, query).
 	proxyApp, err := createAndStartProxyAppConns(clientCreator, logger, abciMetrics)
@@ -337,7 +339,7 @@ func NewNodeWithContext(
 	// we might need to index the txs of the replayed block as this might not have happened
 	// when the node stopped last time (i.e. the node stopped after it saved the block
 	// but before it indexed the txs)
-	eventBus, err := createAndStartEventBus(logger)
+	eventBus, err := createAndStartEventBus(logger, eventBusMetrics)
 	if err != nil {
 		return nil, err
 	}
@@ -398,7 +400,7 @@ func NewNodeWithContext(
 	// Make MempoolReactor
 	mempool, mempoolReactor := createMempoolAndMempoolReactor(config, proxyApp, state, waitSync, memplMetrics, logger)
 
-	evidenceReactor, evidencePool, err := createEvidenceReactor(config, dbProvider, stateStore, blockStore, logger)
+	evidenceReactor, evidencePool, err := createEvidenceReactor(config, dbProvider, stateStore, blockStore, logger, evidenceMetrics)
 	if err != nil {
 		return nil, err
 	}
@@ -583,6 +585,7 @@ func NewNodeWithContext(
 		indexerService:   indexerService,
 		blockIndexer:     blockIndexer,
 		eventBus:         eventBus,
+		rpcMetrics:       rpcMetrics,
 	}
 
 	node.BaseService = *service.NewBaseService(logger, "Node", node)
@@ -718,6 +721,14 @@ func (n *Node) OnStop() {
 			n.Logger.Error("Pprof HTTP server Shutdown", "err", err)
 		}
 	}
+	// the evidence buffered from consensus is flushed using the block and
+	// state stores, so the evidence store is closed first
+	if n.evidencePool != nil {
+		n.Logger.Info("Closing evidencestore")
+		if err := n.evidencePool.CloseAndFlush(n.evidencePool.State()); err != nil {
+			n.Logger.Error("problem closing evidencestore", "err", err)
+		}
+	}
 	if n.blockStore != nil {
 		n.Logger.Info("Closing blockstore")
 		if err := n.blockStore.Close(); err != nil {
@@ -730,12 +741,6 @@ func (n *Node) OnStop() {
 			n.Logger.Error("problem closing statestore", "err", err)
 		}
 	}
-	if n.evidencePool != nil {
-		n.Logger.Info("Closing evidencestore")
-		if err := n.EvidencePool().Close(); err != nil {
-			n.Logger.Error("problem closing evidencestore", "err", err)
-		}
-	}
 }
 
 // ConfigureRPC makes sure RPC has all the objects it needs to operate.
@@ -767,10 +772,21 @@ func (n *Node) ConfigureRPC() (*rpccore.Environment, error) {
 		Logger: n.Logger.With("module", "rpc"),
 
 		Config: *n.config.RPC,
+
+		ABCIQueryTimeout: n.config.RPC.ABCIQueryTimeout,
+		ABCIInfoCacheTTL: n.config.RPC.ABCIInfoCacheTTL,
+
+		MaxQueryResponseBytes: n.config.RPC.MaxQueryResponseBytes,
+
+		Metrics:               n.rpcMetrics,
+		ABCIQueryMetricsPaths: n.config.RPC.ABCIQueryMetricsPaths,
 	}
 	if err := rpcCoreEnv.InitGenesisChunks(); err != nil {
 		return nil, err
 	}
+	if err := rpcCoreEnv.InitABCIInfoCache(); err != nil {
+		return nil, err
+	}
 	return &rpcCoreEnv, nil
 }
 
@@ -808,7 +824,7 @@ func (n *Node) startRPC() ([]net.Listener, error) {
 		wm := rpcserver.NewWebsocketManager(routes,
 			rpcserver.OnDisconnect(func(remoteAddr string) {
 				err := n.eventBus.UnsubscribeAll(context.Background(), remoteAddr)
-				if err != nil && err != cmtpubsub.ErrSubscriptionNotFound {
+				if err != nil && !errors.Is(err, cmtpubsub.ErrSubscriptionNotFound) {
 					wmLogger.Error("Failed to unsubscribe addr from events", "addr", remoteAddr, "err", err)
 				}
 			}),
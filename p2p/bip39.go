@@ -0,0 +1,78 @@
+package p2p
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/cometbft/cometbft/crypto"
+)
+
+// bip39English is the BIP39 English word list, one word per line.
+//
+//go:embed bip39_english.txt
+var bip39English string
+
+// bip39Words holds the words of bip39English, which are sorted.
+var bip39Words = strings.Fields(bip39English)
+
+// mnemonicEntropyBytes is the entropy of the mnemonics generated by
+// GenMnemonic, which have 24 words.
+const mnemonicEntropyBytes = 32
+
+// GenMnemonic returns a new random BIP39 mnemonic of 24 words, which can be
+// given to NodeKeyFromMnemonic.
+func GenMnemonic() string {
+	return entropyToMnemonic(crypto.CRandBytes(mnemonicEntropyBytes))
+}
+
+// entropyToMnemonic returns the BIP39 mnemonic encoding entropy: the entropy
+// followed by the first bits of its SHA-256 checksum (one bit per 32 bits of
+// entropy), split into 11-bit indices in the word list.
+func entropyToMnemonic(entropy []byte) string {
+	checksumBits := len(entropy) / 4
+	bits := new(big.Int).SetBytes(entropy)
+	bits.Lsh(bits, uint(checksumBits))
+	checksum := sha256.Sum256(entropy)
+	bits.Or(bits, big.NewInt(int64(checksum[0]>>(8-checksumBits))))
+
+	words := make([]string, (len(entropy)*8+checksumBits)/11)
+	index := new(big.Int)
+	mask := big.NewInt(1<<11 - 1)
+	for i := len(words) - 1; i >= 0; i-- {
+		words[i] = bip39Words[index.And(bits, mask).Int64()]
+		bits.Rsh(bits, 11)
+	}
+	return strings.Join(words, " ")
+}
+
+// validateMnemonic checks that words is a BIP39 mnemonic: every word is in the
+// word list and the checksum matches the entropy.
+func validateMnemonic(words []string) error {
+	switch len(words) {
+	case 12, 15, 18, 21, 24:
+	default:
+		return fmt.Errorf("mnemonic must have 12, 15, 18, 21 or 24 words, got %d", len(words))
+	}
+	bits := new(big.Int)
+	for i, word := range words {
+		index := sort.SearchStrings(bip39Words, word)
+		if index == len(bip39Words) || bip39Words[index] != word {
+			return fmt.Errorf("word #%d %q is not in the BIP39 English word list", i+1, word)
+		}
+		bits.Lsh(bits, 11)
+		bits.Or(bits, big.NewInt(int64(index)))
+	}
+
+	checksumBits := len(words) * 11 / 33
+	checksum := new(big.Int).And(bits, big.NewInt(1<<checksumBits-1)).Int64()
+	entropy := bits.Rsh(bits, uint(checksumBits)).FillBytes(make([]byte, checksumBits*4))
+	if sum := sha256.Sum256(entropy); int64(sum[0]>>(8-checksumBits)) != checksum {
+		return errors.New("invalid mnemonic checksum, check the words and their order")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package p2p
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBIP39WordList(t *testing.T) {
+	require.Len(t, bip39Words, 2048)
+	assert.True(t, sort.StringsAreSorted(bip39Words))
+	// the hash of english.txt in the BIP39 repository
+	sum := sha256.Sum256([]byte(bip39English))
+	assert.Equal(t, "2f5eed53a4727b4bf8880d8f3f199efc90e58503646d9ff8eff3a2ed3b24dbda", hex.EncodeToString(sum[:]))
+}
+
+func TestEntropyToMnemonic(t *testing.T) {
+	// test vectors from BIP39
+	for _, tc := range []struct {
+		entropy  string
+		mnemonic string
+	}{
+		{"00000000000000000000000000000000", "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"},
+		{"7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f7f", "legal winner thank year wave sausage worth useful legal winner thank yellow"},
+		{"ffffffffffffffffffffffffffffffff", "zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo zoo wrong"},
+		{"6610b25967cdcca9d59875f5cb50b0ea75433311869e930b", "gravity machine north sort system female filter attitude volume fold club stay feature office ecology stable narrow fog"},
+		{
+			"68a79eaca2324873eacc50cb9c6eca8cc68ea5d936f98787c60c7ebc74e6ce7c",
+			"hamster diagram private dutch cause delay private meat slide toddler razor book happy fancy gospel tennis maple dilemma loan word shrug inflict delay length",
+		},
+	} {
+		entropy, err := hex.DecodeString(tc.entropy)
+		require.NoError(t, err)
+		assert.Equal(t, tc.mnemonic, entropyToMnemonic(entropy))
+		assert.NoError(t, validateMnemonic(strings.Fields(tc.mnemonic)))
+	}
+}
+
+func TestValidateMnemonic(t *testing.T) {
+	const mnemonic = "legal winner thank year wave sausage worth useful legal winner thank yellow"
+	require.NoError(t, validateMnemonic(strings.Fields(mnemonic)))
+
+	// a mistyped word
+	err := validateMnemonic(strings.Fields(strings.Replace(mnemonic, "sausage", "sausages", 1)))
+	assert.ErrorContains(t, err, `word #6 "sausages"`)
+	// a valid word in the wrong place
+	err = validateMnemonic(strings.Fields(strings.Replace(mnemonic, "yellow", "year", 1)))
+	assert.ErrorContains(t, err, "checksum")
+	// a missing word
+	err = validateMnemonic(strings.Fields(mnemonic)[1:])
+	assert.ErrorContains(t, err, "got 11")
+}
+
+func TestGenMnemonic(t *testing.T) {
+	mnemonic := GenMnemonic()
+	words := strings.Fields(mnemonic)
+	assert.Len(t, words, 24)
+	require.NoError(t, validateMnemonic(words))
+	assert.NotEqual(t, mnemonic, GenMnemonic())
+}
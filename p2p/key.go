@@ -2,9 +2,16 @@ package p2p
 
 import (
 	"bytes"
+	ed25519lib "crypto/ed25519"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha512"
 	"encoding/hex"
 	"fmt"
 	"os"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/cometbft/cometbft/crypto"
 	"github.com/cometbft/cometbft/crypto/ed25519"
@@ -56,9 +63,9 @@ func LoadOrGenNodeKey(filePath string) (*NodeKey, error) {
 		return nodeKey, nil
 	}
 
-	privKey := ed25519.GenPrivKey()
-	nodeKey := &NodeKey{
-		PrivKey: privKey,
+	nodeKey, err := GenNodeKey(ed25519.KeyType)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := nodeKey.SaveAs(filePath); err != nil {
@@ -68,6 +75,66 @@ func LoadOrGenNodeKey(filePath string) (*NodeKey, error) {
 	return nodeKey, nil
 }
 
+// GenNodeKey generates a new NodeKey of the given key type. It returns an
+// error if the key type is not one of SupportedNodeKeyTypes.
+func GenNodeKey(keyType string) (*NodeKey, error) {
+	var privKey crypto.PrivKey
+	switch keyType {
+	case ed25519.KeyType:
+		privKey = ed25519.GenPrivKey()
+	default:
+		return nil, fmt.Errorf("unsupported node key type %q (supported: %s)",
+			keyType, strings.Join(SupportedNodeKeyTypes(), ", "))
+	}
+	return &NodeKey{PrivKey: privKey}, nil
+}
+
+// NodeKeyFromMnemonic derives an ed25519 NodeKey from a BIP39 mnemonic and
+// an optional passphrase, so that a node's identity can be recreated from a
+// backed-up phrase. The derivation is stable:
+//
+//  1. the words of the mnemonic are joined with single spaces and, like the
+//     passphrase, normalized to NFKD;
+//  2. the BIP39 seed is PBKDF2-HMAC-SHA512 of the mnemonic, salted with
+//     "mnemonic" followed by the passphrase, with 2048 iterations (64 bytes);
+//  3. the key is the SLIP-0010 ed25519 master key of the seed: the first 32
+//     bytes of HMAC-SHA512 of the seed, keyed with "ed25519 seed".
+//
+// The mnemonic must have 12, 15, 18, 21 or 24 words from the BIP39 English
+// word list, with a valid checksum, so that a mistyped word is reported
+// rather than yielding another key.
+func NodeKeyFromMnemonic(mnemonic, passphrase string) (*NodeKey, error) {
+	words := strings.Fields(mnemonic)
+	if err := validateMnemonic(words); err != nil {
+		return nil, err
+	}
+	seed, err := bip39Seed(strings.Join(words, " "), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return &NodeKey{PrivKey: ed25519.PrivKey(ed25519lib.NewKeyFromSeed(slip10MasterKey(seed)))}, nil
+}
+
+// bip39Seed returns the BIP39 seed of a mnemonic and passphrase.
+func bip39Seed(mnemonic, passphrase string) ([]byte, error) {
+	return pbkdf2.Key(sha512.New, norm.NFKD.String(mnemonic),
+		[]byte("mnemonic"+norm.NFKD.String(passphrase)), 2048, 64)
+}
+
+// slip10MasterKey returns the private key of the SLIP-0010 ed25519 master key
+// of seed.
+func slip10MasterKey(seed []byte) []byte {
+	mac := hmac.New(sha512.New, []byte("ed25519 seed"))
+	mac.Write(seed)
+	return mac.Sum(nil)[:32]
+}
+
+// SupportedNodeKeyTypes returns the key types that can be used for a NodeKey.
+// It is limited to the key types the secret connection accepts from peers.
+func SupportedNodeKeyTypes() []string {
+	return []string{ed25519.KeyType}
+}
+
 // LoadNodeKey loads NodeKey located in filePath.
 func LoadNodeKey(filePath string) (*NodeKey, error) {
 	jsonBytes, err := os.ReadFile(filePath)
@@ -2,15 +2,22 @@ package p2p
 
 import (
 	"bytes"
+	"encoding/hex"
+	"errors"
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/cometbft/cometbft/crypto/bls12381"
 	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/secp256k1"
 	cmtrand "github.com/cometbft/cometbft/libs/rand"
+	"github.com/cometbft/cometbft/p2p/conn"
 )
 
 func TestLoadOrGenNodeKey(t *testing.T) {
@@ -53,12 +60,99 @@ func TestNodeKeySaveAs(t *testing.T) {
 	assert.FileExists(t, filePath)
 }
 
+func TestGenNodeKey(t *testing.T) {
+	for _, keyType := range SupportedNodeKeyTypes() {
+		t.Run(keyType, func(t *testing.T) {
+			nodeKey, err := GenNodeKey(keyType)
+			require.NoError(t, err)
+			assert.Equal(t, keyType, nodeKey.PrivKey.Type())
+
+			filePath := filepath.Join(t.TempDir(), "node_key.json")
+			require.NoError(t, nodeKey.SaveAs(filePath))
+
+			loaded, err := LoadNodeKey(filePath)
+			require.NoError(t, err)
+			assert.Equal(t, nodeKey.ID(), loaded.ID())
+		})
+	}
+
+	_, err := GenNodeKey(bls12381.KeyType)
+	assert.Error(t, err)
+	_, err = GenNodeKey(secp256k1.KeyType)
+	assert.Error(t, err)
+}
+
+// A node key of any supported type must be usable to connect to peers.
+func TestSupportedNodeKeyTypesHandshake(t *testing.T) {
+	for _, keyType := range SupportedNodeKeyTypes() {
+		t.Run(keyType, func(t *testing.T) {
+			fooKey, err := GenNodeKey(keyType)
+			require.NoError(t, err)
+			barKey, err := GenNodeKey(keyType)
+			require.NoError(t, err)
+
+			fooConn, barConn := net.Pipe()
+			defer fooConn.Close()
+			defer barConn.Close()
+
+			errc := make(chan error, 1)
+			go func() {
+				sc, err := conn.MakeSecretConnection(barConn, barKey.PrivKey)
+				if err == nil && !sc.RemotePubKey().Equals(fooKey.PubKey()) {
+					err = errors.New("unexpected remote public key")
+				}
+				errc <- err
+			}()
+			sc, err := conn.MakeSecretConnection(fooConn, fooKey.PrivKey)
+			require.NoError(t, err)
+			assert.True(t, sc.RemotePubKey().Equals(barKey.PubKey()))
+			require.NoError(t, <-errc)
+		})
+	}
+}
+
 //----------------------------------------------------------
 
 func padBytes(bz []byte, targetBytes int) []byte {
 	return append(bz, bytes.Repeat([]byte{0xFF}, targetBytes-len(bz))...)
 }
 
+func TestNodeKeyFromMnemonic(t *testing.T) {
+	// test vectors from BIP39 and SLIP-0010
+	seed, err := bip39Seed(strings.Repeat("abandon ", 11)+"about", "TREZOR")
+	require.NoError(t, err)
+	assert.Equal(t, "c55257c360c07c72029aebc1b53c05ed0362ada38ead3e3e9efa3708e53495531f09a6987599d18264c1e1c92f2cf141630c7a3c4ab7c81b2f001698e7463b04",
+		hex.EncodeToString(seed))
+	seed, err = hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	require.NoError(t, err)
+	assert.Equal(t, "2b4be7f19ee27bbf30c667b642d5f4aa69fd169872f8fc3059c08ebae2eb19e7",
+		hex.EncodeToString(slip10MasterKey(seed)))
+
+	const mnemonic = "legal winner thank year wave sausage worth useful legal winner thank yellow"
+	nodeKey, err := NodeKeyFromMnemonic(mnemonic, "")
+	require.NoError(t, err)
+	assert.Equal(t, ed25519.KeyType, nodeKey.PrivKey.Type())
+
+	// the same mnemonic yields the same node ID, whatever the spacing
+	other, err := NodeKeyFromMnemonic(" "+strings.ReplaceAll(mnemonic, " ", "  ")+"\n", "")
+	require.NoError(t, err)
+	assert.Equal(t, nodeKey.ID(), other.ID())
+	assert.True(t, nodeKey.PrivKey.Equals(other.PrivKey))
+
+	other, err = NodeKeyFromMnemonic(mnemonic, "passphrase")
+	require.NoError(t, err)
+	assert.NotEqual(t, nodeKey.ID(), other.ID())
+	other, err = NodeKeyFromMnemonic(strings.Repeat("abandon ", 11)+"about", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, nodeKey.ID(), other.ID())
+
+	_, err = NodeKeyFromMnemonic("legal winner thank year", "")
+	require.Error(t, err)
+	// a mistyped word is rejected rather than yielding another key
+	_, err = NodeKeyFromMnemonic(strings.Replace(mnemonic, "winner", "winer", 1), "")
+	require.Error(t, err)
+}
+
 func TestPoWTarget(t *testing.T) {
 	targetBytes := 20
 	cases := []struct {
@@ -19,6 +19,12 @@ var (
 	ErrNoConnection      = errors.New("endpoint is not connected")
 	ErrReadTimeout       = errors.New("endpoint read timed out")
 	ErrWriteTimeout      = errors.New("endpoint write timed out")
+	// ErrTooManyConnections is returned by Accept when a connection was
+	// rejected because the listener's connection limit was reached.
+	ErrTooManyConnections = errors.New("too many open connections")
+	// ErrPeerNotAllowed is returned by Accept when a connection was rejected
+	// because the UID of the connecting process is not allowed.
+	ErrPeerNotAllowed = errors.New("peer is not allowed to connect")
 )
 
 // RemoteSignerError allows (remote) validators to include meaningful error
@@ -0,0 +1,34 @@
+//go:build linux
+
+package privval
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredSupported reports whether peerUID is implemented on this platform.
+const peerCredSupported = true
+
+// peerUID returns the UID of the process at the other end of conn, using
+// SO_PEERCRED.
+func peerUID(conn *net.UnixConn) (int, error) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	var (
+		cred    *syscall.Ucred
+		credErr error
+	)
+	err = raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if credErr != nil {
+		return 0, credErr
+	}
+	return int(cred.Uid), nil
+}
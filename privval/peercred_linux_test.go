@@ -0,0 +1,51 @@
+package privval
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+)
+
+func TestUnixListenerAllowedUIDs(t *testing.T) {
+	uid := os.Getuid()
+	testCases := []struct {
+		description string
+		allowedUIDs []int
+		wantErr     error
+	}{
+		{"no restriction", nil, nil},
+		{"own uid allowed", []int{uid + 1, uid}, nil},
+		{"own uid not allowed", []int{uid + 1}, ErrPeerNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			addr, err := testUnixAddr()
+			if err != nil {
+				t.Fatal(err)
+			}
+			ln, err := net.Listen("unix", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			unixLn := NewUnixListener(ln)
+			UnixListenerAllowedUIDs(tc.allowedUIDs...)(unixLn)
+			defer unixLn.Close()
+
+			client, err := DialUnixFn(addr)()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			conn, err := unixLn.Accept()
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("have %v, want %v", err, tc.wantErr)
+			}
+			if err == nil {
+				conn.Close()
+			}
+		})
+	}
+}
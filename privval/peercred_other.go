@@ -0,0 +1,16 @@
+//go:build !linux
+
+package privval
+
+import (
+	"errors"
+	"net"
+)
+
+// peerCredSupported reports whether peerUID is implemented on this platform.
+const peerCredSupported = false
+
+// peerUID is not supported on this platform.
+func peerUID(*net.UnixConn) (int, error) {
+	return 0, errors.New("peer credentials are not supported on this platform")
+}
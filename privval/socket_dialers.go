@@ -1,7 +1,9 @@
 package privval
 
 import (
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"net"
 	"time"
 
@@ -18,22 +20,108 @@ var (
 // SocketDialer dials a remote address and returns a net.Conn or an error.
 type SocketDialer func() (net.Conn, error)
 
-// DialTCPFn dials the given tcp addr, using the given timeoutReadWrite and
-// privKey for the authenticated encryption handshake.
-func DialTCPFn(addr string, timeoutReadWrite time.Duration, privKey crypto.PrivKey) SocketDialer {
+// DialTCPFn dials the given tcp addr, using privKey for the authenticated
+// encryption handshake. The handshake must complete within timeoutHandshake,
+// or within timeoutReadWrite if timeoutHandshake is zero; the connection's
+// deadline is then set to timeoutReadWrite.
+func DialTCPFn(addr string, timeoutReadWrite, timeoutHandshake time.Duration, privKey crypto.PrivKey) SocketDialer {
+	if timeoutHandshake == 0 {
+		timeoutHandshake = timeoutReadWrite
+	}
+	return func() (net.Conn, error) {
+		conn, err := cmtnet.Connect(addr)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.SetDeadline(time.Now().Add(timeoutHandshake)); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		secretConn, err := p2pconn.MakeSecretConnection(conn, privKey)
+		if err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+		if err := secretConn.SetDeadline(time.Now().Add(timeoutReadWrite)); err != nil {
+			_ = secretConn.Close()
+			return nil, err
+		}
+		return secretConn, nil
+	}
+}
+
+// DialTLSFn dials the given tcp addr and performs a TLS handshake, using the
+// given timeoutReadWrite. certFile and keyFile hold the client certificate
+// presented to the listener; caFile the CA certificate(s) used to verify the
+// listener's certificate.
+func DialTLSFn(addr string, timeoutReadWrite time.Duration, certFile, keyFile, caFile string) SocketDialer {
 	return func() (net.Conn, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		caPool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		_, address := cmtnet.ProtocolAndAddress(addr)
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+
 		conn, err := cmtnet.Connect(addr)
-		if err == nil {
-			deadline := time.Now().Add(timeoutReadWrite)
-			err = conn.SetDeadline(deadline)
+		if err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(timeoutReadWrite)
+		if err := conn.SetDeadline(deadline); err != nil {
+			_ = conn.Close()
+			return nil, err
 		}
-		if err == nil {
-			conn, err = p2pconn.MakeSecretConnection(conn, privKey)
+		tlsConn := tls.Client(conn, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			RootCAs:      caPool,
+			ServerName:   host,
+			MinVersion:   tls.VersionTLS12,
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			_ = tlsConn.Close()
+			return nil, err
+		}
+		return tlsConn, nil
+	}
+}
+
+// maxRetryBackoff caps the wait between two attempts of a RetryDialer.
+const maxRetryBackoff = 10 * time.Second
+
+// RetryDialer wraps the inner dialer, retrying up to retries times when it
+// fails. The first retry waits for backoff, and the wait doubles after each
+// subsequent attempt, up to 10s (or backoff, if larger). If all attempts
+// fail, the last error is returned.
+func RetryDialer(inner SocketDialer, retries int, backoff time.Duration) SocketDialer {
+	return func() (net.Conn, error) {
+		conn, err := inner()
+		for i := 0; err != nil && i < retries; i++ {
+			time.Sleep(retryBackoff(backoff, i))
+			conn, err = inner()
 		}
 		return conn, err
 	}
 }
 
+// retryBackoff returns the wait before the given retry (starting at 0):
+// backoff doubled attempt times, capped at max(backoff, maxRetryBackoff).
+func retryBackoff(backoff time.Duration, attempt int) time.Duration {
+	limit := max(backoff, maxRetryBackoff)
+	wait := backoff
+	for i := 0; i < attempt && wait > 0 && wait < limit; i++ {
+		wait = min(2*wait, limit)
+	}
+	return wait
+}
+
 // DialUnixFn dials the given unix socket.
 func DialUnixFn(addr string) SocketDialer {
 	return func() (net.Conn, error) {
@@ -2,6 +2,8 @@ package privval
 
 import (
 	"fmt"
+	"io"
+	"net"
 	"testing"
 	"time"
 
@@ -20,7 +22,7 @@ func getDialerTestCases(t *testing.T) []dialerTestCase {
 	return []dialerTestCase{
 		{
 			addr:   tcpAddr,
-			dialer: DialTCPFn(tcpAddr, testTimeoutReadWrite, ed25519.GenPrivKey()),
+			dialer: DialTCPFn(tcpAddr, testTimeoutReadWrite, 0, ed25519.GenPrivKey()),
 		},
 		{
 			addr:   unixAddr,
@@ -32,7 +34,7 @@ func getDialerTestCases(t *testing.T) []dialerTestCase {
 func TestIsConnTimeoutForFundamentalTimeouts(t *testing.T) {
 	// Generate a networking timeout
 	tcpAddr := GetFreeLocalhostAddrPort()
-	dialer := DialTCPFn(tcpAddr, time.Millisecond, ed25519.GenPrivKey())
+	dialer := DialTCPFn(tcpAddr, time.Millisecond, 0, ed25519.GenPrivKey())
 	_, err := dialer()
 	assert.Error(t, err)
 	assert.True(t, IsConnTimeout(err))
@@ -40,9 +42,89 @@ func TestIsConnTimeoutForFundamentalTimeouts(t *testing.T) {
 
 func TestIsConnTimeoutForWrappedConnTimeouts(t *testing.T) {
 	tcpAddr := GetFreeLocalhostAddrPort()
-	dialer := DialTCPFn(tcpAddr, time.Millisecond, ed25519.GenPrivKey())
+	dialer := DialTCPFn(tcpAddr, time.Millisecond, 0, ed25519.GenPrivKey())
 	_, err := dialer()
 	assert.Error(t, err)
 	err = fmt.Errorf("%v: %w", err, ErrConnectionTimeout)
 	assert.True(t, IsConnTimeout(err))
 }
+
+func TestDialTCPFnHandshakeTimeout(t *testing.T) {
+	// the listener accepts the TCP connection, but never completes the
+	// secret connection handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(io.Discard, conn)
+	}()
+
+	dialer := DialTCPFn(ln.Addr().String(), time.Minute, 100*time.Millisecond, ed25519.GenPrivKey())
+	start := time.Now()
+	_, err = dialer()
+	require.Error(t, err)
+	assert.True(t, IsConnTimeout(err), "have %v, want a timeout", err)
+	assert.Less(t, time.Since(start), 10*time.Second)
+}
+
+func TestRetryDialer(t *testing.T) {
+	addr := GetFreeLocalhostAddrPort()
+
+	// Nothing listens on addr during the first attempt, so it is refused. The
+	// listener is started right after, so the first retry succeeds.
+	var (
+		attempts int
+		ln       net.Listener
+	)
+	dialer := RetryDialer(func() (net.Conn, error) {
+		attempts++
+		conn, err := net.Dial("tcp", addr)
+		if attempts == 1 {
+			require.Error(t, err)
+			var lnErr error
+			ln, lnErr = net.Listen("tcp", addr)
+			require.NoError(t, lnErr)
+		}
+		return conn, err
+	}, 3, time.Millisecond)
+
+	conn, err := dialer()
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	conn.Close()
+	ln.Close()
+
+	// All attempts fail: the last error is returned.
+	attempts = 0
+	dialer = RetryDialer(func() (net.Conn, error) {
+		attempts++
+		return nil, fmt.Errorf("attempt %d", attempts)
+	}, 2, time.Millisecond)
+	_, err = dialer()
+	require.EqualError(t, err, "attempt 3")
+	assert.Equal(t, 3, attempts)
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	assert.Equal(t, time.Millisecond, retryBackoff(time.Millisecond, 0))
+	assert.Equal(t, 8*time.Millisecond, retryBackoff(time.Millisecond, 3))
+
+	// with a high retry count, the shift would overflow and go negative
+	prev := time.Duration(0)
+	for i := 0; i < 1000; i++ {
+		wait := retryBackoff(time.Millisecond, i)
+		require.Positive(t, wait, "retry %d", i)
+		require.LessOrEqual(t, wait, maxRetryBackoff, "retry %d", i)
+		require.GreaterOrEqual(t, wait, prev, "retry %d", i)
+		prev = wait
+	}
+	assert.Equal(t, maxRetryBackoff, retryBackoff(time.Millisecond, 1000))
+
+	// a backoff above the cap is used as is
+	assert.Equal(t, time.Minute, retryBackoff(time.Minute, 1000))
+}
@@ -1,10 +1,18 @@
 package privval
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/libs/log"
 	p2pconn "github.com/cometbft/cometbft/p2p/conn"
 )
 
@@ -36,6 +44,30 @@ func TCPListenerTimeoutReadWrite(timeout time.Duration) TCPListenerOption {
 	return func(tl *TCPListener) { tl.timeoutReadWrite = timeout }
 }
 
+// TCPListenerKeepAlive enables TCP keep-alive with the given period on
+// accepted connections, so that half-open connections to external signing
+// processes are detected. A negative value disables keep-alive. A zero value
+// (the default) leaves the setting of the underlying listener untouched.
+func TCPListenerKeepAlive(period time.Duration) TCPListenerOption {
+	return func(tl *TCPListener) { tl.keepAlive = period }
+}
+
+// TCPListenerHandshakeTimeout sets the time within which the authenticated
+// encryption handshake with an external signing process must complete,
+// independently of the read and write timeout. A zero value (the default)
+// disables the timeout, leaving only the read and write timeout.
+func TCPListenerHandshakeTimeout(timeout time.Duration) TCPListenerOption {
+	return func(tl *TCPListener) { tl.timeoutHandshake = timeout }
+}
+
+// TCPListenerMaxConns limits the number of accepted connections which can be
+// open at the same time. Once the limit is reached, Accept closes the new
+// connections right away and returns ErrTooManyConnections, until one of the
+// accepted connections is closed. A zero value (the default) means no limit.
+func TCPListenerMaxConns(n int) TCPListenerOption {
+	return func(tl *TCPListener) { tl.maxConns = n }
+}
+
 // tcpListener implements net.Listener.
 var _ net.Listener = (*TCPListener)(nil)
 
@@ -48,6 +80,11 @@ type TCPListener struct {
 
 	timeoutAccept    time.Duration
 	timeoutReadWrite time.Duration
+	timeoutHandshake time.Duration
+	keepAlive        time.Duration
+
+	maxConns  int
+	openConns atomic.Int64
 }
 
 // NewTCPListener returns a listener that accepts authenticated encrypted connections
@@ -63,28 +100,70 @@ func NewTCPListener(ln net.Listener, secretConnKey ed25519.PrivKey) *TCPListener
 
 // Accept implements net.Listener.
 func (ln *TCPListener) Accept() (net.Conn, error) {
-	deadline := time.Now().Add(ln.timeoutAccept)
-	err := ln.SetDeadline(deadline)
+	tc, err := ln.acceptTCP()
 	if err != nil {
 		return nil, err
 	}
 
-	tc, err := ln.AcceptTCP()
-	if err != nil {
-		return nil, err
+	var conn net.Conn = tc
+	if ln.maxConns > 0 {
+		if ln.openConns.Add(1) > int64(ln.maxConns) {
+			ln.openConns.Add(-1)
+			_ = tc.Close()
+			return nil, ErrTooManyConnections
+		}
+		conn = &limitedConn{Conn: tc, release: func() { ln.openConns.Add(-1) }}
 	}
 
 	// Wrap the conn in our timeout and encryption wrappers
-	timeoutConn := newTimeoutConn(tc, ln.timeoutReadWrite)
+	timeoutConn := newTimeoutConn(conn, ln.timeoutReadWrite)
+	if ln.timeoutHandshake > 0 {
+		timeoutConn.deadline = time.Now().Add(ln.timeoutHandshake)
+	}
 	secretConn, err := p2pconn.MakeSecretConnection(timeoutConn, ln.secretConnKey)
 	if err != nil {
 		_ = timeoutConn.Close()
 		return nil, err
 	}
+	timeoutConn.deadline = time.Time{}
 
 	return secretConn, nil
 }
 
+// acceptTCP accepts the next raw connection, applying the accept timeout and
+// the keep-alive setting.
+func (ln *TCPListener) acceptTCP() (*net.TCPConn, error) {
+	deadline := time.Now().Add(ln.timeoutAccept)
+	err := ln.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	tc, err := ln.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case ln.keepAlive > 0:
+		if err := tc.SetKeepAlive(true); err != nil {
+			_ = tc.Close()
+			return nil, err
+		}
+		if err := tc.SetKeepAlivePeriod(ln.keepAlive); err != nil {
+			_ = tc.Close()
+			return nil, err
+		}
+	case ln.keepAlive < 0:
+		if err := tc.SetKeepAlive(false); err != nil {
+			_ = tc.Close()
+			return nil, err
+		}
+	}
+
+	return tc, nil
+}
+
 //------------------------------------------------------------------
 // Unix Listener
 
@@ -105,6 +184,19 @@ func UnixListenerTimeoutReadWrite(timeout time.Duration) UnixListenerOption {
 	return func(ul *UnixListener) { ul.timeoutReadWrite = timeout }
 }
 
+// UnixListenerAllowedUIDs restricts the connections to the processes running
+// as one of the given UIDs. Accept closes connections from other processes and
+// returns ErrPeerNotAllowed. The peer credentials are only checked on Linux;
+// elsewhere the restriction is not enforced and a warning is logged instead.
+func UnixListenerAllowedUIDs(uids ...int) UnixListenerOption {
+	return func(ul *UnixListener) { ul.allowedUIDs = uids }
+}
+
+// UnixListenerLogger sets the logger of the listener.
+func UnixListenerLogger(logger log.Logger) UnixListenerOption {
+	return func(ul *UnixListener) { ul.logger = logger }
+}
+
 // UnixListener wraps a *net.UnixListener to standardize protocol timeouts
 // and potentially other tuning parameters. It returns unencrypted connections.
 type UnixListener struct {
@@ -112,6 +204,10 @@ type UnixListener struct {
 
 	timeoutAccept    time.Duration
 	timeoutReadWrite time.Duration
+
+	allowedUIDs []int
+	logger      log.Logger
+	warnOnce    sync.Once
 }
 
 // NewUnixListener returns a listener that accepts unencrypted connections
@@ -121,6 +217,7 @@ func NewUnixListener(ln net.Listener) *UnixListener {
 		UnixListener:     ln.(*net.UnixListener),
 		timeoutAccept:    time.Second * defaultTimeoutAcceptSeconds,
 		timeoutReadWrite: time.Second * defaultTimeoutReadWriteSeconds,
+		logger:           log.NewNopLogger(),
 	}
 }
 
@@ -137,6 +234,11 @@ func (ln *UnixListener) Accept() (net.Conn, error) {
 		return nil, err
 	}
 
+	if err := ln.checkPeer(tc); err != nil {
+		_ = tc.Close()
+		return nil, err
+	}
+
 	// Wrap the conn in our timeout wrapper
 	conn := newTimeoutConn(tc, ln.timeoutReadWrite)
 
@@ -146,9 +248,146 @@ func (ln *UnixListener) Accept() (net.Conn, error) {
 	return conn, nil
 }
 
+// checkPeer returns ErrPeerNotAllowed if allowed UIDs are set and the process
+// at the other end of conn runs as none of them.
+func (ln *UnixListener) checkPeer(conn *net.UnixConn) error {
+	if len(ln.allowedUIDs) == 0 {
+		return nil
+	}
+	if !peerCredSupported {
+		ln.warnOnce.Do(func() {
+			ln.logger.Error("Peer credentials are not supported on this platform, accepting connections from any UID",
+				"allowed_uids", ln.allowedUIDs)
+		})
+		return nil
+	}
+
+	uid, err := peerUID(conn)
+	if err != nil {
+		return fmt.Errorf("failed to get peer credentials: %w", err)
+	}
+	for _, allowed := range ln.allowedUIDs {
+		if uid == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: uid %d", ErrPeerNotAllowed, uid)
+}
+
+//------------------------------------------------------------------
+// TLS Listener
+
+// TLSListenerOption sets an optional parameter on the TLSListener.
+type TLSListenerOption func(*TLSListener)
+
+// TLSListenerTimeoutAccept sets the timeout for the listener.
+// A zero time value disables the timeout.
+func TLSListenerTimeoutAccept(timeout time.Duration) TLSListenerOption {
+	return func(tl *TLSListener) { tl.timeoutAccept = timeout }
+}
+
+// TLSListenerTimeoutReadWrite sets the read and write timeout for connections
+// from external signing processes.
+func TLSListenerTimeoutReadWrite(timeout time.Duration) TLSListenerOption {
+	return func(tl *TLSListener) { tl.timeoutReadWrite = timeout }
+}
+
+// tlsListener implements net.Listener.
+var _ net.Listener = (*TLSListener)(nil)
+
+// TLSListener wraps a *net.TCPListener to standardize protocol timeouts
+// and potentially other tuning parameters. It returns TLS connections whose
+// peers presented a client certificate signed by the configured CA.
+type TLSListener struct {
+	*net.TCPListener
+
+	tlsConfig *tls.Config
+
+	timeoutAccept    time.Duration
+	timeoutReadWrite time.Duration
+}
+
+// NewTLSListener returns a listener that accepts mutually authenticated TLS
+// connections using the default timeout values. certFile and keyFile hold the
+// PEM-encoded certificate and key of the listener, caFile the PEM-encoded CA
+// certificate(s) used to verify client certificates.
+func NewTLSListener(ln net.Listener, certFile, keyFile, caFile string) (*TLSListener, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	caPool, err := loadCertPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TLSListener{
+		TCPListener: ln.(*net.TCPListener),
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientCAs:    caPool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		},
+		timeoutAccept:    time.Second * defaultTimeoutAcceptSeconds,
+		timeoutReadWrite: time.Second * defaultTimeoutReadWriteSeconds,
+	}, nil
+}
+
+// Accept implements net.Listener.
+func (ln *TLSListener) Accept() (net.Conn, error) {
+	deadline := time.Now().Add(ln.timeoutAccept)
+	err := ln.SetDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	tc, err := ln.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+
+	// Wrap the conn in our timeout and TLS wrappers
+	timeoutConn := newTimeoutConn(tc, ln.timeoutReadWrite)
+	tlsConn := tls.Server(timeoutConn, ln.tlsConfig)
+	if err := tlsConn.Handshake(); err != nil {
+		_ = tlsConn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// loadCertPool reads the PEM-encoded certificates in caFile into a new pool.
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no valid certificates found in CA file " + caFile)
+	}
+	return pool, nil
+}
+
 //------------------------------------------------------------------
 // Connection
 
+// limitedConn wraps a net.Conn accepted by a TCPListener with a connection
+// limit, releasing its slot when it is closed.
+type limitedConn struct {
+	net.Conn
+	release     func()
+	releaseOnce sync.Once
+}
+
+// Close implements net.Conn.
+func (c *limitedConn) Close() error {
+	c.releaseOnce.Do(c.release)
+	return c.Conn.Close()
+}
+
 // timeoutConn implements net.Conn.
 var _ net.Conn = (*timeoutConn)(nil)
 
@@ -156,20 +395,32 @@ var _ net.Conn = (*timeoutConn)(nil)
 type timeoutConn struct {
 	net.Conn
 	timeout time.Duration
+	// deadline, if not zero, caps the deadline of every read and write, e.g.
+	// during a handshake.
+	deadline time.Time
 }
 
 // newTimeoutConn returns an instance of timeoutConn.
 func newTimeoutConn(conn net.Conn, timeout time.Duration) *timeoutConn {
 	return &timeoutConn{
-		conn,
-		timeout,
+		Conn:    conn,
+		timeout: timeout,
 	}
 }
 
+// nextDeadline returns the deadline of the next read or write.
+func (c timeoutConn) nextDeadline() time.Time {
+	deadline := time.Now().Add(c.timeout)
+	if !c.deadline.IsZero() && c.deadline.Before(deadline) {
+		return c.deadline
+	}
+	return deadline
+}
+
 // Read implements net.Conn.
 func (c timeoutConn) Read(b []byte) (n int, err error) {
 	// Reset deadline
-	deadline := time.Now().Add(c.timeout)
+	deadline := c.nextDeadline()
 	err = c.SetReadDeadline(deadline)
 	if err != nil {
 		return
@@ -181,7 +432,7 @@ func (c timeoutConn) Read(b []byte) (n int, err error) {
 // Write implements net.Conn.
 func (c timeoutConn) Write(b []byte) (n int, err error) {
 	// Reset deadline
-	deadline := time.Now().Add(c.timeout)
+	deadline := c.nextDeadline()
 	err = c.SetWriteDeadline(deadline)
 	if err != nil {
 		return
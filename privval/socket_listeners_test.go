@@ -1,8 +1,19 @@
 package privval
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -16,6 +27,84 @@ func newPrivKey() ed25519.PrivKey {
 	return ed25519.GenPrivKey()
 }
 
+type testTLSFiles struct {
+	caFile                string
+	serverCert, serverKey string
+	clientCert, clientKey string
+}
+
+// writeTestTLSFiles generates a CA as well as a server certificate for
+// 127.0.0.1 and a client certificate signed by it, and writes them as PEM
+// files into a temporary directory.
+func writeTestTLSFiles(t *testing.T) testTLSFiles {
+	t.Helper()
+	dir := t.TempDir()
+
+	writePEM := func(name, typ string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	marshalKey := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der
+	}
+
+	caKey := newKey()
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (string, string) {
+		key := newKey()
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return writePEM(name+".crt", "CERTIFICATE", der), writePEM(name+".key", "EC PRIVATE KEY", marshalKey(key))
+	}
+
+	files := testTLSFiles{caFile: writePEM("ca.crt", "CERTIFICATE", caDER)}
+	files.serverCert, files.serverKey = issue(2, "server", x509.ExtKeyUsageServerAuth)
+	files.clientCert, files.clientKey = issue(3, "client", x509.ExtKeyUsageClientAuth)
+	return files
+}
+
 //-------------------------------------------
 // tests
 
@@ -50,7 +139,7 @@ func tcpListenerTestCase(t *testing.T, timeoutAccept, timeoutReadWrite time.Dura
 	return listenerTestCase{
 		description: "TCP",
 		listener:    tcpLn,
-		dialer:      DialTCPFn(ln.Addr().String(), testTimeoutReadWrite, newPrivKey()),
+		dialer:      DialTCPFn(ln.Addr().String(), testTimeoutReadWrite, 0, newPrivKey()),
 	}
 }
 
@@ -74,10 +163,32 @@ func unixListenerTestCase(t *testing.T, timeoutAccept, timeoutReadWrite time.Dur
 	}
 }
 
+func tlsListenerTestCase(t *testing.T, timeoutAccept, timeoutReadWrite time.Duration) listenerTestCase {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := writeTestTLSFiles(t)
+	tlsLn, err := NewTLSListener(ln, files.serverCert, files.serverKey, files.caFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	TLSListenerTimeoutAccept(timeoutAccept)(tlsLn)
+	TLSListenerTimeoutReadWrite(timeoutReadWrite)(tlsLn)
+	return listenerTestCase{
+		description: "TLS",
+		listener:    tlsLn,
+		dialer: DialTLSFn(ln.Addr().String(), testTimeoutReadWrite,
+			files.clientCert, files.clientKey, files.caFile),
+	}
+}
+
 func listenerTestCases(t *testing.T, timeoutAccept, timeoutReadWrite time.Duration) []listenerTestCase {
 	return []listenerTestCase{
 		tcpListenerTestCase(t, timeoutAccept, timeoutReadWrite),
 		unixListenerTestCase(t, timeoutAccept, timeoutReadWrite),
+		tlsListenerTestCase(t, timeoutAccept, timeoutReadWrite),
 	}
 }
 
@@ -138,3 +249,169 @@ func TestListenerTimeoutReadWrite(t *testing.T) {
 		}
 	}
 }
+
+func TestTCPListenerMaxConns(t *testing.T) {
+	const maxConns = 2
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpLn := NewTCPListener(ln, newPrivKey())
+	TCPListenerMaxConns(maxConns)(tcpLn)
+	t.Cleanup(func() { _ = tcpLn.Close() })
+	dialer := DialTCPFn(ln.Addr().String(), testTimeoutReadWrite, 0, newPrivKey())
+
+	// accept connects with the dialer in the background, as the secret
+	// connection handshake needs both ends
+	accept := func() (net.Conn, error) {
+		dialed := make(chan error, 1)
+		go func() {
+			_, err := dialer()
+			dialed <- err
+		}()
+		conn, err := tcpLn.Accept()
+		if err == nil {
+			err = <-dialed
+		}
+		return conn, err
+	}
+
+	var accepted []net.Conn
+	for i := 0; i < maxConns; i++ {
+		conn, err := accept()
+		if err != nil {
+			t.Fatalf("accepting connection %d: %v", i, err)
+		}
+		accepted = append(accepted, conn)
+	}
+
+	// the extra connection is closed right away
+	extra, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer extra.Close()
+	if _, err := tcpLn.Accept(); !errors.Is(err, ErrTooManyConnections) {
+		t.Fatalf("have %v, want %v", err, ErrTooManyConnections)
+	}
+	if err := extra.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := extra.Read(make([]byte, 1)); !errors.Is(err, io.EOF) {
+		t.Fatalf("reading from the rejected connection: have %v, want %v", err, io.EOF)
+	}
+
+	// closing an accepted connection, even twice, frees a single slot
+	_ = accepted[0].Close()
+	_ = accepted[0].Close()
+	conn, err := accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for _, conn := range accepted[1:] {
+		defer conn.Close()
+	}
+	extra2, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer extra2.Close()
+	if _, err := tcpLn.Accept(); !errors.Is(err, ErrTooManyConnections) {
+		t.Fatalf("have %v, want %v", err, ErrTooManyConnections)
+	}
+}
+
+func TestTCPListenerHandshakeTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tcpLn := NewTCPListener(ln, newPrivKey())
+	TCPListenerTimeoutReadWrite(time.Minute)(tcpLn)
+	TCPListenerHandshakeTimeout(100 * time.Millisecond)(tcpLn)
+	defer tcpLn.Close()
+
+	// the client connects, but never completes the handshake
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	start := time.Now()
+	_, err = tcpLn.Accept()
+	if !IsConnTimeout(err) {
+		t.Fatalf("have %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Fatalf("the handshake timed out after %v", elapsed)
+	}
+}
+
+func TestTLSListenerRejectsUnauthenticatedClients(t *testing.T) {
+	files := writeTestTLSFiles(t)
+	// a second, unrelated CA and the client certificate it issued
+	untrusted := writeTestTLSFiles(t)
+
+	testCases := []struct {
+		description       string
+		certFile, keyFile string
+	}{
+		{"no client certificate", "", ""},
+		{"client certificate from an untrusted CA", untrusted.clientCert, untrusted.clientKey},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tlsLn, err := NewTLSListener(ln, files.serverCert, files.serverKey, files.caFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tlsLn.Close()
+
+			// the client trusts the server, so only the client side can fail
+			caPool, err := loadCertPool(files.caFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			clientConfig := &tls.Config{
+				RootCAs:    caPool,
+				ServerName: "127.0.0.1",
+				MinVersion: tls.VersionTLS12,
+			}
+			if tc.certFile != "" {
+				cert, err := tls.LoadX509KeyPair(tc.certFile, tc.keyFile)
+				if err != nil {
+					t.Fatal(err)
+				}
+				clientConfig.Certificates = []tls.Certificate{cert}
+			}
+
+			go func() {
+				conn, err := net.Dial("tcp", ln.Addr().String())
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				_ = conn.SetDeadline(time.Now().Add(time.Second))
+				tlsConn := tls.Client(conn, clientConfig)
+				// with TLS 1.3, the client only learns about the rejection
+				// when reading from the connection
+				if err := tlsConn.Handshake(); err == nil {
+					_, _ = tlsConn.Read(make([]byte, 1))
+				}
+			}()
+
+			c, err := tlsLn.Accept()
+			if err == nil {
+				c.Close()
+				t.Fatal("expected the TLS handshake to be rejected")
+			}
+		})
+	}
+}
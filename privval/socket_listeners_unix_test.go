@@ -0,0 +1,52 @@
+//go:build unix
+
+package privval
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTCPListenerKeepAlive(t *testing.T) {
+	for _, tc := range []struct {
+		keepAlive time.Duration
+		enabled   bool
+	}{
+		{-1, false},
+		{time.Second, true},
+	} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+
+		tcpLn := NewTCPListener(ln, newPrivKey())
+		TCPListenerKeepAlive(tc.keepAlive)(tcpLn)
+
+		go func() {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err == nil {
+				conn.Close()
+			}
+		}()
+
+		conn, err := tcpLn.acceptTCP()
+		require.NoError(t, err)
+
+		rawConn, err := conn.SyscallConn()
+		require.NoError(t, err)
+		var keepAlive int
+		var sockErr error
+		err = rawConn.Control(func(fd uintptr) {
+			keepAlive, sockErr = syscall.GetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_KEEPALIVE)
+		})
+		require.NoError(t, err)
+		require.NoError(t, sockErr)
+		require.Equal(t, tc.enabled, keepAlive != 0, "keepAlive=%v", tc.keepAlive)
+
+		conn.Close()
+		tcpLn.Close()
+	}
+}
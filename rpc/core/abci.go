@@ -2,43 +2,361 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
 
 	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/merkle"
 	"github.com/cometbft/cometbft/libs/bytes"
 	"github.com/cometbft/cometbft/proxy"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 	rpctypes "github.com/cometbft/cometbft/rpc/jsonrpc/types"
 )
 
-// ABCIQuery queries the application for some information.
+// ABCIQuery queries the application for some information. A path prefixed by
+// "@<id>" is sent over the query connection registered under that ID (see
+// RegisterQueryConn).
 // More: https://docs.cometbft.com/v0.38/spec/rpc/#abciquery
 func (env *Environment) ABCIQuery(
-	_ *rpctypes.Context,
+	ctx *rpctypes.Context,
 	path string,
 	data bytes.HexBytes,
 	height int64,
 	prove bool,
 ) (*ctypes.ResultABCIQuery, error) {
-	resQuery, err := env.ProxyAppQuery.Query(context.TODO(), &abci.RequestQuery{
+	conn, path, err := env.queryConn(path)
+	if err != nil {
+		return nil, errInvalidQuery(err)
+	}
+	if height < 0 {
+		return nil, errInvalidQuery(fmt.Errorf("height must be non-negative, but got %d", height))
+	}
+	if err := env.checkHeightNotPruned(height); err != nil {
+		return nil, err
+	}
+
+	queryCtx, cancel := env.abciQueryContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	resQuery, err := conn.Query(queryCtx, &abci.RequestQuery{
 		Path:   path,
 		Data:   data,
 		Height: height,
 		Prove:  prove,
 	})
+	env.metrics().ABCIQueryDurationSeconds.With("path", env.queryPathLabel(path)).
+		Observe(time.Since(start).Seconds())
 	if err != nil {
+		return nil, errAppUnavailable(err)
+	}
+	if err := env.checkQueryResponseSize(resQuery); err != nil {
 		return nil, err
 	}
 
 	return &ctypes.ResultABCIQuery{Response: *resQuery}, nil
 }
 
-// ABCIInfo gets some info about the application.
-// More: https://docs.cometbft.com/v0.38/spec/rpc/#abciinfo
-func (env *Environment) ABCIInfo(_ *rpctypes.Context) (*ctypes.ResultABCIInfo, error) {
-	resInfo, err := env.ProxyAppQuery.Info(context.TODO(), proxy.RequestInfo)
+// ABCIQueryByHash queries the application like ABCIQuery, at the height of
+// the block with the given hash. An error is returned if the node has no block
+// with this hash, e.g. because it was pruned.
+func (env *Environment) ABCIQueryByHash(
+	ctx *rpctypes.Context,
+	path string,
+	data bytes.HexBytes,
+	hash bytes.HexBytes,
+	prove bool,
+) (*ctypes.ResultABCIQuery, error) {
+	if len(hash) == 0 {
+		return nil, errInvalidQuery(errors.New("hash must not be empty"))
+	}
+	blockMeta := env.BlockStore.LoadBlockMetaByHash(hash)
+	if blockMeta == nil {
+		return nil, &rpctypes.RPCError{
+			Code:    rpctypes.CodeHeightNotAvailable,
+			Message: "Height not available",
+			Data:    fmt.Sprintf("block %X not found, it is unknown or was pruned", hash),
+		}
+	}
+	return env.ABCIQuery(ctx, path, data, blockMeta.Header.Height, prove)
+}
+
+// ABCIQueryAtHeight queries the application like ABCIQuery for each of
+// paths, with the data at the same index in datas, all at the same height so
+// that the responses are consistent with each other even if a block is
+// committed in the meantime. A zero height is resolved to the latest height
+// beforehand. The height queried is returned along with the responses, which
+// are in the order of paths.
+func (env *Environment) ABCIQueryAtHeight(
+	ctx *rpctypes.Context,
+	paths []string,
+	datas []bytes.HexBytes,
+	height int64,
+) (*ctypes.ResultABCIQueryAtHeight, error) {
+	if len(paths) == 0 {
+		return nil, errInvalidQuery(errors.New("paths must not be empty"))
+	}
+	if len(datas) != len(paths) {
+		return nil, errInvalidQuery(fmt.Errorf("got %d paths but %d datas", len(paths), len(datas)))
+	}
+	if height < 0 {
+		return nil, errInvalidQuery(fmt.Errorf("height must be non-negative, but got %d", height))
+	}
+	latestHeight := env.BlockStore.Height()
+	if height == 0 {
+		if latestHeight == 0 {
+			return nil, &rpctypes.RPCError{
+				Code:    rpctypes.CodeHeightNotAvailable,
+				Message: "Height not available",
+				Data:    "no block was committed yet",
+			}
+		}
+		height = latestHeight
+	} else if height > latestHeight {
+		return nil, &rpctypes.RPCError{
+			Code:    rpctypes.CodeHeightNotAvailable,
+			Message: "Height not available",
+			Data:    fmt.Sprintf("height %d is not committed yet, latest height is %d", height, latestHeight),
+		}
+	}
+
+	responses := make([]abci.ResponseQuery, 0, len(paths))
+	for i, path := range paths {
+		res, err := env.ABCIQuery(ctx, path, datas[i], height, false)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, res.Response)
+	}
+	return &ctypes.ResultABCIQueryAtHeight{Height: height, Responses: responses}, nil
+}
+
+// storeNameRegexp extracts the store name from an /abci_query path of the form
+// "/store/<name>/key", as used by the Cosmos SDK.
+var storeNameRegexp = regexp.MustCompile(`\/store\/(.+)\/key`)
+
+// ABCIQueryVerified queries the application like ABCIQuery. If prove is set,
+// the proof returned by the application is verified against the app hash
+// committed in the block at height+1 (the app hash resulting from height H is
+// included in header H+1), and an error is returned if it is invalid.
+//
+// For paths of the form "/store/<name>/key" the Merkle key path is
+// [<name>, key]; otherwise it is just [key].
+func (env *Environment) ABCIQueryVerified(
+	ctx *rpctypes.Context,
+	path string,
+	data bytes.HexBytes,
+	height int64,
+	prove bool,
+) (*ctypes.ResultABCIQueryVerified, error) {
+	res, err := env.ABCIQuery(ctx, path, data, height, prove)
 	if err != nil {
 		return nil, err
 	}
+	if !prove {
+		return &ctypes.ResultABCIQueryVerified{Response: res.Response}, nil
+	}
+
+	resp := res.Response
+	if resp.IsErr() {
+		return nil, fmt.Errorf("query failed with code %d: %s", resp.Code, resp.Log)
+	}
+	if resp.ProofOps == nil || len(resp.ProofOps.Ops) == 0 {
+		return nil, errors.New("no proof ops in query response")
+	}
+	if resp.Height <= 0 {
+		return nil, fmt.Errorf("invalid query response height %d", resp.Height)
+	}
+
+	blockMeta := env.BlockStore.LoadBlockMeta(resp.Height + 1)
+	if blockMeta == nil {
+		if err := env.checkHeightNotPruned(resp.Height + 1); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("app hash for height %d is not committed yet", resp.Height)
+	}
+	appHash := blockMeta.Header.AppHash
+
+	kp := merkle.KeyPath{}
+	if matches := storeNameRegexp.FindStringSubmatch(path); len(matches) == 2 {
+		kp = kp.AppendKey([]byte(matches[1]), merkle.KeyEncodingURL)
+	}
+	kp = kp.AppendKey(resp.Key, merkle.KeyEncodingURL)
+
+	prt := merkle.DefaultProofRuntime()
+	if resp.Value != nil {
+		err = prt.VerifyValue(resp.ProofOps, appHash, kp.String(), resp.Value)
+		if err != nil {
+			return nil, fmt.Errorf("verify value proof: %w", err)
+		}
+	} else {
+		err = prt.VerifyAbsence(resp.ProofOps, appHash, kp.String())
+		if err != nil {
+			return nil, fmt.Errorf("verify absence proof: %w", err)
+		}
+	}
+
+	return &ctypes.ResultABCIQueryVerified{Response: resp, Verified: true}, nil
+}
+
+// ABCIInfo gets some info about the application, along with the lowest
+// heights the node can answer queries for. If ABCIInfoCacheTTL is set, the
+// application's response may be served from a cache.
+// More: https://docs.cometbft.com/v0.38/spec/rpc/#abciinfo
+func (env *Environment) ABCIInfo(ctx *rpctypes.Context) (*ctypes.ResultABCIInfo, error) {
+	var cacheGen uint64
+	if env.ABCIInfoCacheTTL > 0 {
+		var resInfo *abci.ResponseInfo
+		if resInfo, cacheGen = env.infoCache.get(time.Now()); resInfo != nil {
+			return env.resultABCIInfo(resInfo), nil
+		}
+	}
+
+	queryCtx, cancel := env.abciQueryContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	resInfo, err := env.ProxyAppQuery.Info(queryCtx, proxy.RequestInfo)
+	env.metrics().ABCIInfoDurationSeconds.Observe(time.Since(start).Seconds())
+	if err != nil {
+		return nil, errAppUnavailable(err)
+	}
+
+	if env.ABCIInfoCacheTTL > 0 {
+		env.infoCache.set(resInfo, time.Now().Add(env.ABCIInfoCacheTTL), cacheGen)
+	}
+	return env.resultABCIInfo(resInfo), nil
+}
+
+// resultABCIInfo returns the result of ABCIInfo for the application's
+// response. The heights are read from the block store on every call, as
+// they are not invalidated with the cached response.
+func (env *Environment) resultABCIInfo(resInfo *abci.ResponseInfo) *ctypes.ResultABCIInfo {
+	res := &ctypes.ResultABCIInfo{Response: *resInfo}
+	if env.BlockStore != nil {
+		// checkHeightNotPruned accepts any height from the base up
+		res.BaseHeight = env.BlockStore.Base()
+		res.EarliestQueryableHeight = res.BaseHeight
+	}
+	return res
+}
+
+// abciQueryContext returns the context for a call to the application's query
+// connection. It is derived from the request's context, so that it is
+// canceled when the client goes away, and bounded by ABCIQueryTimeout if set.
+func (env *Environment) abciQueryContext(ctx *rpctypes.Context) (context.Context, context.CancelFunc) {
+	parent := context.Background()
+	if ctx != nil {
+		parent = ctx.Context()
+	}
+	if env.ABCIQueryTimeout > 0 {
+		return context.WithTimeout(parent, env.ABCIQueryTimeout)
+	}
+	return context.WithCancel(parent)
+}
+
+// checkHeightNotPruned returns a CodeHeightNotAvailable error if height is
+// below the lowest height the node has blocks for, i.e. the node has pruned
+// it. Zero means the latest height and is always allowed.
+func (env *Environment) checkHeightNotPruned(height int64) error {
+	if height <= 0 || env.BlockStore == nil {
+		return nil
+	}
+	if base := env.BlockStore.Base(); height < base {
+		return &rpctypes.RPCError{
+			Code:    rpctypes.CodeHeightNotAvailable,
+			Message: "Height not available",
+			Data:    fmt.Sprintf("height %d is pruned, lowest height is %d", height, base),
+		}
+	}
+	return nil
+}
+
+// checkQueryResponseSize returns an error if the value and proof of res are
+// larger than MaxQueryResponseBytes, so that an application cannot make the
+// node hold and send an arbitrarily large response.
+func (env *Environment) checkQueryResponseSize(res *abci.ResponseQuery) error {
+	if env.MaxQueryResponseBytes <= 0 {
+		return nil
+	}
+	size := int64(len(res.Value))
+	if res.ProofOps != nil {
+		size += int64(res.ProofOps.Size())
+	}
+	if size > env.MaxQueryResponseBytes {
+		return &rpctypes.RPCError{
+			Code:    rpctypes.CodeServerError,
+			Message: "Query response too large",
+			Data: fmt.Sprintf("the value and proof of the response are %d bytes, more than the maximum of %d",
+				size, env.MaxQueryResponseBytes),
+		}
+	}
+	return nil
+}
+
+// errInvalidQuery returns an invalid params error for a malformed query, such
+// as one for an unknown query connection.
+func errInvalidQuery(err error) error {
+	return &rpctypes.RPCError{Code: -32602, Message: "Invalid params", Data: err.Error()}
+}
+
+// errAppUnavailable returns a CodeAppUnavailable error for an error returned
+// by the application's query connection.
+func errAppUnavailable(err error) error {
+	return &rpctypes.RPCError{
+		Code:    rpctypes.CodeAppUnavailable,
+		Message: "Application unavailable",
+		Data:    err.Error(),
+	}
+}
+
+// abciInfoCacheSubscriber is the EventBus subscriber used to invalidate the
+// abciInfoCache.
+const abciInfoCacheSubscriber = "rpc-abci-info-cache"
+
+// abciInfoCacheCapacity is the capacity of the subscription to new blocks
+// used to invalidate the abciInfoCache.
+const abciInfoCacheCapacity = 10
+
+// abciInfoCache holds the application's last Info response until it expires
+// or is invalidated. The generation is bumped on each invalidation, so that
+// responses queried before a new block are not cached after it.
+type abciInfoCache struct {
+	mtx     sync.Mutex
+	res     *abci.ResponseInfo
+	expires time.Time
+	gen     uint64
+}
+
+// get returns the cached response, or nil if there is none or it expired,
+// along with the current generation.
+func (c *abciInfoCache) get(now time.Time) (*abci.ResponseInfo, uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if c.res == nil || !now.Before(c.expires) {
+		return nil, c.gen
+	}
+	return c.res, c.gen
+}
+
+// set caches res until expires, unless the cache was invalidated since gen
+// was returned by get.
+func (c *abciInfoCache) set(res *abci.ResponseInfo, expires time.Time, gen uint64) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if gen != c.gen {
+		return
+	}
+	c.res = res
+	c.expires = expires
+}
 
-	return &ctypes.ResultABCIInfo{Response: *resInfo}, nil
+func (c *abciInfoCache) invalidate() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.res = nil
+	c.gen++
 }
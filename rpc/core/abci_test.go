@@ -0,0 +1,483 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/crypto/merkle"
+	"github.com/cometbft/cometbft/crypto/tmhash"
+	"github.com/cometbft/cometbft/libs/bytes"
+	cmtcrypto "github.com/cometbft/cometbft/proto/tendermint/crypto"
+	proxymocks "github.com/cometbft/cometbft/proxy/mocks"
+	rpctypes "github.com/cometbft/cometbft/rpc/jsonrpc/types"
+	"github.com/cometbft/cometbft/state/mocks"
+	"github.com/cometbft/cometbft/types"
+)
+
+// kvLeaf encodes a key/value pair the way merkle.ValueOp hashes it.
+func kvLeaf(key, value []byte) []byte {
+	var bz []byte
+	bz = binary.AppendUvarint(bz, uint64(len(key)))
+	bz = append(bz, key...)
+	vhash := tmhash.Sum(value)
+	bz = binary.AppendUvarint(bz, uint64(len(vhash)))
+	return append(bz, vhash...)
+}
+
+func TestABCIQueryVerified(t *testing.T) {
+	const queryHeight int64 = 5
+	key, value := []byte("foo"), []byte("bar")
+	appHash, proofs := merkle.ProofsFromByteSlices([][]byte{
+		kvLeaf(key, value),
+		kvLeaf([]byte("baz"), []byte("qux")),
+	})
+	proofOp := merkle.NewValueOp(key, proofs[0]).ProofOp()
+
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("LoadBlockMeta", queryHeight+1).Return(&types.BlockMeta{
+		Header: types.Header{Height: queryHeight + 1, AppHash: appHash},
+	})
+	blockStore.On("LoadBlockMeta", mock.Anything).Return(nil)
+	blockStore.On("Base").Return(int64(1))
+
+	testCases := []struct {
+		name     string
+		value    []byte
+		height   int64
+		prove    bool
+		wantErr  bool
+		verified bool
+	}{
+		{"valid proof", value, queryHeight, true, false, true},
+		{"no proof requested", value, queryHeight, false, false, false},
+		{"wrong value", []byte("wrong"), queryHeight, true, true, false},
+		{"app hash not committed", value, queryHeight + 1, true, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			proxyApp := &proxymocks.AppConnQuery{}
+			proxyApp.On("Query", mock.Anything, mock.Anything).Return(&abci.ResponseQuery{
+				Key:      key,
+				Value:    tc.value,
+				Height:   tc.height,
+				ProofOps: &cmtcrypto.ProofOps{Ops: []cmtcrypto.ProofOp{proofOp}},
+			}, nil)
+			env := &Environment{ProxyAppQuery: proxyApp, BlockStore: blockStore}
+
+			res, err := env.ABCIQueryVerified(&rpctypes.Context{}, "/key", key, 0, tc.prove)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.verified, res.Verified)
+			require.Equal(t, tc.value, res.Response.Value)
+		})
+	}
+}
+
+func TestABCIQueryByHash(t *testing.T) {
+	const height int64 = 7
+	knownHash := tmhash.Sum([]byte("known"))
+	unknownHash := tmhash.Sum([]byte("unknown"))
+
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("LoadBlockMetaByHash", knownHash).Return(&types.BlockMeta{
+		Header: types.Header{Height: height},
+	})
+	blockStore.On("LoadBlockMetaByHash", mock.Anything).Return(nil)
+	blockStore.On("Base").Return(int64(1))
+
+	proxyApp := &proxymocks.AppConnQuery{}
+	proxyApp.On("Query", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, req *abci.RequestQuery) (*abci.ResponseQuery, error) {
+			return &abci.ResponseQuery{Key: req.Data, Height: req.Height}, nil
+		})
+	env := &Environment{ProxyAppQuery: proxyApp, BlockStore: blockStore}
+
+	res, err := env.ABCIQueryByHash(&rpctypes.Context{}, "/key", []byte("foo"), knownHash, false)
+	require.NoError(t, err)
+	assert.Equal(t, height, res.Response.Height)
+	assert.Equal(t, []byte("foo"), res.Response.Key)
+
+	_, err = env.ABCIQueryByHash(&rpctypes.Context{}, "/key", []byte("foo"), unknownHash, false)
+	var rpcErr *rpctypes.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, rpctypes.CodeHeightNotAvailable, rpcErr.Code)
+	proxyApp.AssertNumberOfCalls(t, "Query", 1)
+}
+
+func TestABCIQueryAtHeight(t *testing.T) {
+	blockStore := &mocks.BlockStore{}
+	latestHeight := int64(7)
+	blockStore.On("Height").Return(func() int64 { return latestHeight })
+	blockStore.On("Base").Return(int64(1))
+
+	proxyApp := &proxymocks.AppConnQuery{}
+	proxyApp.On("Query", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, req *abci.RequestQuery) (*abci.ResponseQuery, error) {
+			// a block is committed while the queries are made
+			latestHeight++
+			return &abci.ResponseQuery{Key: req.Data, Log: req.Path, Height: req.Height}, nil
+		})
+	env := &Environment{ProxyAppQuery: proxyApp, BlockStore: blockStore}
+
+	paths := []string{"/a", "/b", "/c"}
+	datas := []bytes.HexBytes{[]byte("foo"), []byte("bar"), nil}
+
+	// the latest height is resolved once and echoed back
+	res, err := env.ABCIQueryAtHeight(&rpctypes.Context{}, paths, datas, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 7, res.Height)
+	require.Len(t, res.Responses, len(paths))
+	for i, resp := range res.Responses {
+		assert.EqualValues(t, 7, resp.Height)
+		assert.Equal(t, paths[i], resp.Log)
+		assert.Equal(t, []byte(datas[i]), resp.Key)
+	}
+
+	res, err = env.ABCIQueryAtHeight(&rpctypes.Context{}, paths[:1], datas[:1], 5)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, res.Height)
+	assert.EqualValues(t, 5, res.Responses[0].Height)
+
+	var rpcErr *rpctypes.RPCError
+	_, err = env.ABCIQueryAtHeight(&rpctypes.Context{}, paths, datas, latestHeight+1)
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, rpctypes.CodeHeightNotAvailable, rpcErr.Code)
+	_, err = env.ABCIQueryAtHeight(&rpctypes.Context{}, paths, datas[:2], 0)
+	require.Error(t, err)
+	_, err = env.ABCIQueryAtHeight(&rpctypes.Context{}, nil, nil, 0)
+	require.Error(t, err)
+	_, err = env.ABCIQueryAtHeight(&rpctypes.Context{}, paths, datas, -1)
+	require.Error(t, err)
+}
+
+func TestABCIQueryMaxResponseBytes(t *testing.T) {
+	value := make([]byte, 1000)
+	proofOps := &cmtcrypto.ProofOps{Ops: []cmtcrypto.ProofOp{{Type: "test", Key: []byte("key"), Data: make([]byte, 100)}}}
+	proxyApp := &proxymocks.AppConnQuery{}
+	proxyApp.On("Query", mock.Anything, mock.Anything).Return(
+		func(_ context.Context, req *abci.RequestQuery) (*abci.ResponseQuery, error) {
+			res := &abci.ResponseQuery{Value: value}
+			if req.Prove {
+				res.ProofOps = proofOps
+			}
+			return res, nil
+		})
+	env := &Environment{ProxyAppQuery: proxyApp, MaxQueryResponseBytes: int64(len(value))}
+
+	res, err := env.ABCIQuery(&rpctypes.Context{}, "/key", nil, 0, false)
+	require.NoError(t, err)
+	assert.Equal(t, value, res.Response.Value)
+
+	// the proof counts towards the limit
+	_, err = env.ABCIQuery(&rpctypes.Context{}, "/key", nil, 0, true)
+	var rpcErr *rpctypes.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, rpctypes.CodeServerError, rpcErr.Code)
+
+	env.MaxQueryResponseBytes = int64(len(value)) - 1
+	_, err = env.ABCIQuery(&rpctypes.Context{}, "/key", nil, 0, false)
+	require.ErrorAs(t, err, &rpcErr)
+
+	// no limit
+	env.MaxQueryResponseBytes = 0
+	res, err = env.ABCIQuery(&rpctypes.Context{}, "/key", nil, 0, true)
+	require.NoError(t, err)
+	assert.Equal(t, proofOps, res.Response.ProofOps)
+}
+
+func TestABCIQueryContext(t *testing.T) {
+	// The fake application blocks until the call's context is done.
+	proxyApp := &proxymocks.AppConnQuery{}
+	proxyApp.On("Query", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(nil, context.Canceled)
+	proxyApp.On("Info", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(nil, context.Canceled)
+
+	call := func(env *Environment, ctx *rpctypes.Context) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := env.ABCIQuery(ctx, "/key", []byte("foo"), 0, false)
+			assert.Error(t, err)
+			_, err = env.ABCIInfo(ctx)
+			assert.Error(t, err)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("ABCI call did not return after its context was done")
+		}
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		env := &Environment{ProxyAppQuery: proxyApp, ABCIQueryTimeout: 10 * time.Millisecond}
+		call(env, &rpctypes.Context{})
+	})
+
+	t.Run("client gone", func(t *testing.T) {
+		env := &Environment{ProxyAppQuery: proxyApp}
+		reqCtx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequest("GET", "/abci_query", nil).WithContext(reqCtx)
+		time.AfterFunc(10*time.Millisecond, cancel)
+		call(env, &rpctypes.Context{HTTPReq: req})
+	})
+}
+
+func TestABCIInfoCache(t *testing.T) {
+	proxyApp := &proxymocks.AppConnQuery{}
+	proxyApp.On("Info", mock.Anything, mock.Anything).
+		Return(&abci.ResponseInfo{LastBlockHeight: 1}, nil)
+
+	eventBus := types.NewEventBus()
+	require.NoError(t, eventBus.Start())
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	env := &Environment{
+		ProxyAppQuery:    proxyApp,
+		EventBus:         eventBus,
+		ABCIInfoCacheTTL: 100 * time.Millisecond,
+	}
+	require.NoError(t, env.InitABCIInfoCache())
+
+	// Within the TTL the cached response is served.
+	for i := 0; i < 3; i++ {
+		res, err := env.ABCIInfo(&rpctypes.Context{})
+		require.NoError(t, err)
+		require.EqualValues(t, 1, res.Response.LastBlockHeight)
+	}
+	proxyApp.AssertNumberOfCalls(t, "Info", 1)
+
+	// Once the TTL elapses the application is queried again.
+	time.Sleep(2 * env.ABCIInfoCacheTTL)
+	_, err := env.ABCIInfo(&rpctypes.Context{})
+	require.NoError(t, err)
+	proxyApp.AssertNumberOfCalls(t, "Info", 2)
+
+	// A new block invalidates the cache before the TTL elapses.
+	ttl := env.ABCIInfoCacheTTL
+	env.ABCIInfoCacheTTL = time.Hour
+	time.Sleep(2 * ttl)
+	for i := 0; i < 2; i++ {
+		_, err = env.ABCIInfo(&rpctypes.Context{})
+		require.NoError(t, err)
+	}
+	proxyApp.AssertNumberOfCalls(t, "Info", 3)
+	require.NoError(t, eventBus.PublishEventNewBlock(types.EventDataNewBlock{
+		Block: &types.Block{Header: types.Header{Height: 2}},
+	}))
+	require.Eventually(t, func() bool {
+		res, _ := env.infoCache.get(time.Now())
+		return res == nil
+	}, time.Second, 5*time.Millisecond)
+	_, err = env.ABCIInfo(&rpctypes.Context{})
+	require.NoError(t, err)
+	proxyApp.AssertNumberOfCalls(t, "Info", 4)
+
+	// Caching is disabled by default.
+	env = &Environment{ProxyAppQuery: proxyApp}
+	for i := 0; i < 2; i++ {
+		_, err = env.ABCIInfo(&rpctypes.Context{})
+		require.NoError(t, err)
+	}
+	proxyApp.AssertNumberOfCalls(t, "Info", 6)
+}
+
+func TestABCIInfoHeights(t *testing.T) {
+	proxyApp := &proxymocks.AppConnQuery{}
+	proxyApp.On("Info", mock.Anything, mock.Anything).
+		Return(&abci.ResponseInfo{LastBlockHeight: 20}, nil)
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("Base").Return(int64(5)).Once()
+	blockStore.On("Base").Return(int64(8))
+
+	env := &Environment{
+		ProxyAppQuery:    proxyApp,
+		BlockStore:       blockStore,
+		ABCIInfoCacheTTL: time.Hour,
+	}
+	res, err := env.ABCIInfo(&rpctypes.Context{})
+	require.NoError(t, err)
+	assert.EqualValues(t, 20, res.Response.LastBlockHeight)
+	assert.EqualValues(t, 5, res.BaseHeight)
+	assert.EqualValues(t, 5, res.EarliestQueryableHeight)
+
+	// the heights are up to date even when the response is cached
+	res, err = env.ABCIInfo(&rpctypes.Context{})
+	require.NoError(t, err)
+	proxyApp.AssertNumberOfCalls(t, "Info", 1)
+	assert.EqualValues(t, 8, res.BaseHeight)
+	assert.EqualValues(t, 8, res.EarliestQueryableHeight)
+}
+
+func TestABCIQueryConn(t *testing.T) {
+	newConn := func(value string) *proxymocks.AppConnQuery {
+		conn := &proxymocks.AppConnQuery{}
+		conn.On("Query", mock.Anything, mock.Anything).Return(
+			func(_ context.Context, req *abci.RequestQuery) (*abci.ResponseQuery, error) {
+				return &abci.ResponseQuery{Key: []byte(req.Path), Value: []byte(value)}, nil
+			})
+		return conn
+	}
+	env := &Environment{ProxyAppQuery: newConn("default")}
+	require.NoError(t, env.RegisterQueryConn("replica", newConn("replica")))
+
+	require.Error(t, env.RegisterQueryConn("replica", newConn("other")))
+	require.Error(t, env.RegisterQueryConn("", newConn("other")))
+	require.Error(t, env.RegisterQueryConn("a/b", newConn("other")))
+	require.Error(t, env.RegisterQueryConn("other", nil))
+
+	testCases := []struct {
+		path    string
+		conn    string
+		sent    string
+		wantErr bool
+	}{
+		{"/store/bank/key", "default", "/store/bank/key", false},
+		{"@replica/store/bank/key", "replica", "/store/bank/key", false},
+		{"@replica", "replica", "/", false},
+		{"@unknown/store/bank/key", "", "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			res, err := env.ABCIQuery(&rpctypes.Context{}, tc.path, nil, 0, false)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.conn, string(res.Response.Value))
+			require.Equal(t, tc.sent, string(res.Response.Key))
+		})
+	}
+}
+
+func TestABCIQueryErrorCodes(t *testing.T) {
+	blockStore := &mocks.BlockStore{}
+	blockStore.On("Base").Return(int64(10))
+	blockStore.On("LoadBlockMeta", mock.Anything).Return(nil)
+
+	available := &proxymocks.AppConnQuery{}
+	available.On("Query", mock.Anything, mock.Anything).
+		Return(&abci.ResponseQuery{
+			Height:   5,
+			ProofOps: &cmtcrypto.ProofOps{Ops: []cmtcrypto.ProofOp{{Type: "test"}}},
+		}, nil)
+	unavailable := &proxymocks.AppConnQuery{}
+	unavailable.On("Query", mock.Anything, mock.Anything).
+		Return(nil, errors.New("connection refused"))
+	unavailable.On("Info", mock.Anything, mock.Anything).
+		Return(nil, errors.New("connection refused"))
+
+	requireCode := func(t *testing.T, code int, err error) {
+		t.Helper()
+		var rpcErr *rpctypes.RPCError
+		require.ErrorAs(t, err, &rpcErr)
+		require.Equal(t, code, rpcErr.Code)
+	}
+
+	t.Run("app unavailable", func(t *testing.T) {
+		env := &Environment{ProxyAppQuery: unavailable, BlockStore: blockStore}
+		_, err := env.ABCIQuery(&rpctypes.Context{}, "/key", nil, 0, false)
+		requireCode(t, rpctypes.CodeAppUnavailable, err)
+		_, err = env.ABCIInfo(&rpctypes.Context{})
+		requireCode(t, rpctypes.CodeAppUnavailable, err)
+	})
+
+	t.Run("bad path", func(t *testing.T) {
+		env := &Environment{ProxyAppQuery: available, BlockStore: blockStore}
+		_, err := env.ABCIQuery(&rpctypes.Context{}, "@unknown/key", nil, 0, false)
+		requireCode(t, -32602, err)
+	})
+
+	t.Run("height pruned", func(t *testing.T) {
+		env := &Environment{ProxyAppQuery: available, BlockStore: blockStore}
+		_, err := env.ABCIQuery(&rpctypes.Context{}, "/key", nil, 5, false)
+		requireCode(t, rpctypes.CodeHeightNotAvailable, err)
+
+		// The proof of a response at a pruned height cannot be verified.
+		_, err = env.ABCIQueryVerified(&rpctypes.Context{}, "/key", nil, 0, true)
+		requireCode(t, rpctypes.CodeHeightNotAvailable, err)
+
+		_, err = env.ABCIQuery(&rpctypes.Context{}, "/key", nil, 10, false)
+		require.NoError(t, err)
+	})
+}
+
+// labelHistogram is a metrics.Histogram recording the label values of each
+// observation.
+type labelHistogram struct {
+	lvs      []string
+	observed *[][]string
+}
+
+func (h labelHistogram) With(labelValues ...string) metrics.Histogram {
+	return labelHistogram{lvs: append(append([]string{}, h.lvs...), labelValues...), observed: h.observed}
+}
+
+func (h labelHistogram) Observe(float64) {
+	*h.observed = append(*h.observed, h.lvs)
+}
+
+func TestABCIQueryMetrics(t *testing.T) {
+	conn := &proxymocks.AppConnQuery{}
+	conn.On("Query", mock.Anything, mock.Anything).Return(&abci.ResponseQuery{}, nil)
+	conn.On("Info", mock.Anything, mock.Anything).Return(&abci.ResponseInfo{}, nil)
+
+	var queries, infos [][]string
+	env := &Environment{
+		ProxyAppQuery: conn,
+		Metrics: &Metrics{
+			ABCIQueryDurationSeconds: labelHistogram{observed: &queries},
+			ABCIInfoDurationSeconds:  labelHistogram{observed: &infos},
+		},
+		ABCIQueryMetricsPaths: []string{"/store", "/store/bank", "/app/version"},
+	}
+	require.NoError(t, env.RegisterQueryConn("replica", conn))
+
+	for _, path := range []string{
+		"/store/bank/key",
+		"/store/staking/key",
+		"/app/version",
+		"/custom/key",
+		"@replica/store/bank/key",
+	} {
+		_, err := env.ABCIQuery(&rpctypes.Context{}, path, nil, 0, false)
+		require.NoError(t, err)
+	}
+	_, err := env.ABCIInfo(&rpctypes.Context{})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		{"path", "/store/bank"},
+		{"path", "/store"},
+		{"path", "/app/version"},
+		{"path", "other"},
+		{"path", "/store/bank"},
+	}, queries)
+	assert.Len(t, infos, 1)
+
+	// without metrics, observations are discarded
+	env.Metrics = nil
+	_, err = env.ABCIQuery(&rpctypes.Context{}, "/store/bank/key", nil, 0, false)
+	require.NoError(t, err)
+}
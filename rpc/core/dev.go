@@ -10,3 +10,20 @@ func (env *Environment) UnsafeFlushMempool(*rpctypes.Context) (*ctypes.ResultUns
 	env.Mempool.Flush()
 	return &ctypes.ResultUnsafeFlushMempool{}, nil
 }
+
+// UnsafeSubscriptions lists the active event subscriptions, e.g. to find the
+// clients of a stuck RPC server which don't consume their events. WebSocket
+// subscribers are identified by their remote address.
+func (env *Environment) UnsafeSubscriptions(*rpctypes.Context) (*ctypes.ResultSubscriptions, error) {
+	infos := env.EventBus.Subscriptions()
+	subs := make([]ctypes.SubscriptionInfo, len(infos))
+	for i, info := range infos {
+		subs[i] = ctypes.SubscriptionInfo{
+			Subscriber:     info.ClientID,
+			Query:          info.Query,
+			BufferCapacity: info.BufferCap,
+			BufferLen:      info.BufferLen,
+		}
+	}
+	return &ctypes.ResultSubscriptions{Subscriptions: subs}, nil
+}
@@ -0,0 +1,37 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	rpctypes "github.com/cometbft/cometbft/rpc/jsonrpc/types"
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestUnsafeSubscriptions(t *testing.T) {
+	eventBus := types.NewEventBus()
+	require.NoError(t, eventBus.Start())
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	env := &Environment{EventBus: eventBus}
+
+	res, err := env.UnsafeSubscriptions(&rpctypes.Context{})
+	require.NoError(t, err)
+	require.Empty(t, res.Subscriptions)
+
+	_, err = eventBus.Subscribe(context.Background(), "127.0.0.1:1234", types.EventQueryNewBlock, 10)
+	require.NoError(t, err)
+	res, err = env.UnsafeSubscriptions(&rpctypes.Context{})
+	require.NoError(t, err)
+	require.Equal(t, []ctypes.SubscriptionInfo{{
+		Subscriber:     "127.0.0.1:1234",
+		Query:          types.EventQueryNewBlock.String(),
+		BufferCapacity: 10,
+	}}, res.Subscriptions)
+}
@@ -1,8 +1,10 @@
 package core
 
 import (
+	"context"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
 	cfg "github.com/cometbft/cometbft/config"
@@ -30,6 +32,10 @@ const (
 	// genesisChunkSize is the maximum size, in bytes, of each
 	// chunk in the genesis structure for the chunked API
 	genesisChunkSize = 16 * 1024 * 1024 // 16
+
+	// queryConnPrefix marks an abci_query path as targeting a registered
+	// query connection (see RegisterQueryConn).
+	queryConnPrefix = "@"
 )
 
 //----------------------------------------------
@@ -92,8 +98,37 @@ type Environment struct {
 
 	Config cfg.RPCConfig
 
+	// ABCIQueryTimeout bounds calls made through ProxyAppQuery and the
+	// registered query connections (abci_query and abci_info). Zero means no
+	// timeout; the call is still canceled if the client goes away.
+	ABCIQueryTimeout time.Duration
+
+	// ABCIInfoCacheTTL is how long abci_info serves the application's last
+	// Info response before querying it again. Zero disables the cache. The
+	// cache is also invalidated on every new block (see InitABCIInfoCache).
+	ABCIInfoCacheTTL time.Duration
+
+	// MaxQueryResponseBytes caps the size of the value and proof of the
+	// responses to abci_query; larger responses are replaced by an error.
+	// Zero means no limit.
+	MaxQueryResponseBytes int64
+
+	// Metrics are discarded if not set.
+	Metrics *Metrics
+
+	// ABCIQueryMetricsPaths are the abci_query path prefixes reported in the
+	// path label of Metrics.ABCIQueryDurationSeconds; other paths are
+	// reported as "other".
+	ABCIQueryMetricsPaths []string
+
+	// query connections registered with RegisterQueryConn, by ID.
+	queryConns map[string]proxy.AppConnQuery
+
 	// cache of chunked genesis data.
 	genChunks []string
+
+	// cache of the application's Info response.
+	infoCache abciInfoCache
 }
 
 //----------------------------------------------
@@ -162,6 +197,75 @@ func (env *Environment) InitGenesisChunks() error {
 	return nil
 }
 
+// RegisterQueryConn registers an additional connection to the application's
+// query interface under the given ID. An abci_query whose path is prefixed by
+// "@<id>" (for example "@replica1/store/bank/key") is sent over that
+// connection, with the prefix stripped; queries without the prefix keep using
+// ProxyAppQuery. There is no fallback: a query for an ID that is not
+// registered fails, and so does a query whose connection returns an error.
+//
+// IDs must be unique and must not contain "/". RegisterQueryConn must be
+// called before the RPC server is started.
+func (env *Environment) RegisterQueryConn(id string, conn proxy.AppConnQuery) error {
+	if id == "" || strings.Contains(id, "/") {
+		return fmt.Errorf("invalid query connection ID %q", id)
+	}
+	if conn == nil {
+		return fmt.Errorf("query connection %q is nil", id)
+	}
+	if _, ok := env.queryConns[id]; ok {
+		return fmt.Errorf("query connection %q is already registered", id)
+	}
+	if env.queryConns == nil {
+		env.queryConns = make(map[string]proxy.AppConnQuery)
+	}
+	env.queryConns[id] = conn
+	return nil
+}
+
+// queryConn returns the connection an abci_query for path should be sent
+// over, along with the path to send. See RegisterQueryConn.
+func (env *Environment) queryConn(path string) (proxy.AppConnQuery, string, error) {
+	if !strings.HasPrefix(path, queryConnPrefix) {
+		return env.ProxyAppQuery, path, nil
+	}
+	id, rest, _ := strings.Cut(path[len(queryConnPrefix):], "/")
+	conn, ok := env.queryConns[id]
+	if !ok {
+		return nil, "", fmt.Errorf("unknown query connection %q", id)
+	}
+	return conn, "/" + rest, nil
+}
+
+// InitABCIInfoCache subscribes to new blocks to invalidate the cache of the
+// application's Info response, if ABCIInfoCacheTTL is set. It should be
+// called on service startup, once the EventBus is started.
+func (env *Environment) InitABCIInfoCache() error {
+	if env.ABCIInfoCacheTTL <= 0 || env.EventBus == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), SubscribeTimeout)
+	defer cancel()
+	// each environment needs its own subscription
+	subscriber := fmt.Sprintf("%s-%p", abciInfoCacheSubscriber, env)
+	sub, err := env.EventBus.Subscribe(ctx, subscriber, types.EventQueryNewBlock, abciInfoCacheCapacity)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to new blocks: %w", err)
+	}
+	go func() {
+		for {
+			select {
+			case <-sub.Out():
+				env.infoCache.invalidate()
+			case <-sub.Canceled():
+				return
+			}
+		}
+	}()
+	return nil
+}
+
 func validateSkipCount(page, perPage int) int {
 	skipCount := (page - 1) * perPage
 	if skipCount < 0 {
@@ -3,12 +3,20 @@ package core
 import (
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/cometbft/cometbft/evidence"
+	cmtmath "github.com/cometbft/cometbft/libs/math"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 	rpctypes "github.com/cometbft/cometbft/rpc/jsonrpc/types"
 	"github.com/cometbft/cometbft/types"
 )
 
+// defaultPendingEvidenceMaxBytes is the maximum size of the page of evidence
+// returned by PendingEvidence when no max_bytes is given.
+const defaultPendingEvidenceMaxBytes int64 = 1024 * 1024 // 1MB
+
 // BroadcastEvidence broadcasts evidence of the misbehavior.
 // More: https://docs.cometbft.com/v0.38/spec/rpc/#broadcastevidence
 func (env *Environment) BroadcastEvidence(
@@ -28,3 +36,108 @@ func (env *Environment) BroadcastEvidence(
 	}
 	return &ctypes.ResultBroadcastEvidence{Hash: ev.Hash()}, nil
 }
+
+// conflictingVotesReporter is implemented by evidence pools which form
+// evidence from conflicting votes, such as evidence.Pool.
+type conflictingVotesReporter interface {
+	ReportConflictingVotes(voteA, voteB *types.Vote)
+}
+
+// ReportConflictingVotes reports two conflicting votes from the same
+// validator, e.g. observed by a monitoring tool, to the evidence pool, which
+// forms DuplicateVoteEvidence from them once their height is committed. The
+// votes must be for the same height, round and type but for different blocks,
+// and be signed by a validator of that height.
+func (env *Environment) ReportConflictingVotes(
+	_ *rpctypes.Context,
+	voteA, voteB *cmtproto.Vote,
+) (*ctypes.ResultReportConflictingVotes, error) {
+	reporter, ok := env.EvidencePool.(conflictingVotesReporter)
+	if !ok {
+		return nil, errors.New("the evidence pool does not accept conflicting votes")
+	}
+	if voteA == nil || voteB == nil {
+		return nil, errors.New("two votes must be provided")
+	}
+	a, err := types.VoteFromProto(voteA)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vote_a: %w", err)
+	}
+	b, err := types.VoteFromProto(voteB)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vote_b: %w", err)
+	}
+	if err := a.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("invalid vote_a: %w", err)
+	}
+	if err := b.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("invalid vote_b: %w", err)
+	}
+	if a.ValidatorIndex != b.ValidatorIndex {
+		return nil, fmt.Errorf("validator indexes do not match: %d vs %d", a.ValidatorIndex, b.ValidatorIndex)
+	}
+
+	valSet, err := env.StateStore.LoadValidators(a.Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the validators of height %d: %w", a.Height, err)
+	}
+	// the block time is only needed to commit the evidence, which the pool
+	// forms on its own
+	ev, err := types.NewDuplicateVoteEvidence(a, b, time.Time{}, valSet)
+	if err != nil {
+		return nil, fmt.Errorf("votes are not conflicting: %w", err)
+	}
+	if err := evidence.VerifyDuplicateVote(ev, env.GenDoc.ChainID, valSet); err != nil {
+		return nil, fmt.Errorf("votes are not conflicting: %w", err)
+	}
+
+	reporter.ReportConflictingVotes(a, b)
+	return &ctypes.ResultReportConflictingVotes{}, nil
+}
+
+// PendingEvidence returns a page of the evidence in the evidence pool that
+// has been verified but not yet committed, from oldest to newest. The page is
+// further cut to maxBytes (default 1MB).
+func (env *Environment) PendingEvidence(
+	_ *rpctypes.Context,
+	maxBytesPtr *int64,
+	pagePtr, perPagePtr *int,
+) (*ctypes.ResultPendingEvidence, error) {
+	maxBytes := defaultPendingEvidenceMaxBytes
+	if maxBytesPtr != nil {
+		if *maxBytesPtr <= 0 {
+			return nil, fmt.Errorf("max_bytes must be positive, given %d", *maxBytesPtr)
+		}
+		maxBytes = *maxBytesPtr
+	}
+
+	pending, _ := env.EvidencePool.PendingEvidence(-1)
+	totalCount := len(pending)
+	perPage := env.validatePerPage(perPagePtr)
+	page, err := validatePage(pagePtr, perPage, totalCount)
+	if err != nil {
+		return nil, err
+	}
+	skipCount := validateSkipCount(page, perPage)
+	pageSize := cmtmath.MinInt(perPage, totalCount-skipCount)
+
+	var (
+		evidence = make([]types.Evidence, 0, pageSize)
+		size     int64
+		evList   cmtproto.EvidenceList // used for calculating the bytes size
+	)
+	for _, ev := range pending[skipCount : skipCount+pageSize] {
+		evpb, err := types.EvidenceToProto(ev)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode evidence %X: %w", ev.Hash(), err)
+		}
+		evList.Evidence = append(evList.Evidence, *evpb)
+		evSize := int64(evList.Size())
+		if evSize > maxBytes {
+			break
+		}
+		size = evSize
+		evidence = append(evidence, ev)
+	}
+	return &ctypes.ResultPendingEvidence{Evidence: evidence, Size: size, TotalCount: totalCount}, nil
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	rpctypes "github.com/cometbft/cometbft/rpc/jsonrpc/types"
+	"github.com/cometbft/cometbft/state/mocks"
+	"github.com/cometbft/cometbft/types"
+)
+
+func TestPendingEvidence(t *testing.T) {
+	ev, err := types.NewMockDuplicateVoteEvidence(1, time.Now(), "test-chain")
+	require.NoError(t, err)
+
+	evpool := &mocks.EvidencePool{}
+	evpool.On("PendingEvidence", int64(-1)).Return([]types.Evidence{ev}, int64(100))
+	env := &Environment{EvidencePool: evpool}
+
+	res, err := env.PendingEvidence(&rpctypes.Context{}, nil, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, []types.Evidence{ev}, res.Evidence)
+	require.Equal(t, 1, res.TotalCount)
+	require.Positive(t, res.Size)
+
+	maxBytes := int64(10)
+	res, err = env.PendingEvidence(&rpctypes.Context{}, &maxBytes, nil, nil)
+	require.NoError(t, err)
+	require.Empty(t, res.Evidence)
+	require.NotNil(t, res.Evidence)
+	require.Zero(t, res.Size)
+	require.Equal(t, 1, res.TotalCount)
+
+	maxBytes = 0
+	_, err = env.PendingEvidence(&rpctypes.Context{}, &maxBytes, nil, nil)
+	require.Error(t, err)
+}
+
+func TestPendingEvidencePagination(t *testing.T) {
+	const numEvidence = 2*maxPerPage + 5
+	pending := make([]types.Evidence, numEvidence)
+	for i := range pending {
+		ev, err := types.NewMockDuplicateVoteEvidence(int64(i+1), time.Now(), "test-chain")
+		require.NoError(t, err)
+		pending[i] = ev
+	}
+
+	evpool := &mocks.EvidencePool{}
+	evpool.On("PendingEvidence", int64(-1)).Return(pending, int64(0))
+	env := &Environment{EvidencePool: evpool}
+
+	testCases := []struct {
+		name    string
+		page    *int
+		perPage *int
+		want    []types.Evidence
+		wantErr bool
+	}{
+		{"defaults", nil, nil, pending[:defaultPerPage], false},
+		{"first page", intPtr(1), intPtr(10), pending[:10], false},
+		{"middle page", intPtr(2), intPtr(10), pending[10:20], false},
+		{"last full page", intPtr(2), intPtr(maxPerPage), pending[maxPerPage : 2*maxPerPage], false},
+		{"last partial page", intPtr(3), intPtr(maxPerPage), pending[2*maxPerPage:], false},
+		{"per_page clamped", intPtr(3), intPtr(maxPerPage + 1), pending[2*maxPerPage:], false},
+		{"invalid per_page", intPtr(1), intPtr(0), pending[:defaultPerPage], false},
+		{"page past the end", intPtr(4), intPtr(maxPerPage), nil, true},
+		{"page zero", intPtr(0), intPtr(10), nil, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := env.PendingEvidence(&rpctypes.Context{}, nil, tc.page, tc.perPage)
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, res.Evidence)
+			require.Equal(t, numEvidence, res.TotalCount)
+		})
+	}
+
+	// an empty pool has a single, empty, page
+	evpool = &mocks.EvidencePool{}
+	evpool.On("PendingEvidence", int64(-1)).Return([]types.Evidence{}, int64(0))
+	env.EvidencePool = evpool
+
+	res, err := env.PendingEvidence(&rpctypes.Context{}, nil, intPtr(1), nil)
+	require.NoError(t, err)
+	require.Empty(t, res.Evidence)
+	require.Zero(t, res.TotalCount)
+	_, err = env.PendingEvidence(&rpctypes.Context{}, nil, intPtr(2), nil)
+	require.Error(t, err)
+}
+
+// reportingEvidencePool records the votes reported to it.
+type reportingEvidencePool struct {
+	*mocks.EvidencePool
+	reported [][2]*types.Vote
+}
+
+func (p *reportingEvidencePool) ReportConflictingVotes(voteA, voteB *types.Vote) {
+	p.reported = append(p.reported, [2]*types.Vote{voteA, voteB})
+}
+
+func TestReportConflictingVotes(t *testing.T) {
+	const chainID = "test-chain"
+	pv := types.NewMockPV()
+	ev, err := types.NewMockDuplicateVoteEvidenceWithValidator(10, time.Now(), pv, chainID)
+	require.NoError(t, err)
+	otherEv, err := types.NewMockDuplicateVoteEvidenceWithValidator(11, time.Now(), pv, chainID)
+	require.NoError(t, err)
+	pubKey, err := pv.GetPubKey()
+	require.NoError(t, err)
+	valSet := types.NewValidatorSet([]*types.Validator{types.NewValidator(pubKey, 10)})
+	otherValSet, _ := types.RandValidatorSet(1, 10)
+
+	badSig := ev.VoteB.ToProto()
+	badSig.Signature = bytes.Clone(badSig.Signature)
+	badSig.Signature[0] ^= 0xff
+
+	testCases := []struct {
+		name         string
+		voteA, voteB *cmtproto.Vote
+		valSet       *types.ValidatorSet
+		wantErr      bool
+	}{
+		{"conflicting votes", ev.VoteA.ToProto(), ev.VoteB.ToProto(), valSet, false},
+		{"missing vote", ev.VoteA.ToProto(), nil, valSet, true},
+		{"same vote", ev.VoteA.ToProto(), ev.VoteA.ToProto(), valSet, true},
+		{"different heights", ev.VoteA.ToProto(), otherEv.VoteB.ToProto(), valSet, true},
+		{"invalid signature", ev.VoteA.ToProto(), badSig, valSet, true},
+		{"unknown validator", ev.VoteA.ToProto(), ev.VoteB.ToProto(), otherValSet, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stateStore := &mocks.Store{}
+			stateStore.On("LoadValidators", ev.Height()).Return(tc.valSet, nil)
+			evpool := &reportingEvidencePool{EvidencePool: &mocks.EvidencePool{}}
+			env := &Environment{
+				EvidencePool: evpool,
+				StateStore:   stateStore,
+				GenDoc:       &types.GenesisDoc{ChainID: chainID},
+			}
+
+			_, err := env.ReportConflictingVotes(&rpctypes.Context{}, tc.voteA, tc.voteB)
+			if tc.wantErr {
+				require.Error(t, err)
+				require.Empty(t, evpool.reported)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, evpool.reported, 1)
+			require.Equal(t, ev.VoteA.BlockID, evpool.reported[0][0].BlockID)
+			require.Equal(t, ev.VoteB.BlockID, evpool.reported[0][1].BlockID)
+		})
+	}
+
+	// pools which don't form evidence from votes reject them
+	env := &Environment{EvidencePool: &mocks.EvidencePool{}}
+	_, err = env.ReportConflictingVotes(&rpctypes.Context{}, ev.VoteA.ToProto(), ev.VoteB.ToProto())
+	require.Error(t, err)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,53 @@
+package core
+
+import (
+	"strings"
+
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	// MetricsSubsystem is a subsystem shared by all metrics exposed by this
+	// package.
+	MetricsSubsystem = "rpc"
+
+	// otherQueryPath is the path label of the abci_query calls whose path is
+	// not in ABCIQueryMetricsPaths.
+	otherQueryPath = "other"
+)
+
+//go:generate go run ../../scripts/metricsgen -struct=Metrics
+
+// Metrics contains the metrics exposed by the RPC core.
+type Metrics struct {
+	// Time spent by the application answering abci_query, by query path.
+	// Paths are reported as the prefix matching them in the node's
+	// abci_query_metrics_paths, or as other.
+	ABCIQueryDurationSeconds metrics.Histogram `metrics_name:"abci_query_duration_seconds" metrics_bucketsizes:".0001,.0004,.002,.009,.02,.1,.65,2,6,25" metrics_labels:"path"`
+	// Time spent by the application answering abci_info.
+	ABCIInfoDurationSeconds metrics.Histogram `metrics_name:"abci_info_duration_seconds" metrics_bucketsizes:".0001,.0004,.002,.009,.02,.1,.65,2,6,25"`
+}
+
+var nopMetrics = NopMetrics()
+
+// metrics returns env.Metrics, or metrics which are discarded if it is not
+// set.
+func (env *Environment) metrics() *Metrics {
+	if env.Metrics == nil {
+		return nopMetrics
+	}
+	return env.Metrics
+}
+
+// queryPathLabel returns the path label of an abci_query for path: the
+// longest prefix of path in ABCIQueryMetricsPaths, or otherQueryPath. This
+// keeps the cardinality of the label bounded whatever the queried paths.
+func (env *Environment) queryPathLabel(path string) string {
+	label := otherQueryPath
+	for _, prefix := range env.ABCIQueryMetricsPaths {
+		if strings.HasPrefix(path, prefix) && (label == otherQueryPath || len(prefix) > len(label)) {
+			label = prefix
+		}
+	}
+	return label
+}
@@ -46,11 +46,16 @@ func (env *Environment) GetRoutes() RoutesMap {
 		"broadcast_tx_async":  rpc.NewRPCFunc(env.BroadcastTxAsync, "tx"),
 
 		// abci API
-		"abci_query": rpc.NewRPCFunc(env.ABCIQuery, "path,data,height,prove"),
-		"abci_info":  rpc.NewRPCFunc(env.ABCIInfo, "", rpc.Cacheable()),
+		"abci_query":           rpc.NewRPCFunc(env.ABCIQuery, "path,data,height,prove"),
+		"abci_query_verified":  rpc.NewRPCFunc(env.ABCIQueryVerified, "path,data,height,prove"),
+		"abci_query_by_hash":   rpc.NewRPCFunc(env.ABCIQueryByHash, "path,data,hash,prove"),
+		"abci_query_at_height": rpc.NewRPCFunc(env.ABCIQueryAtHeight, "paths,datas,height"),
+		"abci_info":            rpc.NewRPCFunc(env.ABCIInfo, "", rpc.Cacheable()),
 
 		// evidence API
-		"broadcast_evidence": rpc.NewRPCFunc(env.BroadcastEvidence, "evidence"),
+		"broadcast_evidence":       rpc.NewRPCFunc(env.BroadcastEvidence, "evidence"),
+		"pending_evidence":         rpc.NewRPCFunc(env.PendingEvidence, "max_bytes,page,per_page"),
+		"report_conflicting_votes": rpc.NewRPCFunc(env.ReportConflictingVotes, "vote_a,vote_b"),
 	}
 }
 
@@ -60,4 +65,5 @@ func (env *Environment) AddUnsafeRoutes(routes RoutesMap) {
 	routes["dial_seeds"] = rpc.NewRPCFunc(env.UnsafeDialSeeds, "seeds")
 	routes["dial_peers"] = rpc.NewRPCFunc(env.UnsafeDialPeers, "peers,persistent,unconditional,private")
 	routes["unsafe_flush_mempool"] = rpc.NewRPCFunc(env.UnsafeFlushMempool, "")
+	routes["unsafe_subscriptions"] = rpc.NewRPCFunc(env.UnsafeSubscriptions, "")
 }
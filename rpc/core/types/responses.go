@@ -227,6 +227,12 @@ type ResultUnconfirmedTxs struct {
 // Info abci msg
 type ResultABCIInfo struct {
 	Response abci.ResponseInfo `json:"response"`
+	// BaseHeight is the height of the first block in the node's block store,
+	// or 0 if the store is empty.
+	BaseHeight int64 `json:"base_height"`
+	// EarliestQueryableHeight is the lowest height abci_query accepts. Queries
+	// at lower heights fail because the node pruned the blocks.
+	EarliestQueryableHeight int64 `json:"earliest_queryable_height"`
 }
 
 // Query abci msg
@@ -234,11 +240,47 @@ type ResultABCIQuery struct {
 	Response abci.ResponseQuery `json:"response"`
 }
 
+// Result of abci queries made at the same height
+type ResultABCIQueryAtHeight struct {
+	Height    int64                `json:"height"`
+	Responses []abci.ResponseQuery `json:"responses"`
+}
+
+// Result of an abci query whose proof was checked against the committed app
+// hash. Verified is false if no proof was requested.
+type ResultABCIQueryVerified struct {
+	Response abci.ResponseQuery `json:"response"`
+	Verified bool               `json:"verified"`
+}
+
 // Result of broadcasting evidence
 type ResultBroadcastEvidence struct {
 	Hash []byte `json:"hash"`
 }
 
+// A page of the pending evidence in the evidence pool
+type ResultPendingEvidence struct {
+	Evidence []types.Evidence `json:"evidence"`
+	// total size of the evidence in bytes
+	Size int64 `json:"size"`
+	// number of pending evidence in the pool
+	TotalCount int `json:"total_count"`
+}
+
+// SubscriptionInfo describes an active event subscription.
+type SubscriptionInfo struct {
+	Subscriber string `json:"subscriber"`
+	Query      string `json:"query"`
+	// capacity of the subscription's buffer, and number of events in it
+	BufferCapacity int `json:"buffer_capacity"`
+	BufferLen      int `json:"buffer_len"`
+}
+
+// List of the active event subscriptions
+type ResultSubscriptions struct {
+	Subscriptions []SubscriptionInfo `json:"subscriptions"`
+}
+
 // empty results
 type (
 	ResultUnsafeFlushMempool struct{}
@@ -246,6 +288,10 @@ type (
 	ResultSubscribe          struct{}
 	ResultUnsubscribe        struct{}
 	ResultHealth             struct{}
+
+	// ResultReportConflictingVotes is the result of reporting conflicting
+	// votes to the evidence pool.
+	ResultReportConflictingVotes struct{}
 )
 
 // Event data from a subscription
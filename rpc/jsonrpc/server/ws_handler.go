@@ -385,7 +385,7 @@ func (wsc *wsConnection) readRoutine() {
 
 			result, err := unreflectResult(returns)
 			if err != nil {
-				if err := wsc.WriteRPCResponse(writeCtx, types.RPCInternalError(request.ID, err)); err != nil {
+				if err := wsc.WriteRPCResponse(writeCtx, types.RPCFuncError(request.ID, err)); err != nil {
 					wsc.Logger.Error("Error writing RPC response", "err", err)
 				}
 				continue
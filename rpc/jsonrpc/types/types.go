@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -137,6 +138,20 @@ func ArrayToRequest(id jsonrpcid, method string, params []any) (RPCRequest, erro
 //----------------------------------------
 // RESPONSE
 
+// Error codes of server errors, in the range (-32000 to -32099) reserved by
+// JSON-RPC 2.0 for implementation-defined errors. RPC functions return an
+// *RPCError with one of these codes when the client may want to act on it.
+const (
+	// CodeServerError is the code of a generic server error.
+	CodeServerError = -32000
+	// CodeAppUnavailable is the code of an error reaching the ABCI
+	// application. The request may succeed if retried.
+	CodeAppUnavailable = -32001
+	// CodeHeightNotAvailable is the code of an error for a height the node
+	// has pruned. The request should be sent to another node.
+	CodeHeightNotAvailable = -32002
+)
+
 type RPCError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
@@ -243,7 +258,18 @@ func RPCInternalError(id jsonrpcid, err error) RPCResponse {
 }
 
 func RPCServerError(id jsonrpcid, err error) RPCResponse {
-	return NewRPCErrorResponse(id, -32000, "Server error", err.Error())
+	return NewRPCErrorResponse(id, CodeServerError, "Server error", err.Error())
+}
+
+// RPCFuncError returns the response for an error returned by an RPC function.
+// An *RPCError is sent to the client as is, with its code; any other error is
+// sent as an internal error.
+func RPCFuncError(id jsonrpcid, err error) RPCResponse {
+	var rpcErr *RPCError
+	if errors.As(err, &rpcErr) {
+		return NewRPCErrorResponse(id, rpcErr.Code, rpcErr.Message, rpcErr.Data)
+	}
+	return RPCInternalError(id, err)
 }
 
 //----------------------------------------
@@ -81,3 +81,21 @@ func TestRPCError(t *testing.T) {
 			Message: "Badness",
 		}))
 }
+
+func TestRPCFuncError(t *testing.T) {
+	id := JSONRPCIntID(1)
+
+	res := RPCFuncError(id, fmt.Errorf("query: %w", &RPCError{
+		Code:    CodeAppUnavailable,
+		Message: "Application unavailable",
+		Data:    "connection refused",
+	}))
+	assert.Equal(t, &RPCError{
+		Code:    CodeAppUnavailable,
+		Message: "Application unavailable",
+		Data:    "connection refused",
+	}, res.Error)
+
+	res = RPCFuncError(id, errors.New("hello world"))
+	assert.Equal(t, &RPCError{Code: -32603, Message: "Internal error", Data: "hello world"}, res.Error)
+}
@@ -19,9 +19,12 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
+
+	"github.com/pmezard/go-difflib/difflib"
 )
 
 func init() {
@@ -29,8 +32,13 @@ func init() {
 		fmt.Fprintf(os.Stderr, `Usage: %[1]s  -struct <struct>
 
 Generate constructors for the metrics type specified by -struct contained in
-the current directory. The tool creates a new file in the current directory
-containing the generated code.
+the directory specified by -dir (the current directory by default). The tool
+creates a new file in the same directory containing the generated code. With
+-gen-test, it also creates a test checking that the generated constructors set
+every field of the struct. With -docs, it also writes a Markdown table
+documenting the metrics to the given file. With -check, nothing is written: the
+tool exits with an error, printing a diff, if the generated files are not up to
+date.
 
 Options:
 `, filepath.Base(os.Args[0]))
@@ -41,15 +49,27 @@ Options:
 const metricsPackageName = "github.com/go-kit/kit/metrics"
 
 const (
-	metricNameTag = "metrics_name"
-	labelsTag     = "metrics_labels"
-	bucketTypeTag = "metrics_buckettype"
-	bucketSizeTag = "metrics_bucketsizes"
+	metricNameTag  = "metrics_name"
+	labelsTag      = "metrics_labels"
+	constLabelsTag = "metrics_constlabels"
+	unitTag        = "metrics_unit"
+	bucketTypeTag  = "metrics_buckettype"
+	bucketSizeTag  = "metrics_bucketsizes"
+	subsystemTag   = "metrics_subsystem"
+	// skipTag set to "-" excludes a field of a metric type from the
+	// generated constructors, e.g. because it is set manually.
+	skipTag = "metrics"
 )
 
 var (
-	dir   = flag.String("dir", ".", "Path to the directory containing the target package")
-	strct = flag.String("struct", "Metrics", "Struct to parse for metrics")
+	dir     = flag.String("dir", ".", "Path to the directory containing the target package")
+	strct   = flag.String("struct", "Metrics", "Struct to parse for metrics")
+	pkg     = flag.String("package", "", "Package name of the generated file, if different from the package containing the struct")
+	genTest = flag.Bool("gen-test", false, "Also generate metrics_gen_test.go, checking that the generated constructors set "+
+		"every field of the struct")
+	docs  = flag.String("docs", "", "Also write a Markdown table documenting the metrics to this file")
+	check = flag.Bool("check", false, "Instead of writing the generated files, check that they are up to date, "+
+		"printing a diff and exiting with an error if not")
 )
 
 var bucketType = map[string]string{
@@ -77,9 +97,12 @@ func PrometheusMetrics(namespace string, labelsAndValues...string) *Metrics {
 		{{ range $metric := .ParsedMetrics }}
 		{{- $metric.FieldName }}: prometheus.New{{ $metric.TypeName }}From(stdprometheus.{{$metric.TypeName }}Opts{
 			Namespace: namespace,
-			Subsystem: MetricsSubsystem,
+			Subsystem: {{ if ne $metric.Subsystem "" }}{{ printf "%q" $metric.Subsystem }}{{ else }}MetricsSubsystem{{ end }},
 			Name:      "{{$metric.MetricName }}",
-			Help:      "{{ $metric.Description }}",
+			Help:      "{{ $metric.Help }}",
+			{{ if ne $metric.ConstLabels "" }}
+			ConstLabels: stdprometheus.Labels{ {{ $metric.ConstLabels }} },
+			{{ end }}
 			{{ if ne $metric.HistogramOptions.BucketType "" }}
 			Buckets: {{ $metric.HistogramOptions.BucketType }}({{ $metric.HistogramOptions.BucketSizes }}),
 			{{ else if ne $metric.HistogramOptions.BucketSizes "" }}
@@ -104,6 +127,53 @@ func NopMetrics() *Metrics {
 }
 `))
 
+// testTmpl is the template of the test generated with -gen-test. A field of
+// the struct which is not of a metric type recognized by metricsgen is left
+// nil by the constructors, which the test catches.
+var testTmpl = template.Must(template.New("testTmpl").Parse(`// Code generated by metricsgen. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"reflect"
+	"testing"
+
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+// TestGeneratedMetricsSetAllFields checks that PrometheusMetrics and
+// NopMetrics set every field of Metrics, except those tagged metrics:"-".
+// It is skipped in short mode.
+func TestGeneratedMetricsSetAllFields(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping generated metrics check in short mode")
+	}
+	// PrometheusMetrics registers the metrics with the default registerer,
+	// which is replaced by a throwaway registry for the test.
+	defaultRegisterer := stdprometheus.DefaultRegisterer
+	stdprometheus.DefaultRegisterer = stdprometheus.NewRegistry()
+	t.Cleanup(func() { stdprometheus.DefaultRegisterer = defaultRegisterer })
+
+	for name, m := range map[string]*Metrics{
+		"PrometheusMetrics": PrometheusMetrics("metricsgen_test"),
+		"NopMetrics":        NopMetrics(),
+	} {
+		v := reflect.ValueOf(m).Elem()
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Tag.Get("metrics") == "-" {
+				continue
+			}
+			switch f := v.Field(i); f.Kind() {
+			case reflect.Interface, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+				if f.IsNil() {
+					t.Errorf("%s did not set field %s", name, v.Type().Field(i).Name)
+				}
+			}
+		}
+	}
+}
+`))
+
 // ParsedMetricField is the data parsed for a single field of a metric struct.
 type ParsedMetricField struct {
 	TypeName    string
@@ -111,10 +181,27 @@ type ParsedMetricField struct {
 	MetricName  string
 	Description string
 	Labels      string
+	// ConstLabels holds the key-value pairs of the constant labels as Go
+	// source, e.g. `"version": "1.0"`.
+	ConstLabels string
+	// Unit is the unit of the metric, e.g. "seconds" or "bytes".
+	Unit string
+	// Subsystem overrides the MetricsSubsystem of the package for this
+	// metric, if not empty.
+	Subsystem string
 
 	HistogramOptions HistogramOpts
 }
 
+// Help returns the help text of the metric, which is its description followed
+// by its unit, if any. The unit is omitted if there is no description.
+func (pmf ParsedMetricField) Help() string {
+	if pmf.Unit == "" || pmf.Description == "" {
+		return pmf.Description
+	}
+	return fmt.Sprintf("%s (unit: %s)", pmf.Description, pmf.Unit)
+}
+
 type HistogramOpts struct {
 	BucketType  string
 	BucketSizes string
@@ -131,11 +218,25 @@ func main() {
 	if *strct == "" {
 		log.Fatal("You must specify a non-empty -struct")
 	}
-	td, err := ParseMetricsDir(".", *strct)
+	td, err := ParseMetricsDirWithPackage(*dir, *strct, *pkg)
 	if err != nil {
 		log.Fatalf("Parsing file: %v", err)
 	}
 	out := filepath.Join(*dir, "metrics.gen.go")
+	testOut := filepath.Join(*dir, "metrics_gen_test.go")
+	if *check {
+		upToDate := checkFile(out, GenerateMetricsFile, td)
+		if *genTest {
+			upToDate = checkFile(testOut, GenerateMetricsTestFile, td) && upToDate
+		}
+		if *docs != "" {
+			upToDate = checkFile(*docs, GenerateMetricsDocs, td) && upToDate
+		}
+		if !upToDate {
+			os.Exit(1)
+		}
+		return
+	}
 	f, err := os.Create(out)
 	if err != nil {
 		log.Fatalf("Opening file: %v", err)
@@ -144,12 +245,88 @@ func main() {
 	if err != nil {
 		log.Fatalf("Generating code: %v", err)
 	}
+	if *genTest {
+		f, err := os.Create(testOut)
+		if err != nil {
+			log.Fatalf("Opening file: %v", err)
+		}
+		err = GenerateMetricsTestFile(f, td)
+		if err != nil {
+			log.Fatalf("Generating test code: %v", err)
+		}
+	}
+	if *docs != "" {
+		f, err := os.Create(*docs)
+		if err != nil {
+			log.Fatalf("Opening file: %v", err)
+		}
+		err = GenerateMetricsDocs(f, td)
+		if err != nil {
+			log.Fatalf("Generating docs: %v", err)
+		}
+	}
+}
+
+// checkFile reports whether the file is up to date, printing the diff to
+// stderr if not.
+func checkFile(path string, generate func(io.Writer, TemplateData) error, td TemplateData) bool {
+	diff, err := DiffGeneratedFile(path, generate, td)
+	if err != nil {
+		log.Fatalf("Checking %s: %v", path, err)
+	}
+	if diff == "" {
+		return true
+	}
+	fmt.Fprintf(os.Stderr, "%s is not up to date, run metricsgen to regenerate it:\n%s", path, diff)
+	return false
+}
+
+// DiffGeneratedFile generates code with generate into memory and compares it
+// with the file at path, which is not modified. It returns a unified diff
+// from the file to the generated code, or an empty string if they are the
+// same. A missing file is compared as an empty one.
+func DiffGeneratedFile(path string, generate func(io.Writer, TemplateData) error, td TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := generate(&buf, td); err != nil {
+		return "", err
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	if bytes.Equal(existing, buf.Bytes()) {
+		return "", nil
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(existing)),
+		B:        difflib.SplitLines(buf.String()),
+		FromFile: path,
+		ToFile:   path + " (generated)",
+		Context:  3,
+	})
 }
 
 func ignoreTestFiles(f fs.FileInfo) bool {
 	return !strings.Contains(f.Name(), "_test.go")
 }
 
+// ParseMetricsDirWithPackage is like ParseMetricsDir, but if pkg is not empty,
+// the generated file is declared in package pkg instead of the package
+// containing the struct.
+func ParseMetricsDirWithPackage(dir, structName, pkg string) (TemplateData, error) {
+	if pkg != "" && !token.IsIdentifier(pkg) {
+		return TemplateData{}, fmt.Errorf("invalid package name %q: not a valid Go identifier", pkg)
+	}
+	td, err := ParseMetricsDir(dir, structName)
+	if err != nil {
+		return TemplateData{}, err
+	}
+	if pkg != "" {
+		td.Package = pkg
+	}
+	return td, nil
+}
+
 // ParseMetricsDir parses the dir and scans for a struct matching structName,
 // ignoring all test files. ParseMetricsDir iterates the fields of the metrics
 // struct and builds a TemplateData using the data obtained from the abstract syntax tree.
@@ -182,10 +359,13 @@ func ParseMetricsDir(dir, structName string) (TemplateData, error) {
 		return TemplateData{}, err
 	}
 	for _, f := range m.Fields.List {
-		if !isMetric(f.Type, mPkgName) {
+		if !isMetric(f.Type, mPkgName) || isSkipped(f.Tag) {
 			continue
 		}
-		pmf := parseMetricField(f)
+		pmf, err := parseMetricField(f)
+		if err != nil {
+			return TemplateData{}, err
+		}
 		td.ParsedMetrics = append(td.ParsedMetrics, pmf)
 	}
 
@@ -195,9 +375,65 @@ func ParseMetricsDir(dir, structName string) (TemplateData, error) {
 // GenerateMetricsFile executes the metrics file template, writing the result
 // into the io.Writer.
 func GenerateMetricsFile(w io.Writer, td TemplateData) error {
+	return generateFile(w, tmpl, td)
+}
+
+// GenerateMetricsTestFile executes the template of the test checking the
+// constructors written by GenerateMetricsFile, writing the result into the
+// io.Writer.
+func GenerateMetricsTestFile(w io.Writer, td TemplateData) error {
+	return generateFile(w, testTmpl, td)
+}
+
+// GenerateMetricsDocs writes into the io.Writer a Markdown table with the
+// name, type, labels and help text of each metric, ordered by field name.
+func GenerateMetricsDocs(w io.Writer, td TemplateData) error {
+	metrics := make([]ParsedMetricField, len(td.ParsedMetrics))
+	copy(metrics, td.ParsedMetrics)
+	sort.SliceStable(metrics, func(i, j int) bool {
+		return metrics[i].FieldName < metrics[j].FieldName
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("| Name | Type | Labels | Description |\n")
+	buf.WriteString("|------|------|--------|-------------|\n")
+	for _, m := range metrics {
+		labels, err := docLabels(m.Labels)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", m.FieldName, err)
+		}
+		fmt.Fprintf(&buf, "| %s | %s | %s | %s |\n",
+			escapeTableCell(m.MetricName), m.TypeName, escapeTableCell(labels), escapeTableCell(m.Help()))
+	}
+	_, err := io.Copy(w, &buf)
+	return err
+}
+
+// docLabels turns the labels of a metric, as Go source, into a list of label
+// names for the docs.
+func docLabels(labels string) (string, error) {
+	if labels == "" {
+		return "", nil
+	}
+	var res []string
+	for _, l := range strings.Split(labels, ",") {
+		s, err := strconv.Unquote(l)
+		if err != nil {
+			return "", fmt.Errorf("invalid label %s: %w", l, err)
+		}
+		res = append(res, s)
+	}
+	return strings.Join(res, ", "), nil
+}
+
+func escapeTableCell(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func generateFile(w io.Writer, t *template.Template, td TemplateData) error {
 	b := []byte{}
 	buf := bytes.NewBuffer(b)
-	err := tmpl.Execute(buf, td)
+	err := t.Execute(buf, td)
 	if err != nil {
 		return err
 	}
@@ -249,18 +485,40 @@ func findMetricsStruct(files map[string]*ast.File, structName string) (*ast.Stru
 	return nil, "", fmt.Errorf("target struct %q not found in dir", structName)
 }
 
-func parseMetricField(f *ast.Field) ParsedMetricField {
+func parseMetricField(f *ast.Field) (ParsedMetricField, error) {
+	constLabels, err := extractConstLabels(f.Tag)
+	if err != nil {
+		return ParsedMetricField{}, fmt.Errorf("field %s: %w", f.Names[0].String(), err)
+	}
+	subsystem, err := extractSubsystem(f.Tag)
+	if err != nil {
+		return ParsedMetricField{}, fmt.Errorf("field %s: %w", f.Names[0].String(), err)
+	}
 	pmf := ParsedMetricField{
 		Description: extractHelpMessage(f.Doc),
 		MetricName:  extractFieldName(f.Names[0].String(), f.Tag),
 		FieldName:   f.Names[0].String(),
 		TypeName:    extractTypeName(f.Type),
 		Labels:      extractLabels(f.Tag),
+		ConstLabels: constLabels,
+		Unit:        extractUnit(f.Tag),
+		Subsystem:   subsystem,
 	}
 	if pmf.TypeName == "Histogram" {
 		pmf.HistogramOptions = extractHistogramOptions(f.Tag)
 	}
-	return pmf
+	if pmf.Unit != "" && !hasUnitSuffix(pmf.MetricName, pmf.Unit) {
+		fmt.Fprintf(os.Stderr, "warning: metric %q has unit %q but its name does not end with \"_%s\"\n",
+			pmf.MetricName, pmf.Unit, pmf.Unit)
+	}
+	return pmf, nil
+}
+
+// hasUnitSuffix reports whether the metric name ends with the unit, optionally
+// followed by the "_total" suffix of counters.
+func hasUnitSuffix(name, unit string) bool {
+	suffix := "_" + unit
+	return strings.HasSuffix(name, suffix) || strings.HasSuffix(name, suffix+"_total")
 }
 
 func extractTypeName(e ast.Expr) string {
@@ -286,6 +544,15 @@ func isMetric(e ast.Expr, mPkgName string) bool {
 	return strings.Contains(types.ExprString(e), fmt.Sprintf("%s.", mPkgName))
 }
 
+// isSkipped reports whether the field is excluded with the skip tag.
+func isSkipped(tag *ast.BasicLit) bool {
+	if tag == nil {
+		return false
+	}
+	t := reflect.StructTag(strings.Trim(tag.Value, "`"))
+	return t.Get(skipTag) == "-"
+}
+
 func extractLabels(bl *ast.BasicLit) string {
 	if bl != nil {
 		t := reflect.StructTag(strings.Trim(bl.Value, "`"))
@@ -300,6 +567,52 @@ func extractLabels(bl *ast.BasicLit) string {
 	return ""
 }
 
+// extractConstLabels parses the comma-separated key=value pairs of the
+// constant labels tag into the body of a Go map literal.
+func extractConstLabels(bl *ast.BasicLit) (string, error) {
+	if bl == nil {
+		return "", nil
+	}
+	t := reflect.StructTag(strings.Trim(bl.Value, "`"))
+	v := t.Get(constLabelsTag)
+	if v == "" {
+		return "", nil
+	}
+	var res []string
+	for _, pair := range strings.Split(v, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return "", fmt.Errorf("invalid %s pair %q, expected key=value", constLabelsTag, pair)
+		}
+		res = append(res, fmt.Sprintf("%s: %s", strconv.Quote(key), strconv.Quote(strings.TrimSpace(value))))
+	}
+	return strings.Join(res, ", "), nil
+}
+
+func extractUnit(bl *ast.BasicLit) string {
+	if bl != nil {
+		t := reflect.StructTag(strings.Trim(bl.Value, "`"))
+		return strings.TrimSpace(t.Get(unitTag))
+	}
+	return ""
+}
+
+// subsystemRegexp matches the valid subsystems of a Prometheus metric name.
+var subsystemRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func extractSubsystem(bl *ast.BasicLit) (string, error) {
+	if bl == nil {
+		return "", nil
+	}
+	t := reflect.StructTag(strings.Trim(bl.Value, "`"))
+	v := strings.TrimSpace(t.Get(subsystemTag))
+	if v != "" && !subsystemRegexp.MatchString(v) {
+		return "", fmt.Errorf("invalid %s %q: must match %s", subsystemTag, v, subsystemRegexp)
+	}
+	return v, nil
+}
+
 func extractFieldName(name string, tag *ast.BasicLit) string {
 	if tag != nil {
 		t := reflect.StructTag(strings.Trim(tag.Value, "`"))
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -37,6 +38,136 @@ func TestSimpleTemplate(t *testing.T) {
 	}
 }
 
+func TestConstLabelsTemplate(t *testing.T) {
+	td := metricsgen.TemplateData{
+		Package: "mypack",
+		ParsedMetrics: []metricsgen.ParsedMetricField{{
+			TypeName:    "Gauge",
+			FieldName:   "MyMetric",
+			MetricName:  "my_metric",
+			ConstLabels: `"version": "1.0"`,
+		}},
+	}
+	b := bytes.NewBuffer([]byte{})
+	err := metricsgen.GenerateMetricsFile(b, td)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `ConstLabels: stdprometheus.Labels{"version": "1.0"},`)
+	require.Contains(t, b.String(), "MyMetric: discard.NewGauge(),")
+}
+
+func TestGenerateMetricsTestFile(t *testing.T) {
+	td, err := metricsgen.ParseMetricsDirWithPackage(path.Join(testDataDir, "basic"), "Metrics", "otherpack")
+	require.NoError(t, err)
+	b := bytes.NewBuffer([]byte{})
+	err = metricsgen.GenerateMetricsTestFile(b, td)
+	require.NoError(t, err)
+
+	f, err := parser.ParseFile(token.NewFileSet(), "metrics_gen_test.go", b.Bytes(), parser.AllErrors)
+	require.NoError(t, err)
+	require.Equal(t, "otherpack", f.Name.Name)
+	require.Contains(t, b.String(), "func TestGeneratedMetricsSetAllFields(t *testing.T) {")
+	require.Contains(t, b.String(), `PrometheusMetrics("metricsgen_test")`)
+	require.Contains(t, b.String(), "NopMetrics()")
+}
+
+func TestGenerateMetricsDocs(t *testing.T) {
+	td, err := metricsgen.ParseMetricsDir(path.Join(testDataDir, "tags"), "Metrics")
+	require.NoError(t, err)
+	b := bytes.NewBuffer([]byte{})
+	err = metricsgen.GenerateMetricsDocs(b, td)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	require.Len(t, lines, len(td.ParsedMetrics)+2)
+	require.Equal(t, "| Name | Type | Labels | Description |", lines[0])
+	require.Equal(t, "| duration_seconds | Histogram |  | Duration of the operation. (unit: seconds) |", lines[2])
+	require.Contains(t, lines, "| with_labels | Counter | step, time |  |")
+
+	// the table is ordered by field name, whatever the order of the fields
+	td.ParsedMetrics[0], td.ParsedMetrics[1] = td.ParsedMetrics[1], td.ParsedMetrics[0]
+	b2 := bytes.NewBuffer([]byte{})
+	err = metricsgen.GenerateMetricsDocs(b2, td)
+	require.NoError(t, err)
+	require.Equal(t, b.String(), b2.String())
+}
+
+func TestSubsystemTemplate(t *testing.T) {
+	td := metricsgen.TemplateData{
+		Package: "mypack",
+		ParsedMetrics: []metricsgen.ParsedMetricField{
+			{TypeName: "Gauge", FieldName: "MyMetric", MetricName: "my_metric", Subsystem: "other"},
+			{TypeName: "Counter", FieldName: "OwnMetric", MetricName: "own_metric"},
+		},
+	}
+	b := bytes.NewBuffer([]byte{})
+	err := metricsgen.GenerateMetricsFile(b, td)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `Subsystem: "other",`)
+	require.Contains(t, b.String(), "Subsystem: MetricsSubsystem,")
+}
+
+func TestDiffGeneratedFile(t *testing.T) {
+	dir := path.Join(testDataDir, "basic")
+	td, err := metricsgen.ParseMetricsDir(dir, "Metrics")
+	require.NoError(t, err)
+
+	// the golden file is up to date
+	goldenFile := path.Join(dir, "metrics.gen.go")
+	diff, err := metricsgen.DiffGeneratedFile(goldenFile, metricsgen.GenerateMetricsFile, td)
+	require.NoError(t, err)
+	require.Empty(t, diff)
+
+	// a stale copy of it is not, and is left untouched
+	golden, err := os.ReadFile(goldenFile)
+	require.NoError(t, err)
+	stale := bytes.Replace(golden, []byte("discard.NewGauge()"), []byte("nil"), 1)
+	require.NotEqual(t, golden, stale)
+	staleFile := filepath.Join(t.TempDir(), "metrics.gen.go")
+	require.NoError(t, os.WriteFile(staleFile, stale, 0o600))
+
+	diff, err = metricsgen.DiffGeneratedFile(staleFile, metricsgen.GenerateMetricsFile, td)
+	require.NoError(t, err)
+	require.Contains(t, diff, "-\t\tHeight: nil,")
+	require.Contains(t, diff, "+\t\tHeight: discard.NewGauge(),")
+	bz, err := os.ReadFile(staleFile)
+	require.NoError(t, err)
+	require.Equal(t, stale, bz)
+
+	// a missing file is not up to date either
+	diff, err = metricsgen.DiffGeneratedFile(filepath.Join(t.TempDir(), "metrics.gen.go"), metricsgen.GenerateMetricsFile, td)
+	require.NoError(t, err)
+	require.NotEmpty(t, diff)
+}
+
+func TestHelpWithUnit(t *testing.T) {
+	m := metricsgen.ParsedMetricField{Description: "Time spent.", Unit: "seconds"}
+	require.Equal(t, "Time spent. (unit: seconds)", m.Help())
+
+	// the unit alone is not a help text
+	m.Description = ""
+	require.Empty(t, m.Help())
+
+	m = metricsgen.ParsedMetricField{Description: "Time spent."}
+	require.Equal(t, "Time spent.", m.Help())
+}
+
+func TestParseMetricsDirWithPackage(t *testing.T) {
+	dir := path.Join(testDataDir, "basic")
+
+	td, err := metricsgen.ParseMetricsDirWithPackage(dir, "Metrics", "")
+	require.NoError(t, err)
+	require.Equal(t, "basic", td.Package)
+
+	td, err = metricsgen.ParseMetricsDirWithPackage(dir, "Metrics", "othermetrics")
+	require.NoError(t, err)
+	require.Equal(t, "othermetrics", td.Package)
+
+	for _, pkg := range []string{"1metrics", "other-metrics", "other.metrics"} {
+		_, err = metricsgen.ParseMetricsDirWithPackage(dir, "Metrics", pkg)
+		require.ErrorContains(t, err, "not a valid Go identifier", pkg)
+	}
+}
+
 func TestFromData(t *testing.T) {
 	infos, err := os.ReadDir(testDataDir)
 	if err != nil {
@@ -130,6 +261,47 @@ func TestParseMetricsStruct(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "skipped field",
+			metricsStruct: "type Metrics struct {\n" +
+				"myGauge metrics.Gauge\n" +
+				"manual metrics.Counter `metrics:\"-\"`\n" +
+				"}",
+			expected: metricsgen.TemplateData{
+				Package: pkgName,
+				ParsedMetrics: []metricsgen.ParsedMetricField{
+					{
+						TypeName:   "Gauge",
+						FieldName:  "myGauge",
+						MetricName: "my_gauge",
+					},
+				},
+			},
+		},
+		{
+			name: "subsystem",
+			metricsStruct: "type Metrics struct {\n" +
+				"myGauge metrics.Gauge `metrics_subsystem:\"other\"`\n" +
+				"}",
+			expected: metricsgen.TemplateData{
+				Package: pkgName,
+				ParsedMetrics: []metricsgen.ParsedMetricField{
+					{
+						TypeName:   "Gauge",
+						FieldName:  "myGauge",
+						MetricName: "my_gauge",
+						Subsystem:  "other",
+					},
+				},
+			},
+		},
+		{
+			name: "invalid subsystem",
+			metricsStruct: "type Metrics struct {\n" +
+				"myGauge metrics.Gauge `metrics_subsystem:\"other-one\"`\n" +
+				"}",
+			shouldError: true,
+		},
 		{
 			name: "labeled name",
 			metricsStruct: "type Metrics struct {\n" +
@@ -163,6 +335,47 @@ func TestParseMetricsStruct(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "const labels",
+			metricsStruct: "type Metrics struct {\n" +
+				"myGauge metrics.Gauge `metrics_constlabels:\"version=1.0, network = test\"`\n" +
+				"}",
+			expected: metricsgen.TemplateData{
+				Package: pkgName,
+				ParsedMetrics: []metricsgen.ParsedMetricField{
+					{
+						TypeName:    "Gauge",
+						FieldName:   "myGauge",
+						MetricName:  "my_gauge",
+						ConstLabels: "\"version\": \"1.0\", \"network\": \"test\"",
+					},
+				},
+			},
+		},
+		{
+			name: "unit",
+			metricsStruct: "type Metrics struct {\n" +
+				"myDurationSeconds metrics.Histogram `metrics_unit:\"seconds\"`\n" +
+				"}",
+			expected: metricsgen.TemplateData{
+				Package: pkgName,
+				ParsedMetrics: []metricsgen.ParsedMetricField{
+					{
+						TypeName:   "Histogram",
+						FieldName:  "myDurationSeconds",
+						MetricName: "my_duration_seconds",
+						Unit:       "seconds",
+					},
+				},
+			},
+		},
+		{
+			name:        "malformed const labels",
+			shouldError: true,
+			metricsStruct: "type Metrics struct {\n" +
+				"myGauge metrics.Gauge `metrics_constlabels:\"version\"`\n" +
+				"}",
+		},
 		{
 			name: "ignore non-metric field",
 			metricsStruct: `type Metrics struct {
@@ -217,6 +430,33 @@ func TestParseMetricsStruct(t *testing.T) {
 	}
 }
 
+func TestSkippedMetricNotGenerated(t *testing.T) {
+	data := "package mypkg\n\n" +
+		"import \"github.com/go-kit/kit/metrics\"\n\n" +
+		"type Metrics struct {\n" +
+		"\tKept metrics.Gauge\n" +
+		"\tManual metrics.Counter `metrics:\"-\"`\n" +
+		"}\n"
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "metrics.go"), []byte(data), 0o600)
+	require.NoError(t, err)
+
+	td, err := metricsgen.ParseMetricsDir(dir, "Metrics")
+	require.NoError(t, err)
+	b := bytes.NewBuffer([]byte{})
+	err = metricsgen.GenerateMetricsFile(b, td)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), "Kept:")
+	require.NotContains(t, b.String(), "Manual")
+	require.NotContains(t, b.String(), "manual")
+
+	// the generated test does not expect the skipped field to be set
+	b.Reset()
+	err = metricsgen.GenerateMetricsTestFile(b, td)
+	require.NoError(t, err)
+	require.Contains(t, b.String(), `Tag.Get("metrics") == "-"`)
+}
+
 func TestParseAliasedMetric(t *testing.T) {
 	aliasedData := `
 			package mypkg
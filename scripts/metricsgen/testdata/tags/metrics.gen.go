@@ -42,14 +42,30 @@ func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
 			Name:      "metric_with_name",
 			Help:      "",
 		}, labels).With(labelsAndValues...),
+		WithConstLabels: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "with_const_labels",
+			Help:      "",
+
+			ConstLabels: stdprometheus.Labels{"version": "1.0", "network": "test"},
+		}, labels).With(labelsAndValues...),
+		DurationSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "duration_seconds",
+			Help:      "Duration of the operation. (unit: seconds)",
+		}, labels).With(labelsAndValues...),
 	}
 }
 
 func NopMetrics() *Metrics {
 	return &Metrics{
-		WithLabels:     discard.NewCounter(),
-		WithExpBuckets: discard.NewHistogram(),
-		WithBuckets:    discard.NewHistogram(),
-		Named:          discard.NewCounter(),
+		WithLabels:      discard.NewCounter(),
+		WithExpBuckets:  discard.NewHistogram(),
+		WithBuckets:     discard.NewHistogram(),
+		Named:           discard.NewCounter(),
+		WithConstLabels: discard.NewGauge(),
+		DurationSeconds: discard.NewHistogram(),
 	}
 }
@@ -86,6 +86,66 @@ func (idx *BlockerIndexer) Index(bh types.EventDataNewBlockEvents) error {
 	return batch.WriteSync()
 }
 
+// IndexedEvents returns the events indexed for the heights from start to end
+// (inclusive). For each indexed height, it counts how many times each event
+// attribute was indexed, keyed by "<type>.<key>=<value>". A height indexed
+// without any events maps to an empty map, heights that were not indexed are
+// absent. Event sequence numbers are ignored, as they differ between indexing
+// runs.
+//
+// Event keys are ordered by attribute rather than height, so the whole store
+// is scanned.
+func (idx *BlockerIndexer) IndexedEvents(start, end int64) (map[int64]map[string]int, error) {
+	it, err := idx.store.Iterator(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	events := make(map[int64]map[string]int)
+	add := func(height int64) map[string]int {
+		if events[height] == nil {
+			events[height] = make(map[string]int)
+		}
+		return events[height]
+	}
+	for ; it.Valid(); it.Next() {
+		var compositeKey string
+		if _, err := orderedcode.Parse(string(it.Key()), &compositeKey); err != nil {
+			return nil, fmt.Errorf("failed to parse key %X: %w", it.Key(), err)
+		}
+		if compositeKey == types.BlockHeightKey {
+			v, err := parseValueFromPrimaryKey(it.Key())
+			if err != nil {
+				return nil, err
+			}
+			height, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if height >= start && height <= end {
+				add(height)
+			}
+			continue
+		}
+
+		var (
+			eventValue string
+			height     int64
+		)
+		if _, err := orderedcode.Parse(string(it.Key()), &compositeKey, &eventValue, &height); err != nil {
+			return nil, fmt.Errorf("failed to parse event key %X: %w", it.Key(), err)
+		}
+		if height >= start && height <= end {
+			add(height)[compositeKey+"="+eventValue]++
+		}
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
 // Search performs a query for block heights that match a given FinalizeBlock
 // event search criteria. The given query can match against zero,
 // one or more block heights. In the case of height queries, i.e. block.height=H,
@@ -434,3 +434,36 @@ func TestBigInt(t *testing.T) {
 		})
 	}
 }
+
+func TestBlockIndexerIndexedEvents(t *testing.T) {
+	store := db.NewPrefixDB(db.NewMemDB(), []byte("block_events"))
+	indexer := blockidxkv.New(store)
+
+	event := abci.Event{
+		Type: "end_event",
+		Attributes: []abci.EventAttribute{
+			{Key: "foo", Value: "100", Index: true},
+			{Key: "bar", Value: "1", Index: false},
+		},
+	}
+	require.NoError(t, indexer.Index(types.EventDataNewBlockEvents{Height: 1, Events: []abci.Event{event}}))
+	require.NoError(t, indexer.Index(types.EventDataNewBlockEvents{Height: 2, Events: []abci.Event{event, event}}))
+	require.NoError(t, indexer.Index(types.EventDataNewBlockEvents{Height: 3}))
+	require.NoError(t, indexer.Index(types.EventDataNewBlockEvents{Height: 4, Events: []abci.Event{event}}))
+
+	events, err := indexer.IndexedEvents(1, 3)
+	require.NoError(t, err)
+	require.Equal(t, map[int64]map[string]int{
+		1: {"end_event.foo=100": 1},
+		2: {"end_event.foo=100": 2},
+		3: {},
+	}, events)
+
+	// indexing a height again with a new indexer, and thus different event
+	// sequence numbers, yields the same events
+	other := blockidxkv.New(db.NewMemDB())
+	require.NoError(t, other.Index(types.EventDataNewBlockEvents{Height: 2, Events: []abci.Event{event, event}}))
+	otherEvents, err := other.IndexedEvents(2, 2)
+	require.NoError(t, err)
+	require.Equal(t, events[2], otherEvents[2])
+}
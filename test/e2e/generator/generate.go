@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -70,19 +71,167 @@ var (
 	keyType                   = uniformChoice{ed25519.KeyType, secp256k1.KeyType, bls12381.KeyType}
 )
 
+// Values accepted by generateConfig.voteExtensions.
+const (
+	voteExtensionsRandom = "random"
+	voteExtensionsAlways = "always"
+	voteExtensionsNever  = "never"
+)
+
+// Values accepted by generateConfig.stateSync.
+const (
+	stateSyncRandom = "random"
+	stateSyncAlways = "always"
+	stateSyncNever  = "never"
+)
+
+// Values accepted by generateConfig.abciProtocol, besides the ABCI protocols
+// supported by the manifest loader.
+const (
+	abciProtocolRandom = "random"
+	abciProtocolSocket = "socket"
+)
+
 type generateConfig struct {
 	randSource   *rand.Rand
 	outputDir    string
 	multiVersion string
+	minVersion   string
 	prometheus   bool
+	// voteExtensions is one of voteExtensionsRandom (or empty),
+	// voteExtensionsAlways or voteExtensionsNever.
+	voteExtensions string
+	// nodePrefix, if not empty, is prepended to the name of every node,
+	// separated by a dash (e.g. grpA-validator01).
+	nodePrefix string
+	// ensureLightNode adds a light node to the testnets which have none.
+	ensureLightNode bool
+	// excludeModes is a comma-separated list of node modes (seed, full or
+	// light) that are never assigned to the generated nodes.
+	excludeModes string
+	// abciProtocol is the ABCI protocol of the generated testnets: one of
+	// abciProtocolRandom (or empty), abciProtocolSocket (unix or tcp) or a
+	// protocol supported by the manifest loader.
+	abciProtocol string
+	// stateSync is one of stateSyncRandom (or empty), stateSyncAlways (every
+	// node starting after the initial height state syncs) or stateSyncNever.
+	stateSync string
+	// genesisTime, if not zero, is the genesis time of every generated
+	// testnet, so that their manifests do not depend on when they are run.
+	genesisTime time.Time
+	// voteExtensionDelay, if not zero, is the delay the application of every
+	// generated testnet adds to its ExtendVote and VerifyVoteExtension
+	// responses, overriding the randomly chosen ABCI delays.
+	voteExtensionDelay time.Duration
+}
+
+// abciProtocolChoice returns the ABCI protocols the generated testnets choose
+// from, given the value of generateConfig.abciProtocol.
+func abciProtocolChoice(protocol string) (uniformChoice, error) {
+	switch protocol {
+	case "", abciProtocolRandom:
+		return nodeABCIProtocols, nil
+	case abciProtocolSocket:
+		return uniformChoice{string(e2e.ProtocolUNIX), string(e2e.ProtocolTCP)}, nil
+	case string(e2e.ProtocolBuiltin), string(e2e.ProtocolBuiltinConnSync),
+		string(e2e.ProtocolUNIX), string(e2e.ProtocolTCP), string(e2e.ProtocolGRPC):
+		return uniformChoice{protocol}, nil
+	default:
+		return nil, fmt.Errorf("invalid ABCI protocol %q (supported: %s, %s, %s, %s, %s, %s, %s)", protocol,
+			abciProtocolRandom, abciProtocolSocket, e2e.ProtocolBuiltin, e2e.ProtocolBuiltinConnSync,
+			e2e.ProtocolUNIX, e2e.ProtocolTCP, e2e.ProtocolGRPC)
+	}
+}
+
+// parseExcludedModes parses a comma-separated list of node modes to exclude
+// from the generated testnets. Validators cannot be excluded, as a testnet
+// needs at least one.
+func parseExcludedModes(s string) (map[e2e.Mode]bool, error) {
+	excluded := make(map[e2e.Mode]bool)
+	if s == "" {
+		return excluded, nil
+	}
+	for _, mode := range strings.Split(s, ",") {
+		switch m := e2e.Mode(strings.TrimSpace(mode)); m {
+		case e2e.ModeSeed, e2e.ModeFull, e2e.ModeLight:
+			excluded[m] = true
+		case e2e.ModeValidator:
+			return nil, errors.New("cannot exclude validator nodes: testnets need at least one validator")
+		default:
+			return nil, fmt.Errorf("unknown node mode %q", mode)
+		}
+	}
+	return excluded, nil
+}
+
+const (
+	// maxNodeNameLength is the maximum length of a node name, which is used
+	// as a Docker container and host name and must thus fit in a DNS label.
+	maxNodeNameLength = 63
+	// longestNodeName is the longest name generateTestnet assigns to a node,
+	// including the suffix of the container running the upgrade version.
+	longestNodeName = "validator00_u"
+)
+
+// nodePrefixRegexp matches the prefixes accepted by generateConfig.nodePrefix.
+var nodePrefixRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// validateNodePrefix checks that names built with the given prefix are valid
+// Docker container names, and short enough to be host names.
+func validateNodePrefix(prefix string) error {
+	if prefix == "" {
+		return nil
+	}
+	if !nodePrefixRegexp.MatchString(prefix) {
+		return fmt.Errorf("invalid node prefix %q: must match %s", prefix, nodePrefixRegexp)
+	}
+	if l := len(nodeName(prefix, longestNodeName)); l > maxNodeNameLength {
+		return fmt.Errorf("node prefix %q is too long: node names would be up to %d characters (max %d)",
+			prefix, l, maxNodeNameLength)
+	}
+	return nil
+}
+
+// nodeName returns the name of a node with the given prefix.
+func nodeName(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "-" + name
 }
 
 // Generate generates random testnets using the given RNG.
 func Generate(cfg *generateConfig) ([]e2e.Manifest, error) {
 	upgradeVersion := ""
 
+	switch cfg.voteExtensions {
+	case "", voteExtensionsRandom, voteExtensionsAlways, voteExtensionsNever:
+	default:
+		return nil, fmt.Errorf("invalid vote extensions mode %q (supported: %s, %s, %s)",
+			cfg.voteExtensions, voteExtensionsRandom, voteExtensionsAlways, voteExtensionsNever)
+	}
+	switch cfg.stateSync {
+	case "", stateSyncRandom, stateSyncAlways, stateSyncNever:
+	default:
+		return nil, fmt.Errorf("invalid state sync mode %q (supported: %s, %s, %s)",
+			cfg.stateSync, stateSyncRandom, stateSyncAlways, stateSyncNever)
+	}
+	if err := validateNodePrefix(cfg.nodePrefix); err != nil {
+		return nil, err
+	}
+	excludeModes, err := parseExcludedModes(cfg.excludeModes)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ensureLightNode && excludeModes[e2e.ModeLight] {
+		return nil, errors.New("cannot both ensure a light node and exclude light nodes")
+	}
+	abciProtocols, err := abciProtocolChoice(cfg.abciProtocol)
+	if err != nil {
+		return nil, err
+	}
+
 	if cfg.multiVersion != "" {
-		var err error
 		nodeVersions, upgradeVersion, err = parseWeightedVersions(cfg.multiVersion)
 		if err != nil {
 			return nil, err
@@ -106,6 +255,15 @@ func Generate(cfg *generateConfig) ([]e2e.Manifest, error) {
 			}
 		}
 	}
+	if cfg.minVersion != "" {
+		nodeVersions, err = filterVersionsBelow(nodeVersions, cfg.minVersion)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := nodeVersions[upgradeVersion]; upgradeVersion != "" && !ok {
+			return nil, fmt.Errorf("upgrade version %s is below the minimum version %s", upgradeVersion, cfg.minVersion)
+		}
+	}
 	fmt.Println("Generating testnet with weighted versions:")
 	for ver, wt := range nodeVersions {
 		if ver == "" {
@@ -117,20 +275,75 @@ func Generate(cfg *generateConfig) ([]e2e.Manifest, error) {
 
 	manifests := make([]e2e.Manifest, 0, len(testnetCombinations))
 	for _, opt := range combinations(testnetCombinations) {
-		manifest, err := generateTestnet(cfg.randSource, opt, upgradeVersion, cfg.prometheus)
+		manifest, err := generateTestnet(cfg.randSource, opt, upgradeVersion, cfg.prometheus, cfg.voteExtensions,
+			cfg.nodePrefix, excludeModes, abciProtocols, cfg.stateSync)
 		if err != nil {
 			return nil, err
 		}
+		if !cfg.genesisTime.IsZero() {
+			genesisTime := cfg.genesisTime
+			manifest.GenesisTime = &genesisTime
+		}
+		if cfg.voteExtensionDelay != 0 {
+			manifest.VoteExtensionDelay = cfg.voteExtensionDelay
+		}
 		manifests = append(manifests, manifest)
 	}
+	// This is done once all testnets are generated, so that they are the same
+	// regardless of ensureLightNode.
+	if cfg.ensureLightNode {
+		for _, manifest := range manifests {
+			if err := ensureLightNode(cfg.randSource, manifest, cfg.nodePrefix); err != nil {
+				return nil, err
+			}
+		}
+	}
 	return manifests, nil
 }
 
+// ensureLightNode adds a light node to the manifest if it has none. Its
+// providers are the nodes which have all blocks from the initial height, with
+// a full node, if any, as the primary. As the light client needs a witness,
+// the primary is also used as the witness if it is the only provider.
+func ensureLightNode(r *rand.Rand, manifest e2e.Manifest, nodePrefix string) error {
+	var providers []string
+	for name, node := range manifest.Nodes {
+		switch node.Mode {
+		case string(e2e.ModeLight):
+			return nil
+		case string(e2e.ModeSeed):
+			continue
+		}
+		if (node.StartAt == 0 || node.StartAt == manifest.InitialHeight) && node.RetainBlocks == 0 {
+			providers = append(providers, name)
+		}
+	}
+	if len(providers) == 0 {
+		return errors.New("cannot add a light node: no node has all blocks from the initial height")
+	}
+	sort.Slice(providers, func(i, j int) bool {
+		iFull := manifest.Nodes[providers[i]].Mode == string(e2e.ModeFull)
+		jFull := manifest.Nodes[providers[j]].Mode == string(e2e.ModeFull)
+		if iFull != jFull {
+			return iFull
+		}
+		return providers[i] < providers[j]
+	})
+	if len(providers) == 1 {
+		providers = append(providers, providers[0])
+	}
+	manifest.Nodes[nodeName(nodePrefix, "light01")] = generateLightNode(r, manifest.InitialHeight+10, providers)
+	return nil
+}
+
 // generateTestnet generates a single testnet with the given options.
-func generateTestnet(r *rand.Rand, opt map[string]any, upgradeVersion string, prometheus bool) (e2e.Manifest, error) {
+func generateTestnet(
+	r *rand.Rand, opt map[string]any, upgradeVersion string, prometheus bool, voteExtensions, nodePrefix string,
+	excludeModes map[e2e.Mode]bool, abciProtocols uniformChoice, stateSync string,
+) (e2e.Manifest, error) {
 	manifest := e2e.Manifest{
 		IPv6:             ipv6.Choose(r).(bool),
-		ABCIProtocol:     nodeABCIProtocols.Choose(r).(string),
+		ABCIProtocol:     abciProtocols.Choose(r).(string),
 		InitialHeight:    int64(opt["initialHeight"].(int)),
 		InitialState:     opt["initialState"].(map[string]string),
 		Validators:       &map[string]int64{},
@@ -164,6 +377,15 @@ func generateTestnet(r *rand.Rand, opt map[string]any, upgradeVersion string, pr
 		baseHeight := max(manifest.VoteExtensionsUpdateHeight+1, manifest.InitialHeight)
 		manifest.VoteExtensionsEnableHeight = baseHeight + voteExtensionHeightOffset.Choose(r).(int64)
 	}
+	// The random choices above are still drawn when the mode is pinned, so
+	// that the rest of the testnet is the same regardless of the mode.
+	switch voteExtensions {
+	case voteExtensionsAlways:
+		manifest.VoteExtensionsUpdateHeight = -1
+		manifest.VoteExtensionsEnableHeight = max(manifest.InitialHeight, 1)
+	case voteExtensionsNever:
+		manifest.VoteExtensionsEnableHeight = 0
+	}
 
 	manifest.VoteExtensionSize = voteExtensionSize.Choose(r).(uint)
 
@@ -182,11 +404,22 @@ func generateTestnet(r *rand.Rand, opt map[string]any, upgradeVersion string, pr
 	default:
 		return manifest, fmt.Errorf("unknown topology %q", opt["topology"])
 	}
+	// As for vote extensions, the counts are drawn even for excluded modes so
+	// that the rest of the testnet is the same.
+	if excludeModes[e2e.ModeSeed] {
+		numSeeds = 0
+	}
+	if excludeModes[e2e.ModeFull] {
+		numFulls = 0
+	}
+	if excludeModes[e2e.ModeLight] {
+		numLightClients = 0
+	}
 
 	// First we generate seed nodes, starting at the initial height.
 	for i := 1; i <= numSeeds; i++ {
-		manifest.Nodes[fmt.Sprintf("seed%02d", i)] = generateNode(
-			r, e2e.ModeSeed, 0, false)
+		manifest.Nodes[nodeName(nodePrefix, fmt.Sprintf("seed%02d", i))] = generateNode(
+			r, e2e.ModeSeed, 0, false, stateSync)
 	}
 
 	// Next, we generate validators. We make sure a BFT quorum of validators start
@@ -200,9 +433,9 @@ func generateTestnet(r *rand.Rand, opt map[string]any, upgradeVersion string, pr
 			startAt = nextStartAt
 			nextStartAt += 5
 		}
-		name := fmt.Sprintf("validator%02d", i)
+		name := nodeName(nodePrefix, fmt.Sprintf("validator%02d", i))
 		manifest.Nodes[name] = generateNode(
-			r, e2e.ModeValidator, startAt, i <= 2)
+			r, e2e.ModeValidator, startAt, i <= 2, stateSync)
 
 		if startAt == 0 {
 			(*manifest.Validators)[name] = int64(30 + r.Intn(71))
@@ -230,8 +463,8 @@ func generateTestnet(r *rand.Rand, opt map[string]any, upgradeVersion string, pr
 			startAt = nextStartAt
 			nextStartAt += 5
 		}
-		manifest.Nodes[fmt.Sprintf("full%02d", i)] = generateNode(
-			r, e2e.ModeFull, startAt, false)
+		manifest.Nodes[nodeName(nodePrefix, fmt.Sprintf("full%02d", i))] = generateNode(
+			r, e2e.ModeFull, startAt, false, stateSync)
 	}
 
 	// We now set up peer discovery for nodes. Seed nodes are fully meshed with
@@ -251,6 +484,9 @@ func generateTestnet(r *rand.Rand, opt map[string]any, upgradeVersion string, pr
 		}
 	}
 
+	// the names are sorted, so that the manifest only depends on r
+	sort.Strings(seedNames)
+	sort.Strings(lightProviders)
 	for _, name := range seedNames {
 		for _, otherName := range seedNames {
 			if name != otherName {
@@ -281,20 +517,55 @@ func generateTestnet(r *rand.Rand, opt map[string]any, upgradeVersion string, pr
 	// lastly, set up the light clients
 	for i := 1; i <= numLightClients; i++ {
 		startAt := manifest.InitialHeight + 5
-		manifest.Nodes[fmt.Sprintf("light%02d", i)] = generateLightNode(
+		manifest.Nodes[nodeName(nodePrefix, fmt.Sprintf("light%02d", i))] = generateLightNode(
 			r, startAt+(5*int64(i)), lightProviders,
 		)
 	}
 
+	if err := checkStateSync(manifest); err != nil {
+		return manifest, err
+	}
 	return manifest, nil
 }
 
+// checkStateSync checks that the nodes of the manifest which state sync can
+// do so: a node must serve snapshots from the initial height, and the runner
+// needs two archive nodes, other than the syncing node, as RPC servers.
+func checkStateSync(manifest e2e.Manifest) error {
+	var hasSnapshots bool
+	archiveNodes := 0
+	for _, node := range manifest.Nodes {
+		if node.Mode == string(e2e.ModeSeed) || node.Mode == string(e2e.ModeLight) || node.StartAt != 0 {
+			continue
+		}
+		if node.SnapshotInterval > 0 {
+			hasSnapshots = true
+		}
+		if node.RetainBlocks == 0 {
+			archiveNodes++
+		}
+	}
+	for name, node := range manifest.Nodes {
+		if !node.StateSync {
+			continue
+		}
+		if !hasSnapshots {
+			return fmt.Errorf("node %s cannot state sync: no node serves snapshots from the initial height", name)
+		}
+		if archiveNodes < 2 {
+			return fmt.Errorf("node %s cannot state sync: %d archive nodes to use as RPC servers, need 2",
+				name, archiveNodes)
+		}
+	}
+	return nil
+}
+
 // generateNode randomly generates a node, with some constraints to avoid
 // generating invalid configurations. We do not set Seeds or PersistentPeers
 // here, since we need to know the overall network topology and startup
 // sequencing.
 func generateNode(
-	r *rand.Rand, mode e2e.Mode, startAt int64, forceArchive bool,
+	r *rand.Rand, mode e2e.Mode, startAt int64, forceArchive bool, stateSync string,
 ) *e2e.ManifestNode {
 	node := e2e.ManifestNode{
 		Version:          nodeVersions.Choose(r).(string),
@@ -310,8 +581,19 @@ func generateNode(
 		Perturb:          nodePerturbations.Choose(r),
 	}
 
+	// Nodes starting at the initial height have nothing to state sync from.
+	// The choice above is drawn regardless, so that the rest of the node is
+	// the same whatever the mode.
+	switch stateSync {
+	case stateSyncAlways:
+		node.StateSync = startAt > 0
+	case stateSyncNever:
+		node.StateSync = false
+	}
+
 	// If this node is forced to be an archive node, retain all blocks and
-	// enable state sync snapshotting.
+	// enable state sync snapshotting. These are the snapshot providers of the
+	// nodes which state sync.
 	if forceArchive {
 		node.RetainBlocks = 0
 		node.SnapshotInterval = 3
@@ -408,6 +690,39 @@ func parseWeightedVersions(s string) (weightedChoice, string, error) {
 	return wc, lastVersion, nil
 }
 
+// filterVersionsBelow returns the weighted versions which are greater than or
+// equal to minVersion. Versions are compared on their tag (e.g. the "v0.34.21"
+// in "cometbft/e2e-node:v0.34.21"); the local version ("") is compared using
+// the current version of CometBFT. Returns an error if no version remains.
+func filterVersionsBelow(versions weightedChoice, minVersion string) (weightedChoice, error) {
+	minSemVer, err := semver.NewVersion(minVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse minimum version %q: %w", minVersion, err)
+	}
+
+	filtered := make(weightedChoice, len(versions))
+	for ver, weight := range versions {
+		tag := strings.Split(version.TMCoreSemVer, "-")[0]
+		if ver.(string) != "" {
+			parts := strings.Split(ver.(string), ":")
+			tag = parts[len(parts)-1]
+		}
+		semVer, err := semver.NewVersion(tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version %q: %w", ver, err)
+		}
+		if semVer.LessThan(minSemVer) {
+			continue
+		}
+		filtered[ver] = weight
+	}
+
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("no versions left after dropping those below the minimum version %s", minVersion)
+	}
+	return filtered, nil
+}
+
 // Extracts the latest release version from the given Git repository. Uses the
 // current version of CometBFT to establish the "major" version
 // currently in use.
@@ -3,10 +3,14 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"math/rand"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -30,3 +34,425 @@ func TestGenerator(t *testing.T) {
 		})
 	}
 }
+
+func TestGeneratorVoteExtensions(t *testing.T) {
+	for _, mode := range []string{voteExtensionsAlways, voteExtensionsNever} {
+		t.Run(mode, func(t *testing.T) {
+			manifests, err := Generate(&generateConfig{
+				randSource:     rand.New(rand.NewSource(randomSeed)),
+				voteExtensions: mode,
+			})
+			require.NoError(t, err)
+			for _, m := range manifests {
+				if mode == voteExtensionsAlways {
+					require.EqualValues(t, -1, m.VoteExtensionsUpdateHeight)
+					require.Equal(t, max(m.InitialHeight, 1), m.VoteExtensionsEnableHeight)
+				} else {
+					require.Zero(t, m.VoteExtensionsEnableHeight)
+				}
+			}
+		})
+	}
+
+	_, err := Generate(&generateConfig{
+		randSource:     rand.New(rand.NewSource(randomSeed)),
+		voteExtensions: "sometimes",
+	})
+	require.Error(t, err)
+}
+
+func TestGeneratorNodePrefix(t *testing.T) {
+	manifests, err := Generate(&generateConfig{
+		randSource: rand.New(rand.NewSource(randomSeed)),
+		nodePrefix: "grpA",
+	})
+	require.NoError(t, err)
+	for idx, m := range manifests {
+		for name, node := range m.Nodes {
+			require.True(t, strings.HasPrefix(name, "grpA-"), name)
+			for _, peer := range append(node.Seeds, node.PersistentPeers...) {
+				require.True(t, strings.HasPrefix(peer, "grpA-"), peer)
+			}
+		}
+		infra, err := e2e.NewDockerInfrastructureData(m)
+		require.NoError(t, err)
+		_, err = e2e.NewTestnetFromManifest(m, filepath.Join(t.TempDir(), fmt.Sprintf("Case%04d", idx)), infra)
+		require.NoError(t, err)
+	}
+
+	for _, prefix := range []string{"-grpA", "grp A", strings.Repeat("a", maxNodeNameLength)} {
+		_, err := Generate(&generateConfig{
+			randSource: rand.New(rand.NewSource(randomSeed)),
+			nodePrefix: prefix,
+		})
+		require.Error(t, err, prefix)
+	}
+}
+
+func TestGeneratorEnsureLightNode(t *testing.T) {
+	manifests, err := Generate(&generateConfig{
+		randSource:      rand.New(rand.NewSource(randomSeed)),
+		ensureLightNode: true,
+		nodePrefix:      "grpA",
+	})
+	require.NoError(t, err)
+	for i, m := range manifests {
+		var lightNodes []*e2e.ManifestNode
+		for _, node := range m.Nodes {
+			if node.Mode == string(e2e.ModeLight) {
+				lightNodes = append(lightNodes, node)
+			}
+		}
+		require.NotEmpty(t, lightNodes)
+		for _, node := range lightNodes {
+			// a primary and at least a witness
+			require.GreaterOrEqual(t, len(node.PersistentPeers), 2)
+		}
+
+		file := filepath.Join(t.TempDir(), fmt.Sprintf("gen-%04d.toml", i))
+		require.NoError(t, m.Save(file))
+		require.NoError(t, validateManifest(file))
+	}
+}
+
+func TestGeneratorExcludeModes(t *testing.T) {
+	manifests, err := Generate(&generateConfig{
+		randSource:   rand.New(rand.NewSource(randomSeed)),
+		excludeModes: "seed, light",
+	})
+	require.NoError(t, err)
+	for _, m := range manifests {
+		validators := 0
+		for name, node := range m.Nodes {
+			require.NotEqual(t, string(e2e.ModeSeed), node.Mode, name)
+			require.NotEqual(t, string(e2e.ModeLight), node.Mode, name)
+			if node.Mode == string(e2e.ModeValidator) {
+				validators++
+			}
+		}
+		require.Positive(t, validators)
+	}
+
+	for _, modes := range []string{"validator", "full,validator", "archive"} {
+		_, err = Generate(&generateConfig{
+			randSource:   rand.New(rand.NewSource(randomSeed)),
+			excludeModes: modes,
+		})
+		require.Error(t, err, modes)
+	}
+	_, err = Generate(&generateConfig{
+		randSource:      rand.New(rand.NewSource(randomSeed)),
+		excludeModes:    "light",
+		ensureLightNode: true,
+	})
+	require.Error(t, err)
+}
+
+func TestGeneratorABCIProtocol(t *testing.T) {
+	testCases := []struct {
+		protocol string
+		want     []string
+	}{
+		{"builtin", []string{"builtin"}},
+		{"grpc", []string{"grpc"}},
+		{"socket", []string{"unix", "tcp"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.protocol, func(t *testing.T) {
+			manifests, err := Generate(&generateConfig{
+				randSource:   rand.New(rand.NewSource(randomSeed)),
+				abciProtocol: tc.protocol,
+			})
+			require.NoError(t, err)
+			for i, m := range manifests {
+				require.Contains(t, tc.want, m.ABCIProtocol)
+
+				file := filepath.Join(t.TempDir(), fmt.Sprintf("gen-%04d.toml", i))
+				require.NoError(t, m.Save(file))
+				require.NoError(t, validateManifest(file))
+			}
+		})
+	}
+
+	_, err := Generate(&generateConfig{
+		randSource:   rand.New(rand.NewSource(randomSeed)),
+		abciProtocol: "file",
+	})
+	require.Error(t, err)
+}
+
+func TestGeneratorStateSync(t *testing.T) {
+	for _, mode := range []string{stateSyncAlways, stateSyncNever} {
+		t.Run(mode, func(t *testing.T) {
+			manifests, err := Generate(&generateConfig{
+				randSource: rand.New(rand.NewSource(randomSeed)),
+				stateSync:  mode,
+			})
+			require.NoError(t, err)
+			syncing := 0
+			for i, m := range manifests {
+				for name, node := range m.Nodes {
+					want := mode == stateSyncAlways && node.StartAt > 0 && node.Mode != string(e2e.ModeLight)
+					require.Equal(t, want, node.StateSync, name)
+					if node.StateSync {
+						syncing++
+					}
+				}
+				require.NoError(t, checkStateSync(m))
+
+				file := filepath.Join(t.TempDir(), fmt.Sprintf("gen-%04d.toml", i))
+				require.NoError(t, m.Save(file))
+				require.NoError(t, validateManifest(file))
+			}
+			if mode == stateSyncAlways {
+				require.Positive(t, syncing)
+			}
+		})
+	}
+
+	_, err := Generate(&generateConfig{
+		randSource: rand.New(rand.NewSource(randomSeed)),
+		stateSync:  "sometimes",
+	})
+	require.Error(t, err)
+}
+
+func TestCheckStateSync(t *testing.T) {
+	manifest := e2e.Manifest{Nodes: map[string]*e2e.ManifestNode{
+		"validator01": {Mode: string(e2e.ModeValidator)},
+		"validator02": {Mode: string(e2e.ModeValidator)},
+		"full01":      {Mode: string(e2e.ModeFull), StartAt: 10, StateSync: true},
+	}}
+	require.Error(t, checkStateSync(manifest))
+
+	manifest.Nodes["validator01"].SnapshotInterval = 3
+	require.NoError(t, checkStateSync(manifest))
+
+	manifest.Nodes["validator02"].RetainBlocks = 20
+	require.Error(t, checkStateSync(manifest))
+}
+
+func TestValidateManifests(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range []string{formatTOML, formatJSON} {
+		out := outputOptions{dir: filepath.Join(dir, format), limit: 5, format: format}
+		err := (&CLI{}).generate(out, randomSeed, &generateConfig{})
+		require.NoError(t, err)
+		require.NoError(t, validateManifests(out.dir))
+	}
+
+	// a manifest saved in both formats loads the same
+	fromTOML, err := e2e.LoadManifest(filepath.Join(dir, formatTOML, "gen-0000.toml"))
+	require.NoError(t, err)
+	require.NoError(t, fromTOML.SaveJSON(filepath.Join(dir, "manifest.json")))
+	fromJSON, err := e2e.LoadManifest(filepath.Join(dir, "manifest.json"))
+	require.NoError(t, err)
+	require.Equal(t, fromTOML, fromJSON)
+
+	// a manifest referring to an unknown node is reported
+	m := fromTOML
+	m.Nodes["validator01"].PersistentPeers = []string{"unknown"}
+	require.NoError(t, m.Save(filepath.Join(dir, formatTOML, "gen-9999.toml")))
+	require.Error(t, validateManifests(filepath.Join(dir, formatTOML)))
+
+	require.Error(t, validateManifests(t.TempDir()))
+}
+
+func TestGenerateIndex(t *testing.T) {
+	dir := t.TempDir()
+	out := outputOptions{dir: dir, groups: 2, limit: 4, format: formatTOML, index: true}
+	require.NoError(t, (&CLI{}).generate(out, randomSeed, &generateConfig{prometheus: true}))
+
+	bz, err := os.ReadFile(filepath.Join(dir, indexFile))
+	require.NoError(t, err)
+	var index manifestIndex
+	require.NoError(t, json.Unmarshal(bz, &index))
+	require.Equal(t, randomSeed, index.Seed)
+	require.Len(t, index.Manifests, 4)
+
+	for _, summary := range index.Manifests {
+		m, err := e2e.LoadManifest(filepath.Join(dir, summary.File))
+		require.NoError(t, err)
+		require.NotNil(t, summary.Group)
+		require.Contains(t, summary.File, fmt.Sprintf("group%02d", *summary.Group))
+		require.Equal(t, len(m.Nodes), summary.Nodes)
+		require.Equal(t, map[string]int{"local": len(m.Nodes)}, summary.Versions)
+		require.True(t, summary.Prometheus)
+	}
+
+	// without --index, no index is written
+	dir = t.TempDir()
+	out = outputOptions{dir: dir, limit: 1, format: formatTOML}
+	require.NoError(t, (&CLI{}).generate(out, randomSeed, &generateConfig{}))
+	require.NoFileExists(t, filepath.Join(dir, indexFile))
+}
+
+func TestGenerateGroupSeedOffset(t *testing.T) {
+	const offset = 1000
+	dir := t.TempDir()
+	out := outputOptions{dir: dir, groups: 3, groupSeedOffset: offset, limit: 6, format: formatTOML, index: true}
+	require.NoError(t, (&CLI{}).generate(out, randomSeed, &generateConfig{}))
+
+	bz, err := os.ReadFile(filepath.Join(dir, indexFile))
+	require.NoError(t, err)
+	var index manifestIndex
+	require.NoError(t, json.Unmarshal(bz, &index))
+	require.Equal(t, randomSeed, index.Seed)
+	// each group takes its share of its own batch
+	require.Len(t, index.Manifests, 6)
+	for _, summary := range index.Manifests {
+		require.NotNil(t, summary.Group)
+		require.NotNil(t, summary.Seed)
+		require.Equal(t, randomSeed+int64(*summary.Group)*offset, *summary.Seed)
+		require.FileExists(t, filepath.Join(dir, summary.File))
+	}
+
+	// without an offset, the seed of the groups is not recorded
+	dir = t.TempDir()
+	out = outputOptions{dir: dir, groups: 2, limit: 2, format: formatTOML, index: true}
+	require.NoError(t, (&CLI{}).generate(out, randomSeed, &generateConfig{}))
+	bz, err = os.ReadFile(filepath.Join(dir, indexFile))
+	require.NoError(t, err)
+	index = manifestIndex{}
+	require.NoError(t, json.Unmarshal(bz, &index))
+	for _, summary := range index.Manifests {
+		require.Nil(t, summary.Seed)
+	}
+}
+
+func TestGenerateGenesisTime(t *testing.T) {
+	genesisTime := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	generate := func(format string, genesisTime time.Time) string {
+		dir := t.TempDir()
+		out := outputOptions{dir: dir, limit: 20, format: format}
+		require.NoError(t, (&CLI{}).generate(out, randomSeed, &generateConfig{genesisTime: genesisTime}))
+		return dir
+	}
+
+	for _, format := range []string{formatTOML, formatJSON} {
+		t.Run(format, func(t *testing.T) {
+			// two runs with the same seed and genesis time write the same files
+			dir, otherDir := generate(format, genesisTime), generate(format, genesisTime)
+			files, err := filepath.Glob(filepath.Join(dir, "gen-*."+format))
+			require.NoError(t, err)
+			require.Len(t, files, 20)
+			for _, file := range files {
+				bz, err := os.ReadFile(file)
+				require.NoError(t, err)
+				otherBz, err := os.ReadFile(filepath.Join(otherDir, filepath.Base(file)))
+				require.NoError(t, err)
+				require.Equal(t, string(bz), string(otherBz), file)
+
+				manifest, err := e2e.LoadManifest(file)
+				require.NoError(t, err)
+				require.NotNil(t, manifest.GenesisTime)
+				require.True(t, genesisTime.Equal(*manifest.GenesisTime), file)
+			}
+
+			// without a genesis time, it is left to the runner
+			dir = generate(format, time.Time{})
+			manifest, err := e2e.LoadManifest(filepath.Join(dir, "gen-0000."+format))
+			require.NoError(t, err)
+			require.Nil(t, manifest.GenesisTime)
+		})
+	}
+}
+
+func TestManifestSchema(t *testing.T) {
+	schema := manifestSchema()
+	require.Equal(t, jsonSchemaDraft, schema["$schema"])
+	// the schema is valid JSON
+	_, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	manifests, err := Generate(&generateConfig{randSource: rand.New(rand.NewSource(randomSeed))})
+	require.NoError(t, err)
+	dir := t.TempDir()
+	for i, m := range manifests[:10] {
+		file := filepath.Join(dir, fmt.Sprintf("gen-%04d.json", i))
+		require.NoError(t, m.SaveJSON(file))
+		bz, err := os.ReadFile(file)
+		require.NoError(t, err)
+		var v any
+		require.NoError(t, json.Unmarshal(bz, &v))
+		require.NoError(t, validateSchema(v, schema, "manifest"), file)
+	}
+
+	var v any
+	require.NoError(t, json.Unmarshal([]byte(`{"node": {"validator01": {"mod": "validator"}}}`), &v))
+	require.ErrorContains(t, validateSchema(v, schema, "manifest"), "manifest.node.validator01.mod")
+	require.NoError(t, json.Unmarshal([]byte(`{"initial_height": "1"}`), &v))
+	require.ErrorContains(t, validateSchema(v, schema, "manifest"), "manifest.initial_height")
+}
+
+// validateSchema checks v, decoded from JSON, against the subset of JSON schema
+// produced by typeSchema.
+func validateSchema(v any, schema map[string]any, path string) error {
+	switch schema["type"] {
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: %v is not a boolean", path, v)
+		}
+	case "integer":
+		if f, ok := v.(float64); !ok || f != float64(int64(f)) {
+			return fmt.Errorf("%s: %v is not an integer", path, v)
+		}
+	case "number":
+		if _, ok := v.(float64); !ok {
+			return fmt.Errorf("%s: %v is not a number", path, v)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: %v is not a string", path, v)
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: %v is not an array", path, v)
+		}
+		for i, item := range items {
+			if err := validateSchema(item, schema["items"].(map[string]any), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: %v is not an object", path, v)
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for key, value := range obj {
+			propSchema, ok := properties[key].(map[string]any)
+			if !ok {
+				if propSchema, ok = schema["additionalProperties"].(map[string]any); !ok {
+					return fmt.Errorf("%s.%s: unknown property", path, key)
+				}
+			}
+			if err := validateSchema(value, propSchema, path+"."+key); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%s: unsupported schema type %v", path, schema["type"])
+	}
+	return nil
+}
+
+func TestGenerateVoteExtensionDelay(t *testing.T) {
+	const delay = 500 * time.Millisecond
+	for _, format := range []string{formatTOML, formatJSON} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			out := outputOptions{dir: dir, limit: 20, format: format}
+			require.NoError(t, (&CLI{}).generate(out, randomSeed, &generateConfig{voteExtensionDelay: delay}))
+			files, err := filepath.Glob(filepath.Join(dir, "gen-*."+format))
+			require.NoError(t, err)
+			require.Len(t, files, 20)
+			for _, file := range files {
+				manifest, err := e2e.LoadManifest(file)
+				require.NoError(t, err)
+				require.Equal(t, delay, manifest.VoteExtensionDelay, file)
+			}
+		})
+	}
+}
@@ -45,3 +45,27 @@ func TestVersionFinder(t *testing.T) {
 		assert.Equal(t, tc.expectedLatest, actualLatest)
 	}
 }
+
+func TestFilterVersionsBelow(t *testing.T) {
+	versions := weightedChoice{
+		"":                             2,
+		"cometbft/e2e-node:v0.34.27":   1,
+		"cometbft/e2e-node:v0.38.0":    1,
+		"ghcr.io/org/e2e-node:v1.0.1":  3,
+		"cometbft/e2e-node:v0.37.0-rc": 1,
+	}
+
+	filtered, err := filterVersionsBelow(versions, "v0.38.0")
+	require.NoError(t, err)
+	assert.Equal(t, weightedChoice{
+		"":                            2,
+		"cometbft/e2e-node:v0.38.0":   1,
+		"ghcr.io/org/e2e-node:v1.0.1": 3,
+	}, filtered)
+
+	_, err = filterVersionsBelow(weightedChoice{"cometbft/e2e-node:v0.34.27": 1}, "v0.38.0")
+	require.Error(t, err)
+
+	_, err = filterVersionsBelow(versions, "not-a-version")
+	require.Error(t, err)
+}
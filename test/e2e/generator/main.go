@@ -1,19 +1,27 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/cometbft/cometbft/libs/log"
+	e2e "github.com/cometbft/cometbft/test/e2e/pkg"
 )
 
 const (
 	randomSeed int64 = 4827085738
+
+	formatTOML = "toml"
+	formatJSON = "json"
 )
 
 var logger = log.NewTMLogger(log.NewSyncWriter(os.Stdout))
@@ -31,15 +39,25 @@ type CLI struct {
 func NewCLI() *CLI {
 	cli := &CLI{}
 	cli.root = &cobra.Command{
-		Use:           "generator -d dir [-g int] [-m version_weight_csv] [-p]",
+		Use:           "generator {-d dir [-g int] [-m version_weight_csv] [-p] [--seed int] [--index] | --validate-only dir}",
 		Short:         "End-to-end testnet generator",
 		SilenceUsage:  true,
 		SilenceErrors: true, // we'll output them ourselves in Run()
 		RunE: func(cmd *cobra.Command, args []string) error {
+			validateDir, err := cmd.Flags().GetString("validate-only")
+			if err != nil {
+				return err
+			}
+			if validateDir != "" {
+				return validateManifests(validateDir)
+			}
 			dir, err := cmd.Flags().GetString("dir")
 			if err != nil {
 				return err
 			}
+			if dir == "" {
+				return errors.New("either --dir or --validate-only must be set")
+			}
 			groups, err := cmd.Flags().GetInt("groups")
 			if err != nil {
 				return err
@@ -52,58 +70,378 @@ func NewCLI() *CLI {
 			if err != nil {
 				return err
 			}
-			return cli.generate(dir, groups, multiVersion, prometheus)
+			minVersion, err := cmd.Flags().GetString("min-version")
+			if err != nil {
+				return err
+			}
+			seed, err := cmd.Flags().GetInt64("seed")
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("seed") {
+				logger.Info("Using random seed", "seed", seed)
+			}
+			limit, err := cmd.Flags().GetInt("limit")
+			if err != nil {
+				return err
+			}
+			minNodes, err := cmd.Flags().GetInt("min-nodes")
+			if err != nil {
+				return err
+			}
+			maxNodes, err := cmd.Flags().GetInt("max-nodes")
+			if err != nil {
+				return err
+			}
+			if maxNodes > 0 && minNodes > maxNodes {
+				return fmt.Errorf("--min-nodes (%d) is greater than --max-nodes (%d)", minNodes, maxNodes)
+			}
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+			if format != formatTOML && format != formatJSON {
+				return fmt.Errorf("unsupported manifest format %q (supported: %s, %s)", format, formatTOML, formatJSON)
+			}
+			voteExtensions, err := cmd.Flags().GetString("vote-extensions")
+			if err != nil {
+				return err
+			}
+			nodePrefix, err := cmd.Flags().GetString("node-prefix")
+			if err != nil {
+				return err
+			}
+			index, err := cmd.Flags().GetBool("index")
+			if err != nil {
+				return err
+			}
+			ensureLightNode, err := cmd.Flags().GetBool("ensure-light-node")
+			if err != nil {
+				return err
+			}
+			excludeModes, err := cmd.Flags().GetString("exclude-modes")
+			if err != nil {
+				return err
+			}
+			abciProtocol, err := cmd.Flags().GetString("abci-protocol")
+			if err != nil {
+				return err
+			}
+			stateSync, err := cmd.Flags().GetString("state-sync")
+			if err != nil {
+				return err
+			}
+			groupSeedOffset, err := cmd.Flags().GetInt64("group-seed-offset")
+			if err != nil {
+				return err
+			}
+			if groupSeedOffset != 0 && groups <= 0 {
+				return errors.New("--group-seed-offset requires --groups")
+			}
+			var genesisTime time.Time
+			if s, err := cmd.Flags().GetString("genesis-time"); err != nil {
+				return err
+			} else if s != "" {
+				if genesisTime, err = time.Parse(time.RFC3339, s); err != nil {
+					return fmt.Errorf("invalid --genesis-time: %w", err)
+				}
+			}
+			voteExtensionDelay, err := cmd.Flags().GetDuration("app-vote-extension-delay")
+			if err != nil {
+				return err
+			}
+			if voteExtensionDelay < 0 {
+				return errors.New("--app-vote-extension-delay can't be negative")
+			}
+			out := outputOptions{
+				dir:             dir,
+				groups:          groups,
+				groupSeedOffset: groupSeedOffset,
+				limit:           limit,
+				minNodes:        minNodes,
+				maxNodes:        maxNodes,
+				format:          format,
+				index:           index,
+			}
+			return cli.generate(out, seed, &generateConfig{
+				multiVersion:       multiVersion,
+				minVersion:         minVersion,
+				prometheus:         prometheus,
+				voteExtensions:     voteExtensions,
+				nodePrefix:         nodePrefix,
+				ensureLightNode:    ensureLightNode,
+				excludeModes:       excludeModes,
+				abciProtocol:       abciProtocol,
+				stateSync:          stateSync,
+				genesisTime:        genesisTime,
+				voteExtensionDelay: voteExtensionDelay,
+			})
 		},
 	}
 
+	cli.root.AddCommand(newSchemaCmd())
+
 	cli.root.PersistentFlags().StringP("dir", "d", "", "Output directory for manifests")
-	_ = cli.root.MarkPersistentFlagRequired("dir")
+	cli.root.PersistentFlags().String("validate-only", "", "Instead of generating manifests, validate the gen-*.toml "+
+		"and gen-*.json manifests in this directory")
+	cli.root.MarkFlagsMutuallyExclusive("dir", "validate-only")
 	cli.root.PersistentFlags().StringP("multi-version", "m", "", "Comma-separated list of versions of CometBFT to test in the generated testnets, "+
 		"or empty to only use this branch's version")
 	cli.root.PersistentFlags().IntP("groups", "g", 0, "Number of groups")
+	cli.root.PersistentFlags().Int64("group-seed-offset", 0, "With --groups, generate each group from its own seed, "+
+		"seed + group*offset, instead of splitting a single batch; zero disables it")
 	cli.root.PersistentFlags().BoolP("prometheus", "p", false, "Enable generation of Prometheus metrics on all manifests")
+	cli.root.PersistentFlags().String("min-version", "", "Minimum version of CometBFT to use in the generated testnets; "+
+		"versions from --multi-version below it are dropped")
+	cli.root.PersistentFlags().Int64("seed", randomSeed, "Seed for the random number generator used to generate the testnets")
+	cli.root.PersistentFlags().Int("limit", 0, "Maximum number of manifests to write (applied before grouping), "+
+		"or zero for no limit")
+	cli.root.PersistentFlags().Int("min-nodes", 0, "Only write testnets with at least this many nodes, or zero for no minimum")
+	cli.root.PersistentFlags().Int("max-nodes", 0, "Only write testnets with at most this many nodes, or zero for no maximum")
+	cli.root.PersistentFlags().String("format", formatTOML, "Format of the generated manifests (toml|json)")
+	cli.root.PersistentFlags().String("vote-extensions", voteExtensionsRandom, "Whether vote extensions are enabled in the generated testnets: "+
+		"random, always (enabled at genesis) or never")
+	cli.root.PersistentFlags().String("node-prefix", "", "Prefix prepended to the node names in the generated testnets, "+
+		"e.g. grpA for grpA-validator01")
+	cli.root.PersistentFlags().Bool("ensure-light-node", false, "Add a light node to the generated testnets which have none")
+	cli.root.PersistentFlags().String("exclude-modes", "", "Comma-separated list of node modes (seed, full, light) "+
+		"to leave out of the generated testnets")
+	cli.root.PersistentFlags().String("abci-protocol", abciProtocolRandom, "ABCI protocol of the generated testnets: "+
+		"random, socket (unix or tcp), builtin, builtin_connsync, unix, tcp or grpc")
+	cli.root.PersistentFlags().String("state-sync", stateSyncRandom, "Whether the nodes of the generated testnets "+
+		"state sync: random, always (every node starting after the initial height) or never")
+	cli.root.PersistentFlags().String("genesis-time", "", "Genesis time of the generated testnets, in RFC3339 format "+
+		"(e.g. 2023-01-01T00:00:00Z), or empty to use the time each testnet is set up")
+	cli.root.PersistentFlags().Duration("app-vote-extension-delay", 0, "Delay the application of the generated "+
+		"testnets adds to its vote extension responses (e.g. 500ms), or zero to choose it randomly")
+	cli.root.PersistentFlags().Bool("index", false, "Also write an "+indexFile+" file listing the generated manifests "+
+		"with their main attributes and the seed")
 
 	return cli
 }
 
+// outputOptions controls which of the generated manifests are written to disk
+// and how.
+type outputOptions struct {
+	dir    string
+	groups int
+	// groupSeedOffset, if not zero, makes each group generated from its own
+	// seed, the base seed plus the group number times the offset.
+	groupSeedOffset int64
+	// limit is the maximum number of manifests to write; zero or negative
+	// means no limit.
+	limit int
+	// minNodes and maxNodes bound the number of nodes of the testnets to
+	// write; zero means no bound.
+	minNodes int
+	maxNodes int
+	// format is the manifest file format, either formatTOML or formatJSON.
+	format string
+	// index enables writing indexFile.
+	index bool
+}
+
+// save writes the manifest to path (without extension) in the selected format,
+// returning the name of the file written.
+func (out outputOptions) save(manifest e2e.Manifest, path string) (string, error) {
+	if out.format == formatJSON {
+		return path + ".json", manifest.SaveJSON(path + ".json")
+	}
+	return path + ".toml", manifest.Save(path + ".toml")
+}
+
 // generate generates manifests in a directory.
-func (cli *CLI) generate(dir string, groups int, multiVersion string, prometheus bool) error {
-	err := os.MkdirAll(dir, 0o755)
+func (*CLI) generate(out outputOptions, seed int64, cfg *generateConfig) error {
+	err := os.MkdirAll(out.dir, 0o755)
 	if err != nil {
 		return err
 	}
 
-	cfg := &generateConfig{
-		randSource:   rand.New(rand.NewSource(randomSeed)), //nolint:gosec
-		multiVersion: multiVersion,
-		prometheus:   prometheus,
-	}
-	manifests, err := Generate(cfg)
+	manifests, err := out.generateManifests(seed, cfg)
 	if err != nil {
 		return err
 	}
-	if groups <= 0 {
+	index := manifestIndex{Seed: seed}
+	if out.groups <= 0 {
 		for i, manifest := range manifests {
-			err = manifest.Save(filepath.Join(dir, fmt.Sprintf("gen-%04d.toml", i)))
+			file, err := out.save(manifest, filepath.Join(out.dir, fmt.Sprintf("gen-%04d", i)))
 			if err != nil {
 				return err
 			}
+			index.add(file, manifest, nil, nil)
 		}
 	} else {
-		groupSize := int(math.Ceil(float64(len(manifests)) / float64(groups)))
-		for g := 0; g < groups; g++ {
+		for g := 0; g < out.groups; g++ {
+			// without an offset, the groups split a single batch; with one,
+			// each group takes its share of its own batch
+			var groupSeed *int64
+			if out.groupSeedOffset != 0 {
+				s := seed + int64(g)*out.groupSeedOffset
+				logger.Info("Generating group", "group", g, "seed", s)
+				groupSeed = &s
+				if g > 0 {
+					if manifests, err = out.generateManifests(s, cfg); err != nil {
+						return err
+					}
+				}
+			}
+			groupSize := int(math.Ceil(float64(len(manifests)) / float64(out.groups)))
 			for i := 0; i < groupSize && g*groupSize+i < len(manifests); i++ {
 				manifest := manifests[g*groupSize+i]
-				err = manifest.Save(filepath.Join(dir, fmt.Sprintf("gen-group%02d-%04d.toml", g, i)))
+				file, err := out.save(manifest, filepath.Join(out.dir, fmt.Sprintf("gen-group%02d-%04d", g, i)))
 				if err != nil {
 					return err
 				}
+				group := g
+				index.add(file, manifest, &group, groupSeed)
 			}
 		}
 	}
+	if out.index {
+		return index.save(filepath.Join(out.dir, indexFile))
+	}
+	return nil
+}
+
+// generateManifests generates the manifests from seed, keeping the ones
+// selected by out.
+func (out outputOptions) generateManifests(seed int64, cfg *generateConfig) ([]e2e.Manifest, error) {
+	cfg.randSource = rand.New(rand.NewSource(seed)) //nolint:gosec
+	manifests, err := Generate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	manifests, err = filterByNodeCount(manifests, out.minNodes, out.maxNodes)
+	if err != nil {
+		return nil, err
+	}
+	if out.limit > 0 && len(manifests) > out.limit {
+		manifests = manifests[:out.limit]
+	}
+	return manifests, nil
+}
+
+// indexFile is the name of the file describing the generated manifests,
+// written in the output directory with --index.
+const indexFile = "index.json"
+
+// manifestIndex describes a batch of generated manifests, so that subsets can
+// be picked by attribute without parsing the manifests.
+type manifestIndex struct {
+	// Seed is the seed the batch was generated with.
+	Seed      int64             `json:"seed"`
+	Manifests []manifestSummary `json:"manifests"`
+}
+
+// manifestSummary holds the main attributes of a generated manifest.
+type manifestSummary struct {
+	// File is the name of the manifest file, relative to the index.
+	File string `json:"file"`
+	// Group is the group of the manifest, if generated with --groups.
+	Group *int `json:"group,omitempty"`
+	// Seed is the seed of the manifest's group, if generated with
+	// --group-seed-offset.
+	Seed  *int64 `json:"seed,omitempty"`
+	Nodes int    `json:"nodes"`
+	// Versions is the number of nodes running each version of CometBFT,
+	// the version under test being "local".
+	Versions   map[string]int `json:"versions"`
+	Prometheus bool           `json:"prometheus"`
+}
+
+func (index *manifestIndex) add(file string, manifest e2e.Manifest, group *int, seed *int64) {
+	versions := make(map[string]int)
+	for _, node := range manifest.Nodes {
+		version := node.Version
+		if version == "" {
+			version = "local"
+		}
+		versions[version]++
+	}
+	index.Manifests = append(index.Manifests, manifestSummary{
+		File:       filepath.Base(file),
+		Group:      group,
+		Seed:       seed,
+		Nodes:      len(manifest.Nodes),
+		Versions:   versions,
+		Prometheus: manifest.Prometheus,
+	})
+}
+
+func (index *manifestIndex) save(file string) error {
+	bz, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(bz, '\n'), 0o644) //nolint:gosec
+}
+
+// filterByNodeCount keeps the manifests whose number of nodes is within
+// [minNodes, maxNodes]; a zero bound is ignored.
+func filterByNodeCount(manifests []e2e.Manifest, minNodes, maxNodes int) ([]e2e.Manifest, error) {
+	if minNodes <= 0 && maxNodes <= 0 {
+		return manifests, nil
+	}
+	filtered := make([]e2e.Manifest, 0, len(manifests))
+	for _, manifest := range manifests {
+		n := len(manifest.Nodes)
+		if (minNodes > 0 && n < minNodes) || (maxNodes > 0 && n > maxNodes) {
+			continue
+		}
+		filtered = append(filtered, manifest)
+	}
+	if len(filtered) == 0 {
+		return nil, fmt.Errorf("none of the %d generated testnets has between %d and %d nodes",
+			len(manifests), minNodes, maxNodes)
+	}
+	return filtered, nil
+}
+
+// validateManifests loads the generated manifests in dir and builds a testnet
+// from each of them, like the runner does, logging the ones which are
+// invalid. An error is returned if any manifest is invalid.
+func validateManifests(dir string) error {
+	var files []string
+	for _, pattern := range []string{"gen-*.toml", "gen-*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		files = append(files, matches...)
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no generated manifests found in %s", dir)
+	}
+	sort.Strings(files)
+
+	invalid := 0
+	for _, file := range files {
+		if err := validateManifest(file); err != nil {
+			logger.Error("Invalid manifest", "file", file, "err", err)
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d manifests in %s are invalid", invalid, len(files), dir)
+	}
+	logger.Info("All manifests are valid", "dir", dir, "manifests", len(files))
 	return nil
 }
 
+// validateManifest loads the manifest in file and builds a testnet from it.
+func validateManifest(file string) error {
+	m, err := e2e.LoadManifest(file)
+	if err != nil {
+		return err
+	}
+	infra, err := e2e.NewDockerInfrastructureData(m)
+	if err != nil {
+		return err
+	}
+	_, err = e2e.NewTestnetFromManifest(m, file, infra)
+	return err
+}
+
 // Run runs the CLI.
 func (cli *CLI) Run() {
 	if err := cli.root.Execute(); err != nil {
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"maps"
 	"math/rand"
+	"slices"
 	"sort"
 )
 
@@ -59,9 +61,10 @@ func (uc uniformChoice) Choose(r *rand.Rand) any {
 type probSetChoice map[string]float64
 
 func (pc probSetChoice) Choose(r *rand.Rand) []string {
+	// the items are drawn in order, so that the choice only depends on r
 	choices := []string{}
-	for item, prob := range pc {
-		if r.Float64() <= prob {
+	for _, item := range slices.Sorted(maps.Keys(pc)) {
+		if r.Float64() <= pc[item] {
 			choices = append(choices, item)
 		}
 	}
@@ -93,6 +96,10 @@ func (wc weightedChoice) Choose(r *rand.Rand) any {
 		total += int(weight)
 		choices = append(choices, choice)
 	}
+	// the choices are walked in order, so that the choice only depends on r
+	sort.Slice(choices, func(i, j int) bool {
+		return fmt.Sprint(choices[i]) < fmt.Sprint(choices[j])
+	})
 
 	rem := r.Intn(total)
 	for _, choice := range choices {
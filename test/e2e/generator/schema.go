@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	e2e "github.com/cometbft/cometbft/test/e2e/pkg"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// newSchemaCmd returns the command printing the JSON schema of the manifests.
+func newSchemaCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema [--out file]",
+		Short: "Print the JSON schema of the testnet manifests",
+		Long: `Print the JSON schema of the testnet manifests, as written with --format json,
+e.g. to validate hand-written manifests in an editor. The schema is derived from
+the manifest type, so it is always in sync with the generator.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			out, err := cmd.Flags().GetString("out")
+			if err != nil {
+				return err
+			}
+			bz, err := json.MarshalIndent(manifestSchema(), "", "  ")
+			if err != nil {
+				return err
+			}
+			bz = append(bz, '\n')
+			if out == "" {
+				_, err = os.Stdout.Write(bz)
+				return err
+			}
+			return os.WriteFile(out, bz, 0o644) //nolint:gosec
+		},
+	}
+	cmd.Flags().String("out", "", "File to write the schema to, instead of stdout")
+	return cmd
+}
+
+// manifestSchema returns the JSON schema of e2e.Manifest, as encoded by
+// Manifest.SaveJSON.
+func manifestSchema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(e2e.Manifest{}))
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "CometBFT end-to-end testnet manifest"
+	return schema
+}
+
+// typeSchema returns the JSON schema of the values of type t, encoded in TOML
+// and converted to JSON. Struct fields are named after their toml tag, and
+// properties which are not fields are rejected.
+func typeSchema(t reflect.Type) map[string]any {
+	if t == durationType {
+		return map[string]any{"type": "string", "description": `a duration, e.g. "1s" or "500ms"`}
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = typeSchema(field.Type)
+		}
+		return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+	default:
+		panic(fmt.Sprintf("no JSON schema for manifest values of type %v", t))
+	}
+}
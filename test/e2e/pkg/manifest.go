@@ -1,8 +1,11 @@
 package e2e
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -16,6 +19,10 @@ type Manifest struct {
 	// InitialHeight specifies the initial block height, set in genesis. Defaults to 1.
 	InitialHeight int64 `toml:"initial_height"`
 
+	// GenesisTime specifies the genesis time. Defaults to the time the
+	// testnet is set up.
+	GenesisTime *time.Time `toml:"genesis_time"`
+
 	// InitialState is an initial set of key/value pairs for the application,
 	// set in genesis. Defaults to nothing.
 	InitialState map[string]string `toml:"initial_state"`
@@ -208,12 +215,88 @@ func (m Manifest) Save(file string) error {
 	return toml.NewEncoder(f).Encode(m)
 }
 
-// LoadManifest loads a testnet manifest from a file.
+// SaveJSON saves the testnet manifest to a file as JSON. The manifest is
+// first encoded as TOML and then converted, so that the JSON output has the
+// same keys and field set as the file written by Save.
+func (m Manifest) SaveJSON(file string) error {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(m); err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	fields := map[string]any{}
+	if _, err := toml.NewDecoder(&buf).Decode(&fields); err != nil {
+		return fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	bz, err := json.MarshalIndent(fields, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest to JSON: %w", err)
+	}
+	if err := os.WriteFile(file, bz, 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("failed to write manifest file %q: %w", file, err)
+	}
+	return nil
+}
+
+// LoadManifest loads a testnet manifest from a file. Files with a .json
+// extension are read as written by SaveJSON, all others as TOML.
 func LoadManifest(file string) (Manifest, error) {
 	manifest := Manifest{}
+	if filepath.Ext(file) == ".json" {
+		if err := loadManifestJSON(file, &manifest); err != nil {
+			return manifest, fmt.Errorf("failed to load testnet manifest %q: %w", file, err)
+		}
+		return manifest, nil
+	}
 	_, err := toml.DecodeFile(file, &manifest)
 	if err != nil {
 		return manifest, fmt.Errorf("failed to load testnet manifest %q: %w", file, err)
 	}
 	return manifest, nil
 }
+
+// loadManifestJSON reverses SaveJSON: the JSON fields are converted back to
+// TOML, so that they are decoded with the same keys and types as by
+// LoadManifest.
+func loadManifestJSON(file string, manifest *Manifest) error {
+	bz, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(bz))
+	dec.UseNumber()
+	fields := map[string]any{}
+	if err := dec.Decode(&fields); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(fromJSONNumbers(fields)); err != nil {
+		return err
+	}
+	_, err = toml.NewDecoder(&buf).Decode(manifest)
+	return err
+}
+
+// fromJSONNumbers replaces the json.Number values in v with int64 or float64
+// values, which TOML encodes as integers or floats respectively.
+func fromJSONNumbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = fromJSONNumbers(e)
+		}
+		return v
+	case []any:
+		for i, e := range v {
+			v[i] = fromJSONNumbers(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
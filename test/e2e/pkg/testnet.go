@@ -72,6 +72,7 @@ type Testnet struct {
 	Dir                                                  string
 	IP                                                   *net.IPNet
 	InitialHeight                                        int64
+	GenesisTime                                          time.Time
 	InitialState                                         map[string]string
 	Validators                                           map[*Node]int64
 	ValidatorUpdates                                     map[int64]map[*Node]int64
@@ -193,6 +194,9 @@ func NewTestnetFromManifest(manifest Manifest, file string, ifd InfrastructureDa
 	if manifest.InitialHeight > 0 {
 		testnet.InitialHeight = manifest.InitialHeight
 	}
+	if manifest.GenesisTime != nil {
+		testnet.GenesisTime = *manifest.GenesisTime
+	}
 	if testnet.KeyType == "" {
 		testnet.KeyType = ed25519.KeyType
 	}
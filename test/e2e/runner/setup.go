@@ -147,6 +147,9 @@ func MakeGenesis(testnet *e2e.Testnet) (types.GenesisDoc, error) {
 		ConsensusParams: types.DefaultConsensusParams(),
 		InitialHeight:   testnet.InitialHeight,
 	}
+	if !testnet.GenesisTime.IsZero() {
+		genesis.GenesisTime = testnet.GenesisTime
+	}
 	// set the app version to 1
 	genesis.ConsensusParams.Version.App = 1
 	genesis.ConsensusParams.Evidence.MaxAgeNumBlocks = e2e.EvidenceAgeHeight
@@ -2,16 +2,31 @@ package types
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/time/rate"
 
 	"github.com/cometbft/cometbft/abci/types"
 	"github.com/cometbft/cometbft/libs/log"
 	cmtpubsub "github.com/cometbft/cometbft/libs/pubsub"
 	"github.com/cometbft/cometbft/libs/service"
+	cmtsync "github.com/cometbft/cometbft/libs/sync"
 )
 
 const defaultCapacity = 0
 
+// ErrPublishTimeout is returned by PublishSync when a subscriber did not accept
+// the event in time.
+var ErrPublishTimeout = errors.New("timed out waiting for subscribers to accept the event")
+
+// ErrPublishRateLimited is returned by the Publish methods when events of the
+// type are published faster than allowed by WithPublishRateLimit. The event
+// is dropped.
+var ErrPublishRateLimited = errors.New("event publish rate limit exceeded")
+
 type EventBusSubscriber interface {
 	Subscribe(ctx context.Context, subscriber string, query cmtpubsub.Query, outCapacity ...int) (Subscription, error)
 	Unsubscribe(ctx context.Context, subscriber string, query cmtpubsub.Query) error
@@ -32,19 +47,72 @@ type Subscription interface {
 // EventBus to ensure correct data types.
 type EventBus struct {
 	service.BaseService
-	pubsub *cmtpubsub.Server
+	pubsub  *cmtpubsub.Server
+	metrics *Metrics
+
+	// event type -> rate limiter; only written by the options
+	rateLimits map[string]*rate.Limiter
+
+	// subscriber -> subscription created by SubscribeUnfiltered
+	unfilteredMtx cmtsync.RWMutex
+	unfiltered    map[string]*UnfilteredSubscription
+}
+
+// EventBusOption sets an optional parameter on the EventBus.
+type EventBusOption func(*EventBus)
+
+// WithMetrics sets the metrics.
+func WithMetrics(metrics *Metrics) EventBusOption {
+	return func(b *EventBus) { b.metrics = metrics }
+}
+
+// WithPublishRateLimit limits the events of the given type to eventsPerSecond
+// on average, with bursts of up to burst events. Events of the type published
+// beyond the limit are dropped, and the Publish methods return
+// ErrPublishRateLimited rather than blocking. Events are not rate limited by
+// default.
+func WithPublishRateLimit(eventType string, eventsPerSecond, burst int) EventBusOption {
+	return func(b *EventBus) {
+		if b.rateLimits == nil {
+			b.rateLimits = make(map[string]*rate.Limiter)
+		}
+		b.rateLimits[eventType] = rate.NewLimiter(rate.Limit(eventsPerSecond), burst)
+	}
 }
 
 // NewEventBus returns a new event bus.
-func NewEventBus() *EventBus {
-	return NewEventBusWithBufferCapacity(defaultCapacity)
+func NewEventBus(options ...EventBusOption) *EventBus {
+	return NewEventBusWithBufferCapacity(defaultCapacity, options...)
 }
 
 // NewEventBusWithBufferCapacity returns a new event bus with the given buffer capacity.
-func NewEventBusWithBufferCapacity(cap int) *EventBus {
+func NewEventBusWithBufferCapacity(cap int, options ...EventBusOption) *EventBus {
+	return newEventBus(cap, 0, options...)
+}
+
+// NewEventBusWithWorkers returns a new event bus whose events are pushed to
+// the subscribers by a pool of the given number of goroutines, rather than by
+// the goroutine matching them against the queries. Events are still delivered
+// to each subscription in the order they were published. See
+// cmtpubsub.Workers.
+func NewEventBusWithWorkers(workers int, options ...EventBusOption) *EventBus {
+	return newEventBus(defaultCapacity, workers, options...)
+}
+
+func newEventBus(cap, workers int, options ...EventBusOption) *EventBus {
+	b := &EventBus{metrics: NopMetrics()}
+	for _, option := range options {
+		option(b)
+	}
 	// capacity could be exposed later if needed
-	pubsub := cmtpubsub.NewServer(cmtpubsub.BufferCapacity(cap))
-	b := &EventBus{pubsub: pubsub}
+	b.pubsub = cmtpubsub.NewServer(
+		cmtpubsub.BufferCapacity(cap),
+		cmtpubsub.Workers(workers),
+		cmtpubsub.OutOfCapacityCallback(func(clientID, query string) {
+			b.Logger.Info("Subscription canceled: subscriber too slow", "subscriber", clientID, "query", query)
+			b.metrics.SlowSubscribers.Add(1)
+		}),
+	)
 	b.BaseService = *service.NewBaseService(nil, "EventBus", b)
 	return b
 }
@@ -62,6 +130,14 @@ func (b *EventBus) OnStop() {
 	if err := b.pubsub.Stop(); err != nil {
 		b.pubsub.Logger.Error("error trying to stop eventBus", "error", err)
 	}
+	// like the pubsub subscriptions, the unfiltered ones are canceled
+	// without an error
+	b.unfilteredMtx.Lock()
+	defer b.unfilteredMtx.Unlock()
+	for subscriber, sub := range b.unfiltered {
+		sub.cancel(nil)
+		delete(b.unfiltered, subscriber)
+	}
 }
 
 func (b *EventBus) NumClients() int {
@@ -72,13 +148,93 @@ func (b *EventBus) NumClientSubscriptions(clientID string) int {
 	return b.pubsub.NumClientSubscriptions(clientID)
 }
 
+// SubscriptionInfo describes an active EventBus subscription.
+type SubscriptionInfo = cmtpubsub.SubscriptionInfo
+
+// unfilteredQuery is the query reported by Subscriptions for the
+// subscriptions created by SubscribeUnfiltered.
+const unfilteredQuery = "<unfiltered>"
+
+// Subscriptions returns a snapshot of all active subscriptions, ordered by
+// subscriber and query, along with the capacity of their buffer and the
+// number of events buffered for each of them. It is meant for diagnostics,
+// e.g. finding clients that leak subscriptions or fail to consume events, and
+// is safe to call at any time.
+func (b *EventBus) Subscriptions() []SubscriptionInfo {
+	infos := b.pubsub.Subscriptions()
+
+	b.unfilteredMtx.RLock()
+	defer b.unfilteredMtx.RUnlock()
+	if len(b.unfiltered) == 0 {
+		return infos
+	}
+	for subscriber, sub := range b.unfiltered {
+		infos = append(infos, SubscriptionInfo{
+			ClientID:  subscriber,
+			Query:     unfilteredQuery,
+			BufferLen: len(sub.out),
+			BufferCap: cap(sub.out),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].ClientID != infos[j].ClientID {
+			return infos[i].ClientID < infos[j].ClientID
+		}
+		return infos[i].Query < infos[j].Query
+	})
+	return infos
+}
+
 func (b *EventBus) Subscribe(
 	ctx context.Context,
 	subscriber string,
 	query cmtpubsub.Query,
 	outCapacity ...int,
 ) (Subscription, error) {
-	return b.pubsub.Subscribe(ctx, subscriber, query, outCapacity...)
+	sub, err := b.pubsub.Subscribe(ctx, subscriber, query, outCapacity...)
+	if err != nil {
+		return nil, err
+	}
+	b.metrics.SubscriptionsActive.Add(1)
+	return sub, nil
+}
+
+// SubscribeWithPolicy is like Subscribe, but sets what happens when the
+// subscriber does not pull the events fast enough, e.g. dropping the oldest
+// buffered event so that a slow client always gets the latest state, rather
+// than canceling the subscription. See cmtpubsub.OverflowPolicy.
+func (b *EventBus) SubscribeWithPolicy(
+	ctx context.Context,
+	subscriber string,
+	query cmtpubsub.Query,
+	outCapacity int,
+	policy cmtpubsub.OverflowPolicy,
+) (Subscription, error) {
+	sub, err := b.pubsub.SubscribeWithPolicy(ctx, subscriber, query, outCapacity, policy)
+	if err != nil {
+		return nil, err
+	}
+	b.metrics.SubscriptionsActive.Add(1)
+	return sub, nil
+}
+
+// SubscribeMulti subscribes to several queries with a single subscription,
+// which receives the events matching any of them, once each. The query an
+// event matched is returned by its Query method. See
+// cmtpubsub.Server.SubscribeMulti for how the subscription behaves.
+func (b *EventBus) SubscribeMulti(
+	ctx context.Context,
+	subscriber string,
+	queries []cmtpubsub.Query,
+	outCapacity int,
+) (Subscription, error) {
+	sub, err := b.pubsub.SubscribeMulti(ctx, subscriber, queries, outCapacity)
+	if err != nil {
+		return nil, err
+	}
+	// like NumClientSubscriptions, the metric counts one subscription per query
+	b.metrics.SubscriptionsActive.Add(float64(len(queries)))
+	return sub, nil
 }
 
 // SubscribeUnbuffered can be used for a local consensus explorer and synchronous
@@ -88,21 +244,236 @@ func (b *EventBus) SubscribeUnbuffered(
 	subscriber string,
 	query cmtpubsub.Query,
 ) (Subscription, error) {
-	return b.pubsub.SubscribeUnbuffered(ctx, subscriber, query)
+	sub, err := b.pubsub.SubscribeUnbuffered(ctx, subscriber, query)
+	if err != nil {
+		return nil, err
+	}
+	b.metrics.SubscriptionsActive.Add(1)
+	return sub, nil
+}
+
+// UnfilteredEvent is an event delivered to an UnfilteredSubscription, tagged
+// with its type.
+type UnfilteredEvent struct {
+	Type   string
+	Data   TMEventData
+	Events map[string][]string
+}
+
+// UnfilteredSubscription is a subscription created by SubscribeUnfiltered.
+type UnfilteredSubscription struct {
+	out      chan UnfilteredEvent
+	canceled chan struct{}
+	mtx      cmtsync.RWMutex
+	err      error
+}
+
+// Out returns the channel onto which the events are published. It is not
+// closed when the subscription is canceled.
+func (s *UnfilteredSubscription) Out() <-chan UnfilteredEvent {
+	return s.out
+}
+
+// Canceled returns a channel that's closed when the subscription is
+// terminated, either because the subscriber unsubscribed or because it was
+// too slow.
+func (s *UnfilteredSubscription) Canceled() <-chan struct{} {
+	return s.canceled
+}
+
+// Err returns why the subscription was canceled: cmtpubsub.ErrUnsubscribed or
+// cmtpubsub.ErrOutOfCapacity. It returns nil while the subscription is
+// active, and once the EventBus is stopped.
+func (s *UnfilteredSubscription) Err() error {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.err
+}
+
+// cancel cancels the subscription with the given error, unless it was
+// already canceled.
+func (s *UnfilteredSubscription) cancel(err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	select {
+	case <-s.canceled:
+		return
+	default:
+	}
+	s.err = err
+	close(s.canceled)
+}
+
+// SubscribeUnfiltered subscribes to every event published on the bus, for
+// in-process consumers which want all the events anyway. The events are
+// pushed to the subscription by the publisher, once handed over to the pubsub
+// server, without being matched against a query. Each event is tagged with
+// its type.
+//
+// Like a buffered subscription, the subscription is canceled with
+// cmtpubsub.ErrOutOfCapacity if the subscriber does not pull the events fast
+// enough. It is canceled by UnsubscribeAll. It is not counted by NumClients
+// and NumClientSubscriptions, but is listed by Subscriptions.
+//
+// An error is returned if the context is canceled or if the subscriber
+// already has an unfiltered subscription. Panics if outCapacity is less than
+// or equal to zero.
+func (b *EventBus) SubscribeUnfiltered(
+	ctx context.Context,
+	subscriber string,
+	outCapacity int,
+) (*UnfilteredSubscription, error) {
+	if outCapacity <= 0 {
+		panic("Negative or zero capacity")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.unfilteredMtx.Lock()
+	defer b.unfilteredMtx.Unlock()
+	if _, ok := b.unfiltered[subscriber]; ok {
+		return nil, cmtpubsub.ErrAlreadySubscribed
+	}
+	sub := &UnfilteredSubscription{
+		out:      make(chan UnfilteredEvent, outCapacity),
+		canceled: make(chan struct{}),
+	}
+	if b.unfiltered == nil {
+		b.unfiltered = make(map[string]*UnfilteredSubscription)
+	}
+	b.unfiltered[subscriber] = sub
+	b.metrics.SubscriptionsActive.Add(1)
+	return sub, nil
+}
+
+// removeUnfiltered cancels the unfiltered subscription of the subscriber with
+// the given error and reports whether it did. If sub is not nil, the
+// subscription is only removed if it is still sub.
+func (b *EventBus) removeUnfiltered(subscriber string, sub *UnfilteredSubscription, reason error) bool {
+	b.unfilteredMtx.Lock()
+	defer b.unfilteredMtx.Unlock()
+	current, ok := b.unfiltered[subscriber]
+	if !ok || (sub != nil && current != sub) {
+		return false
+	}
+	current.cancel(reason)
+	delete(b.unfiltered, subscriber)
+	b.metrics.SubscriptionsActive.Add(-1)
+	return true
+}
+
+// deliverUnfiltered pushes the event to the unfiltered subscriptions,
+// canceling those which are out of capacity.
+func (b *EventBus) deliverUnfiltered(eventType string, eventData TMEventData, events map[string][]string) {
+	b.unfilteredMtx.RLock()
+	if len(b.unfiltered) == 0 {
+		b.unfilteredMtx.RUnlock()
+		return
+	}
+	event := UnfilteredEvent{Type: eventType, Data: eventData, Events: events}
+	var slow map[string]*UnfilteredSubscription
+	for subscriber, sub := range b.unfiltered {
+		select {
+		case sub.out <- event:
+		default:
+			if slow == nil {
+				slow = make(map[string]*UnfilteredSubscription)
+			}
+			slow[subscriber] = sub
+		}
+	}
+	b.unfilteredMtx.RUnlock()
+
+	for subscriber, sub := range slow {
+		if b.removeUnfiltered(subscriber, sub, cmtpubsub.ErrOutOfCapacity) {
+			b.Logger.Info("Subscription canceled: subscriber too slow", "subscriber", subscriber, "query", "unfiltered")
+			b.metrics.SlowSubscribers.Add(1)
+		}
+	}
 }
 
 func (b *EventBus) Unsubscribe(ctx context.Context, subscriber string, query cmtpubsub.Query) error {
-	return b.pubsub.Unsubscribe(ctx, subscriber, query)
+	if err := b.pubsub.Unsubscribe(ctx, subscriber, query); err != nil {
+		return err
+	}
+	b.metrics.SubscriptionsActive.Add(-1)
+	return nil
 }
 
+// UnsubscribeAll removes all the subscriptions of the subscriber, including
+// those canceled because it was too slow, in one call. It is safe to call for
+// a subscriber with no subscription, in which case nothing is done and
+// cmtpubsub.ErrSubscriptionNotFound is returned; callers cleaning up after a
+// client which may never have subscribed, such as the WebSocket disconnect
+// handler, should ignore that error.
 func (b *EventBus) UnsubscribeAll(ctx context.Context, subscriber string) error {
-	return b.pubsub.UnsubscribeAll(ctx, subscriber)
+	unfiltered := b.removeUnfiltered(subscriber, nil, cmtpubsub.ErrUnsubscribed)
+	n := b.pubsub.NumClientSubscriptions(subscriber)
+	err := b.pubsub.UnsubscribeAll(ctx, subscriber)
+	if unfiltered && errors.Is(err, cmtpubsub.ErrSubscriptionNotFound) {
+		// the subscriber only had an unfiltered subscription
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	b.metrics.SubscriptionsActive.Add(-float64(n))
+	return nil
 }
 
 func (b *EventBus) Publish(eventType string, eventData TMEventData) error {
 	// no explicit deadline for publishing events
 	ctx := context.Background()
-	return b.pubsub.PublishWithEvents(ctx, eventData, map[string][]string{EventTypeKey: {eventType}})
+	return b.publish(ctx, eventType, eventData, map[string][]string{EventTypeKey: {eventType}})
+}
+
+// PublishSync publishes the event and waits until every matching subscriber
+// has accepted it. Subscribers that do not keep up are not unsubscribed;
+// instead, ErrPublishTimeout is returned if any of them could not accept the
+// event within the timeout. Unfiltered subscriptions (see SubscribeUnfiltered)
+// are not waited for. Meant for tests and tooling which must guarantee
+// delivery; the node itself publishes with the Publish* methods.
+func (b *EventBus) PublishSync(eventType string, eventData TMEventData, timeout time.Duration) error {
+	if err := b.checkRateLimit(eventType); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	events := map[string][]string{EventTypeKey: {eventType}}
+	err := b.pubsub.PublishWithEventsSync(ctx, eventData, events)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrPublishTimeout
+	}
+	if err != nil {
+		return err
+	}
+	b.deliverUnfiltered(eventType, eventData, events)
+	b.metrics.EventsPublished.With("event_type", eventType).Add(1)
+	return nil
+}
+
+// publish publishes the event and records it in the metrics.
+func (b *EventBus) publish(ctx context.Context, eventType string, eventData TMEventData, events map[string][]string) error {
+	if err := b.checkRateLimit(eventType); err != nil {
+		return err
+	}
+	if err := b.pubsub.PublishWithEvents(ctx, eventData, events); err != nil {
+		return err
+	}
+	b.deliverUnfiltered(eventType, eventData, events)
+	b.metrics.EventsPublished.With("event_type", eventType).Add(1)
+	return nil
+}
+
+// checkRateLimit returns ErrPublishRateLimited if an event of the given type
+// cannot be published now because of its rate limit, if any.
+func (b *EventBus) checkRateLimit(eventType string) error {
+	if limiter, ok := b.rateLimits[eventType]; ok && !limiter.Allow() {
+		return ErrPublishRateLimited
+	}
+	return nil
 }
 
 // validateAndStringifyEvents takes a slice of event objects and creates a
@@ -137,7 +508,7 @@ func (b *EventBus) PublishEventNewBlock(data EventDataNewBlock) error {
 	// add predefined new block event
 	events[EventTypeKey] = append(events[EventTypeKey], EventNewBlock)
 
-	return b.pubsub.PublishWithEvents(ctx, data, events)
+	return b.publish(ctx, EventNewBlock, data, events)
 }
 
 func (b *EventBus) PublishEventNewBlockEvents(data EventDataNewBlockEvents) error {
@@ -149,7 +520,7 @@ func (b *EventBus) PublishEventNewBlockEvents(data EventDataNewBlockEvents) erro
 	// add predefined new block event
 	events[EventTypeKey] = append(events[EventTypeKey], EventNewBlockEvents)
 
-	return b.pubsub.PublishWithEvents(ctx, data, events)
+	return b.publish(ctx, EventNewBlockEvents, data, events)
 }
 
 func (b *EventBus) PublishEventNewBlockHeader(data EventDataNewBlockHeader) error {
@@ -182,7 +553,7 @@ func (b *EventBus) PublishEventTx(data EventDataTx) error {
 	events[TxHashKey] = append(events[TxHashKey], fmt.Sprintf("%X", Tx(data.Tx).Hash()))
 	events[TxHeightKey] = append(events[TxHeightKey], fmt.Sprintf("%d", data.Height))
 
-	return b.pubsub.PublishWithEvents(ctx, data, events)
+	return b.publish(ctx, EventTx, data, events)
 }
 
 func (b *EventBus) PublishEventNewRoundStep(data EventDataRoundState) error {
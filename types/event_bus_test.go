@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/metrics"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -406,6 +408,428 @@ func TestEventBusPublish(t *testing.T) {
 	}
 }
 
+func TestEventBusSubscriptions(t *testing.T) {
+	eventBus := NewEventBus()
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	_, err = eventBus.Subscribe(context.Background(), "test", EventQueryNewBlockHeader, 5)
+	require.NoError(t, err)
+
+	err = eventBus.PublishEventNewBlockHeader(EventDataNewBlockHeader{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		subs := eventBus.Subscriptions()
+		return len(subs) == 1 && subs[0].BufferLen == 1
+	}, time.Second, 10*time.Millisecond)
+
+	subs := eventBus.Subscriptions()
+	assert.Equal(t, "test", subs[0].ClientID)
+	assert.Equal(t, EventQueryNewBlockHeader.String(), subs[0].Query)
+	assert.Equal(t, 5, subs[0].BufferCap)
+
+	// unfiltered subscriptions are listed too
+	_, err = eventBus.SubscribeUnfiltered(context.Background(), "indexer", 3)
+	require.NoError(t, err)
+	err = eventBus.PublishEventNewBlockHeader(EventDataNewBlockHeader{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		subs := eventBus.Subscriptions()
+		return len(subs) == 2 && subs[1].BufferLen == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, SubscriptionInfo{ClientID: "indexer", Query: unfilteredQuery, BufferLen: 1, BufferCap: 3},
+		eventBus.Subscriptions()[0])
+}
+
+func TestEventBusSubscribeMulti(t *testing.T) {
+	eventBus := NewEventBus()
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	sub, err := eventBus.SubscribeMulti(context.Background(), "test",
+		[]cmtpubsub.Query{EventQueryNewBlock, EventQueryTx}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, eventBus.NumClientSubscriptions("test"))
+
+	block := MakeBlock(1, []Tx{}, nil, []Evidence{})
+	ps, err := block.MakePartSet(BlockPartSizeBytes)
+	require.NoError(t, err)
+	err = eventBus.PublishEventNewBlock(EventDataNewBlock{
+		Block:   block,
+		BlockID: BlockID{Hash: block.Hash(), PartSetHeader: ps.Header()},
+	})
+	require.NoError(t, err)
+	err = eventBus.PublishEventTx(EventDataTx{abci.TxResult{Height: 1, Tx: Tx("foo")}})
+	require.NoError(t, err)
+
+	for _, want := range []cmtpubsub.Query{EventQueryNewBlock, EventQueryTx} {
+		select {
+		case msg := <-sub.Out():
+			assert.Equal(t, want.String(), msg.Query())
+			switch want {
+			case EventQueryNewBlock:
+				assert.IsType(t, EventDataNewBlock{}, msg.Data())
+			case EventQueryTx:
+				assert.IsType(t, EventDataTx{}, msg.Data())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("did not receive the %s event after 1 sec.", want)
+		}
+	}
+}
+
+func TestEventBusSubscribeWithPolicy(t *testing.T) {
+	eventBus := NewEventBus()
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	sub, err := eventBus.SubscribeWithPolicy(ctx, "ws-client", EventQueryNewRound, 2, cmtpubsub.DropOldest)
+	require.NoError(t, err)
+	for round := int32(0); round < 5; round++ {
+		require.NoError(t, eventBus.PublishEventNewRound(EventDataNewRound{Round: round}))
+	}
+
+	// the bus is unbuffered, so this returns once the rounds were delivered
+	require.NoError(t, eventBus.PublishEventVote(EventDataVote{}))
+
+	// the subscriber which didn't keep up gets the latest rounds
+	var rounds []int32
+	for len(sub.Out()) > 0 {
+		rounds = append(rounds, (<-sub.Out()).Data().(EventDataNewRound).Round)
+	}
+	assert.Equal(t, []int32{3, 4}, rounds)
+	assert.NoError(t, sub.Err())
+	assert.Equal(t, 1, eventBus.NumClientSubscriptions("ws-client"))
+}
+
+func TestEventBusUnsubscribeAll(t *testing.T) {
+	eventBus := NewEventBus()
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	var subs []Subscription
+	for _, q := range []cmtpubsub.Query{EventQueryNewBlock, EventQueryVote} {
+		sub, err := eventBus.Subscribe(ctx, "client", q, 5)
+		require.NoError(t, err)
+		subs = append(subs, sub)
+	}
+	sub, err := eventBus.SubscribeMulti(ctx, "client", []cmtpubsub.Query{EventQueryTx, EventQueryNewRound}, 5)
+	require.NoError(t, err)
+	subs = append(subs, sub)
+	other, err := eventBus.Subscribe(ctx, "other", EventQueryNewBlock, 5)
+	require.NoError(t, err)
+	require.Equal(t, 4, eventBus.NumClientSubscriptions("client"))
+
+	err = eventBus.UnsubscribeAll(ctx, "client")
+	require.NoError(t, err)
+	for _, sub := range subs {
+		select {
+		case <-sub.Canceled():
+			assert.Equal(t, cmtpubsub.ErrUnsubscribed, sub.Err())
+		case <-time.After(time.Second):
+			t.Fatal("expected the subscription to be canceled")
+		}
+	}
+	assert.Zero(t, eventBus.NumClientSubscriptions("client"))
+	assert.Equal(t, 1, eventBus.NumClients())
+	assert.NoError(t, other.Err())
+
+	// nothing left to unsubscribe
+	err = eventBus.UnsubscribeAll(ctx, "client")
+	require.ErrorIs(t, err, cmtpubsub.ErrSubscriptionNotFound)
+	err = eventBus.UnsubscribeAll(ctx, "never-subscribed")
+	require.ErrorIs(t, err, cmtpubsub.ErrSubscriptionNotFound)
+}
+
+func TestEventBusSubscribeUnfiltered(t *testing.T) {
+	eventBus := NewEventBus()
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	sub, err := eventBus.SubscribeUnfiltered(ctx, "indexer", 10)
+	require.NoError(t, err)
+	_, err = eventBus.SubscribeUnfiltered(ctx, "indexer", 10)
+	require.ErrorIs(t, err, cmtpubsub.ErrAlreadySubscribed)
+	slow, err := eventBus.SubscribeUnfiltered(ctx, "slow", 1)
+	require.NoError(t, err)
+
+	require.NoError(t, eventBus.PublishEventVote(EventDataVote{}))
+	require.NoError(t, eventBus.PublishEventTx(EventDataTx{TxResult: abci.TxResult{Height: 1}}))
+	require.NoError(t, eventBus.PublishSync(EventNewRound, EventDataNewRound{}, time.Second))
+	for _, eventType := range []string{EventVote, EventTx, EventNewRound} {
+		event := <-sub.Out()
+		assert.Equal(t, eventType, event.Type)
+		assert.Equal(t, []string{eventType}, event.Events[EventTypeKey])
+	}
+	assert.NoError(t, sub.Err())
+
+	// the slow subscriber only had room for the first event
+	select {
+	case <-slow.Canceled():
+		assert.Equal(t, cmtpubsub.ErrOutOfCapacity, slow.Err())
+	default:
+		t.Fatal("expected the slow subscription to be canceled")
+	}
+	_, err = eventBus.SubscribeUnfiltered(ctx, "slow", 1)
+	require.NoError(t, err)
+
+	// the subscriber only has an unfiltered subscription
+	require.NoError(t, eventBus.UnsubscribeAll(ctx, "indexer"))
+	<-sub.Canceled()
+	assert.Equal(t, cmtpubsub.ErrUnsubscribed, sub.Err())
+	require.NoError(t, eventBus.PublishEventVote(EventDataVote{}))
+	assert.Empty(t, sub.Out())
+	err = eventBus.UnsubscribeAll(ctx, "indexer")
+	require.ErrorIs(t, err, cmtpubsub.ErrSubscriptionNotFound)
+}
+
+func TestEventBusPublishSync(t *testing.T) {
+	eventBus := NewEventBus()
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	sub, err := eventBus.Subscribe(ctx, "reader", EventQueryNewBlockHeader, 1)
+	require.NoError(t, err)
+
+	// buffered subscriber with room: delivered
+	err = eventBus.PublishSync(EventNewBlockHeader, EventDataNewBlockHeader{}, time.Second)
+	require.NoError(t, err)
+	// the buffer is now full, but the subscriber is not unsubscribed
+	err = eventBus.PublishSync(EventNewBlockHeader, EventDataNewBlockHeader{}, 10*time.Millisecond)
+	require.ErrorIs(t, err, ErrPublishTimeout)
+	require.NoError(t, sub.Err())
+	<-sub.Out()
+
+	// zero-capacity subscriber which never reads
+	_, err = eventBus.SubscribeUnbuffered(ctx, "stuck", EventQueryNewBlockHeader)
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = eventBus.PublishSync(EventNewBlockHeader, EventDataNewBlockHeader{}, 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrPublishTimeout)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	// the other subscriber still got the event
+	select {
+	case <-sub.Out():
+	case <-time.After(time.Second):
+		t.Fatal("expected the reading subscriber to receive the event")
+	}
+}
+
+func TestEventBusWorkers(t *testing.T) {
+	eventBus := NewEventBusWithWorkers(4)
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	const (
+		numClients = 10
+		numEvents  = 50
+	)
+	ctx := context.Background()
+	subs := make([]Subscription, numClients)
+	for i := range subs {
+		subs[i], err = eventBus.Subscribe(ctx, fmt.Sprintf("client-%d", i), EventQueryNewRound, numEvents)
+		require.NoError(t, err)
+	}
+	slow, err := eventBus.Subscribe(ctx, "slow", EventQueryNewRound, 1)
+	require.NoError(t, err)
+
+	for i := 0; i < numEvents; i++ {
+		err := eventBus.PublishEventNewRound(EventDataNewRound{Round: int32(i)})
+		require.NoError(t, err)
+	}
+
+	// every subscriber gets the events in the order they were published
+	for _, sub := range subs {
+		for i := 0; i < numEvents; i++ {
+			select {
+			case msg := <-sub.Out():
+				assert.Equal(t, int32(i), msg.Data().(EventDataNewRound).Round)
+			case <-time.After(time.Second):
+				t.Fatalf("did not receive event %d after 1 sec.", i)
+			}
+		}
+	}
+
+	// the slow subscriber, which never reads, is canceled by its worker
+	select {
+	case <-slow.Canceled():
+		assert.Equal(t, cmtpubsub.ErrOutOfCapacity, slow.Err())
+	case <-time.After(time.Second):
+		t.Fatal("expected the slow subscription to be canceled")
+	}
+	// the other subscribers are not affected
+	err = eventBus.PublishSync(EventNewRound, EventDataNewRound{}, time.Second)
+	require.NoError(t, err)
+	assert.Len(t, eventBus.Subscriptions(), numClients)
+}
+
+func TestEventBusPublishRateLimit(t *testing.T) {
+	const burst = 3
+	eventBus := NewEventBus(WithPublishRateLimit(EventTx, 1, burst))
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	txs, err := eventBus.Subscribe(ctx, "txs", EventQueryTx, 10)
+	require.NoError(t, err)
+	blocks, err := eventBus.Subscribe(ctx, "blocks", EventQueryNewBlockHeader, 10)
+	require.NoError(t, err)
+
+	// the events beyond the burst are dropped
+	for i := 0; i < burst+2; i++ {
+		err := eventBus.PublishEventTx(EventDataTx{abci.TxResult{Height: 1, Index: uint32(i), Tx: Tx("foo")}})
+		if i < burst {
+			require.NoError(t, err)
+		} else {
+			require.ErrorIs(t, err, ErrPublishRateLimited)
+		}
+	}
+	err = eventBus.PublishSync(EventTx, EventDataTx{}, time.Second)
+	require.ErrorIs(t, err, ErrPublishRateLimited)
+
+	// other event types are not limited
+	for i := 0; i < burst+2; i++ {
+		err := eventBus.PublishEventNewBlockHeader(EventDataNewBlockHeader{})
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return len(txs.Out()) == burst && len(blocks.Out()) == burst+2
+	}, time.Second, 10*time.Millisecond)
+	for i := 0; i < burst; i++ {
+		msg := <-txs.Out()
+		assert.Equal(t, uint32(i), msg.Data().(EventDataTx).Index)
+	}
+}
+
+func TestEventBusMetrics(t *testing.T) {
+	subscriptions := &testGauge{}
+	eventsPublished := &testCounter{}
+	slowSubscribers := &testCounter{}
+	eventBus := NewEventBus(WithMetrics(&Metrics{
+		SubscriptionsActive: subscriptions,
+		EventsPublished:     eventsPublished,
+		SlowSubscribers:     slowSubscribers,
+	}))
+	err := eventBus.Start()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	slow, err := eventBus.Subscribe(ctx, "slow", EventQueryNewBlockHeader, 1)
+	require.NoError(t, err)
+	_, err = eventBus.Subscribe(ctx, "other", EventQueryNewBlockHeader, 5)
+	require.NoError(t, err)
+	_, err = eventBus.Subscribe(ctx, "other", EventQueryVote, 5)
+	require.NoError(t, err)
+	assert.InDelta(t, 3, subscriptions.Value(), 0)
+
+	// the slow subscriber never reads, so the second event cancels it
+	for i := 0; i < 2; i++ {
+		err = eventBus.PublishEventNewBlockHeader(EventDataNewBlockHeader{})
+		require.NoError(t, err)
+	}
+	select {
+	case <-slow.Canceled():
+	case <-time.After(time.Second):
+		t.Fatal("expected the slow subscription to be canceled")
+	}
+	require.Eventually(t, func() bool {
+		return slowSubscribers.Value() == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.InDelta(t, 2, eventsPublished.Value(), 0)
+
+	err = eventBus.Unsubscribe(ctx, "slow", EventQueryNewBlockHeader)
+	require.NoError(t, err)
+	err = eventBus.UnsubscribeAll(ctx, "other")
+	require.NoError(t, err)
+	assert.InDelta(t, 0, subscriptions.Value(), 0)
+}
+
+// testValue holds the value of a test metric.
+type testValue struct {
+	mtx   sync.Mutex
+	value float64
+}
+
+func (v *testValue) Add(delta float64) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.value += delta
+}
+
+func (v *testValue) Value() float64 {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return v.value
+}
+
+// testGauge and testCounter are metrics which ignore labels.
+type (
+	testGauge   struct{ testValue }
+	testCounter struct{ testValue }
+)
+
+func (g *testGauge) With(...string) metrics.Gauge { return g }
+
+func (g *testGauge) Set(value float64) {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	g.value = value
+}
+
+func (c *testCounter) With(...string) metrics.Counter { return c }
+
 func BenchmarkEventBus(b *testing.B) {
 	benchmarks := []struct {
 		name        string
@@ -432,16 +856,105 @@ func BenchmarkEventBus(b *testing.B) {
 
 	for _, bm := range benchmarks {
 		b.Run(bm.name, func(b *testing.B) {
-			benchmarkEventBus(bm.numClients, bm.randQueries, bm.randEvents, b)
+			benchmarkEventBus(bm.numClients, bm.randQueries, bm.randEvents, 0, b)
 		})
 	}
 }
 
-func benchmarkEventBus(numClients int, randQueries bool, randEvents bool, b *testing.B) {
+// BenchmarkEventBusWorkers compares pushing the events to 1000 subscribers
+// from the pubsub server's goroutine, as by default, with pushing them from a
+// pool of workers.
+func BenchmarkEventBusWorkers(b *testing.B) {
+	for _, workers := range []int{0, 4, 16} {
+		b.Run(fmt.Sprintf("%dWorkers", workers), func(b *testing.B) {
+			benchmarkEventBus(1000, false, false, workers, b)
+		})
+	}
+}
+
+// BenchmarkEventBusUnfiltered compares receiving every event with a
+// subscription to cmtquery.All, which goes through the pubsub server, with an
+// unfiltered subscription, next to a number of clients subscribed to random
+// queries, e.g. over RPC.
+func BenchmarkEventBusUnfiltered(b *testing.B) {
+	for _, numClients := range []int{0, 100} {
+		for _, unfiltered := range []bool{false, true} {
+			name := fmt.Sprintf("QueryAll%dClients", numClients)
+			if unfiltered {
+				name = fmt.Sprintf("Unfiltered%dClients", numClients)
+			}
+			b.Run(name, func(b *testing.B) {
+				benchmarkEventBusUnfiltered(numClients, unfiltered, b)
+			})
+		}
+	}
+}
+
+func benchmarkEventBusUnfiltered(numClients int, unfiltered bool, b *testing.B) {
+	rnd := rand.New(rand.NewSource(time.Now().Unix()))
+	eventBus := NewEventBus()
+	require.NoError(b, eventBus.Start())
+	b.Cleanup(func() {
+		if err := eventBus.Stop(); err != nil {
+			b.Error(err)
+		}
+	})
+
+	ctx := context.Background()
+	for i := 0; i < numClients; i++ {
+		sub, err := eventBus.Subscribe(ctx, fmt.Sprintf("client-%d", i), randQuery(rnd), 100)
+		require.NoError(b, err)
+		go func() {
+			for {
+				select {
+				case <-sub.Out():
+				case <-sub.Canceled():
+					return
+				}
+			}
+		}()
+	}
+
+	received := make(chan struct{})
+	if unfiltered {
+		sub, err := eventBus.SubscribeUnfiltered(ctx, "all", 1)
+		require.NoError(b, err)
+		go func() {
+			for range sub.Out() {
+				received <- struct{}{}
+			}
+		}()
+	} else {
+		sub, err := eventBus.Subscribe(ctx, "all", cmtquery.All, 1)
+		require.NoError(b, err)
+		go func() {
+			for range sub.Out() {
+				received <- struct{}{}
+			}
+		}()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := eventBus.Publish(randEvent(rnd), EventDataString("Gamora")); err != nil {
+			b.Fatal(err)
+		}
+		// wait for the event, so that the subscriber never runs out of
+		// capacity
+		<-received
+	}
+}
+
+func benchmarkEventBus(numClients int, randQueries bool, randEvents bool, workers int, b *testing.B) {
 	// for random* functions
 	rnd := rand.New(rand.NewSource(time.Now().Unix()))
 
-	eventBus := NewEventBusWithBufferCapacity(0) // set buffer capacity to 0 so we are not testing cache
+	// set buffer capacity to 0 so we are not testing cache
+	eventBus := NewEventBusWithBufferCapacity(0)
+	if workers > 0 {
+		eventBus = NewEventBusWithWorkers(workers)
+	}
 	err := eventBus.Start()
 	if err != nil {
 		b.Error(err)
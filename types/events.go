@@ -89,6 +89,34 @@ type EventDataTx struct {
 	abci.TxResult
 }
 
+// TxHash returns the hash of the transaction.
+func (data EventDataTx) TxHash() []byte {
+	return Tx(data.Tx).Hash()
+}
+
+// Succeeded returns true if the transaction was executed successfully, i.e.
+// its result code is zero.
+func (data EventDataTx) Succeeded() bool {
+	return data.Result.IsOK()
+}
+
+// FindAttribute returns the value of the first attribute with the given key
+// among the events of the given type emitted by the transaction. The second
+// return value is false if there is no such attribute.
+func (data EventDataTx) FindAttribute(eventType, key string) (string, bool) {
+	for _, event := range data.Result.Events {
+		if event.Type != eventType {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			if attr.Key == key {
+				return attr.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
 // NOTE: This goes into the replay WAL
 type EventDataRoundState struct {
 	Height int64  `json:"height"`
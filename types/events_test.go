@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	abci "github.com/cometbft/cometbft/abci/types"
 )
 
 func TestQueryTxFor(t *testing.T) {
@@ -25,3 +27,58 @@ func TestQueryForEvent(t *testing.T) {
 		QueryForEvent(EventNewEvidence).String(),
 	)
 }
+
+func TestEventDataTxAccessors(t *testing.T) {
+	tx := Tx("foo")
+	data := EventDataTx{TxResult: abci.TxResult{
+		Height: 1,
+		Tx:     tx,
+		Result: abci.ExecTxResult{
+			Events: []abci.Event{
+				{
+					Type: "transfer",
+					Attributes: []abci.EventAttribute{
+						{Key: "sender", Value: "foo", Index: true},
+						{Key: "recipient", Value: "bar", Index: true},
+					},
+				},
+				{
+					Type: "transfer",
+					Attributes: []abci.EventAttribute{
+						{Key: "sender", Value: "baz", Index: true},
+						{Key: "amount", Value: "10", Index: true},
+					},
+				},
+				{
+					Type: "message",
+					Attributes: []abci.EventAttribute{
+						{Key: "sender", Value: "qux", Index: true},
+					},
+				},
+			},
+		},
+	}}
+
+	assert.Equal(t, tx.Hash(), data.TxHash())
+	assert.True(t, data.Succeeded())
+
+	testCases := []struct {
+		eventType, key string
+		value          string
+		found          bool
+	}{
+		{"transfer", "sender", "foo", true}, // first match wins
+		{"transfer", "amount", "10", true},
+		{"message", "sender", "qux", true},
+		{"message", "recipient", "", false},
+		{"unknown", "sender", "", false},
+	}
+	for _, tc := range testCases {
+		value, found := data.FindAttribute(tc.eventType, tc.key)
+		assert.Equal(t, tc.found, found, "%s.%s", tc.eventType, tc.key)
+		assert.Equal(t, tc.value, value, "%s.%s", tc.eventType, tc.key)
+	}
+
+	data.Result.Code = 1
+	assert.False(t, data.Succeeded())
+}
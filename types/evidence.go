@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math/big"
 	"sort"
 	"strings"
 	"time"
@@ -12,6 +13,7 @@ import (
 	abci "github.com/cometbft/cometbft/abci/types"
 	"github.com/cometbft/cometbft/crypto/merkle"
 	"github.com/cometbft/cometbft/crypto/tmhash"
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
 	cmtjson "github.com/cometbft/cometbft/libs/json"
 	cmtrand "github.com/cometbft/cometbft/libs/rand"
 	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
@@ -118,6 +120,45 @@ func (dve *DuplicateVoteEvidence) String() string {
 	return fmt.Sprintf("DuplicateVoteEvidence{VoteA: %v, VoteB: %v}", dve.VoteA, dve.VoteB)
 }
 
+// ConflictSummary returns a one-line description of the fields in which VoteA
+// and VoteB differ, in the order they are declared in Vote, formatted as
+// "Field: valueA != valueB" and separated by "; ". A genuine double-sign has
+// different block IDs, whereas votes which only differ in their timestamp or
+// signature usually point to the same key being used by more than one signer.
+func (dve *DuplicateVoteEvidence) ConflictSummary() string {
+	a, b := dve.VoteA, dve.VoteB
+	if a == nil || b == nil {
+		return fmt.Sprintf("missing vote (VoteA: %v, VoteB: %v)", a, b)
+	}
+
+	var diffs []string
+	diff := func(field string, equal bool, valA, valB any) {
+		if !equal {
+			diffs = append(diffs, fmt.Sprintf("%s: %v != %v", field, valA, valB))
+		}
+	}
+	diffBytes := func(field string, bzA, bzB []byte) {
+		diff(field, bytes.Equal(bzA, bzB),
+			fmt.Sprintf("%X", cmtbytes.Fingerprint(bzA)), fmt.Sprintf("%X", cmtbytes.Fingerprint(bzB)))
+	}
+
+	diff("Type", a.Type == b.Type, a.Type, b.Type)
+	diff("Height", a.Height == b.Height, a.Height, b.Height)
+	diff("Round", a.Round == b.Round, a.Round, b.Round)
+	diff("BlockID", a.BlockID.Equals(b.BlockID), a.BlockID, b.BlockID)
+	diff("Timestamp", a.Timestamp.Equal(b.Timestamp), CanonicalTime(a.Timestamp), CanonicalTime(b.Timestamp))
+	diffBytes("ValidatorAddress", a.ValidatorAddress, b.ValidatorAddress)
+	diff("ValidatorIndex", a.ValidatorIndex == b.ValidatorIndex, a.ValidatorIndex, b.ValidatorIndex)
+	diffBytes("Signature", a.Signature, b.Signature)
+	diffBytes("Extension", a.Extension, b.Extension)
+	diffBytes("ExtensionSignature", a.ExtensionSignature, b.ExtensionSignature)
+
+	if len(diffs) == 0 {
+		return "no differing fields"
+	}
+	return strings.Join(diffs, "; ")
+}
+
 // Time returns the time of the infraction
 func (dve *DuplicateVoteEvidence) Time() time.Time {
 	return dve.Timestamp
@@ -247,6 +288,26 @@ func (l *LightClientAttackEvidence) Bytes() []byte {
 	return bz
 }
 
+// ByzantineVotingPower returns the sum of the voting power of the byzantine
+// validators, or zero if there are none.
+func (l *LightClientAttackEvidence) ByzantineVotingPower() int64 {
+	var power int64
+	for _, val := range l.ByzantineValidators {
+		power += val.VotingPower
+	}
+	return power
+}
+
+// ByzantineFraction returns the fraction of TotalVotingPower held by the
+// byzantine validators. It is zero if there are no byzantine validators or if
+// TotalVotingPower is not positive.
+func (l *LightClientAttackEvidence) ByzantineFraction() *big.Rat {
+	if l.TotalVotingPower <= 0 {
+		return new(big.Rat)
+	}
+	return big.NewRat(l.ByzantineVotingPower(), l.TotalVotingPower)
+}
+
 // GetByzantineValidators finds out what style of attack LightClientAttackEvidence was and then works out who
 // the malicious validators were and returns them. This is used both for forming the ByzantineValidators
 // field and for validating that it is correct. Validators are ordered based on validator power
@@ -563,6 +624,11 @@ func (err *ErrInvalidEvidence) Error() string {
 	return fmt.Sprintf("Invalid evidence: %v. Evidence: %v", err.Reason, err.Evidence)
 }
 
+// Unwrap returns the reason the evidence is invalid.
+func (err *ErrInvalidEvidence) Unwrap() error {
+	return err.Reason
+}
+
 // ErrEvidenceOverflow is for when there the amount of evidence exceeds the max bytes.
 type ErrEvidenceOverflow struct {
 	Max int64
@@ -1,7 +1,10 @@
 package types
 
 import (
+	"fmt"
 	"math"
+	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +12,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/bls12381"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/secp256k1"
 	"github.com/cometbft/cometbft/crypto/tmhash"
 	cmtrand "github.com/cometbft/cometbft/libs/rand"
 	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
@@ -50,8 +56,57 @@ func TestDuplicateVoteEvidence(t *testing.T) {
 	assert.Equal(t, ev.Height(), height)
 }
 
+func TestDuplicateVoteEvidenceConflictSummary(t *testing.T) {
+	blockID := makeBlockID([]byte("blockhash"), 1000, []byte("partshash"))
+	blockID2 := makeBlockID([]byte("blockhash2"), 1000, []byte("partshash"))
+	newVote := func() *Vote {
+		return &Vote{
+			Type:             cmtproto.PrecommitType,
+			Height:           10,
+			Round:            2,
+			BlockID:          blockID,
+			Timestamp:        defaultVoteTime,
+			ValidatorAddress: crypto.AddressHash([]byte("validator")),
+			ValidatorIndex:   1,
+			Signature:        []byte("signature"),
+		}
+	}
+
+	dve := &DuplicateVoteEvidence{VoteA: newVote(), VoteB: newVote()}
+	assert.Equal(t, "no differing fields", dve.ConflictSummary())
+
+	dve.VoteB.BlockID = blockID2
+	assert.Equal(t,
+		fmt.Sprintf("BlockID: %v != %v", blockID, blockID2),
+		dve.ConflictSummary())
+
+	dve.VoteB = newVote()
+	dve.VoteB.Timestamp = defaultVoteTime.Add(time.Minute)
+	assert.Equal(t,
+		fmt.Sprintf("Timestamp: %s != %s", CanonicalTime(defaultVoteTime), CanonicalTime(defaultVoteTime.Add(time.Minute))),
+		dve.ConflictSummary())
+
+	dve.VoteB.Signature = []byte("other signature")
+	summary := dve.ConflictSummary()
+	assert.True(t, strings.HasPrefix(summary, "Timestamp: "), summary)
+	assert.Contains(t, summary, "; Signature: ")
+}
+
 func TestDuplicateVoteEvidenceValidation(t *testing.T) {
-	val := NewMockPV()
+	for _, keyType := range []string{ed25519.KeyType, secp256k1.KeyType, bls12381.KeyType} {
+		t.Run(keyType, func(t *testing.T) {
+			if keyType == bls12381.KeyType && !bls12381.Enabled {
+				t.Skip("bls12381 is disabled")
+			}
+			val, err := NewMockPVWithKeyType(keyType)
+			require.NoError(t, err)
+			testDuplicateVoteEvidenceValidation(t, val)
+		})
+	}
+}
+
+func testDuplicateVoteEvidenceValidation(t *testing.T, val MockPV) {
+	t.Helper()
 	blockID := makeBlockID(tmhash.Sum([]byte("blockhash")), math.MaxInt32, tmhash.Sum([]byte("partshash")))
 	blockID2 := makeBlockID(tmhash.Sum([]byte("blockhash2")), math.MaxInt32, tmhash.Sum([]byte("partshash")))
 	const chainID = "mychain"
@@ -76,6 +131,9 @@ func TestDuplicateVoteEvidenceValidation(t *testing.T) {
 			ev.VoteA = ev.VoteB.Copy()
 			ev.VoteB = swap
 		}, true},
+		{"Signature too big", func(ev *DuplicateVoteEvidence) {
+			ev.VoteB.Signature = make([]byte, MaxSignatureSize+1)
+		}, true},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.testName, func(t *testing.T) {
@@ -90,6 +148,17 @@ func TestDuplicateVoteEvidenceValidation(t *testing.T) {
 	}
 }
 
+func TestValidateSignatureSize(t *testing.T) {
+	ed25519Key := ed25519.GenPrivKey().PubKey()
+	secp256k1Key := secp256k1.GenPrivKey().PubKey()
+
+	require.NoError(t, ValidateSignatureSize(ed25519Key, make([]byte, ed25519.SignatureSize)))
+	require.NoError(t, ValidateSignatureSize(secp256k1Key, make([]byte, secp256k1.SignatureSize)))
+	require.Error(t, ValidateSignatureSize(ed25519Key, make([]byte, bls12381.SignatureLength)))
+	require.Error(t, ValidateSignatureSize(secp256k1Key, make([]byte, secp256k1.SignatureSize-1)))
+	require.Error(t, ValidateSignatureSize(ed25519Key, nil))
+}
+
 func TestLightClientAttackEvidenceBasic(t *testing.T) {
 	height := int64(5)
 	commonHeight := height - 1
@@ -151,6 +220,23 @@ func TestLightClientAttackEvidenceBasic(t *testing.T) {
 	}
 }
 
+func TestLightClientAttackEvidenceByzantinePower(t *testing.T) {
+	valSet, _ := RandValidatorSet(4, 10)
+	lcae := &LightClientAttackEvidence{
+		TotalVotingPower:    valSet.TotalVotingPower(),
+		ByzantineValidators: valSet.Validators[:1],
+	}
+	assert.EqualValues(t, 10, lcae.ByzantineVotingPower())
+	assert.Zero(t, big.NewRat(1, 4).Cmp(lcae.ByzantineFraction()))
+
+	lcae.ByzantineValidators = nil
+	assert.Zero(t, lcae.ByzantineVotingPower())
+	assert.Zero(t, lcae.ByzantineFraction().Sign())
+
+	lcae.TotalVotingPower = 0
+	assert.Zero(t, lcae.ByzantineFraction().Sign())
+}
+
 func TestLightClientAttackEvidenceValidation(t *testing.T) {
 	height := int64(5)
 	commonHeight := height - 1
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"github.com/go-kit/kit/metrics"
+)
+
+const (
+	// MetricsSubsystem is a subsystem shared by all metrics exposed by this
+	// package.
+	MetricsSubsystem = "event_bus"
+)
+
+//go:generate go run ../scripts/metricsgen -struct=Metrics
+
+// Metrics contains metrics exposed by the event bus.
+type Metrics struct {
+	// Number of subscriptions currently registered with the event bus.
+	SubscriptionsActive metrics.Gauge
+	// Number of events published on the event bus, labeled by event type.
+	EventsPublished metrics.Counter `metrics_labels:"event_type"`
+	// Number of subscriptions canceled because the subscriber did not
+	// consume events fast enough.
+	SlowSubscribers metrics.Counter
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 
 	"github.com/cometbft/cometbft/crypto"
+	"github.com/cometbft/cometbft/crypto/bls12381"
 	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/secp256k1"
 	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
 )
 
@@ -57,6 +59,27 @@ func NewMockPV() MockPV {
 	return MockPV{ed25519.GenPrivKey(), false, false}
 }
 
+// NewMockPVWithKeyType returns a MockPV with a new private key of the given
+// type: ed25519, secp256k1 or bls12_381 (if built with BLS support).
+func NewMockPVWithKeyType(keyType string) (MockPV, error) {
+	var privKey crypto.PrivKey
+	switch keyType {
+	case ed25519.KeyType:
+		privKey = ed25519.GenPrivKey()
+	case secp256k1.KeyType:
+		privKey = secp256k1.GenPrivKey()
+	case bls12381.KeyType:
+		pk, err := bls12381.GenPrivKey()
+		if err != nil {
+			return MockPV{}, err
+		}
+		privKey = pk
+	default:
+		return MockPV{}, fmt.Errorf("unsupported key type %q", keyType)
+	}
+	return MockPV{privKey, false, false}, nil
+}
+
 // NewMockPVWithParams allows one to create a MockPV instance, but with finer
 // grained control over the operation of the mock validator. This is useful for
 // mocking test failures.
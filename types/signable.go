@@ -1,19 +1,47 @@
 package types
 
 import (
+	"fmt"
+
+	"github.com/cometbft/cometbft/crypto"
 	"github.com/cometbft/cometbft/crypto/bls12381"
 	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/secp256k1"
 	cmtmath "github.com/cometbft/cometbft/libs/math"
 )
 
 // MaxSignatureSize is a maximum allowed signature size for the Proposal
 // and Vote.
-// XXX: secp256k1 does not have max signature size defined.
 var MaxSignatureSize = cmtmath.MaxInt(
 	ed25519.SignatureSize,
-	bls12381.SignatureLength,
+	cmtmath.MaxInt(secp256k1.SignatureSize, bls12381.SignatureLength),
 )
 
+// signatureSizes maps the validator key types to the size of their
+// signatures.
+var signatureSizes = map[string]int{
+	ed25519.KeyType:   ed25519.SignatureSize,
+	secp256k1.KeyType: secp256k1.SignatureSize,
+	bls12381.KeyType:  bls12381.SignatureLength,
+}
+
+// ValidateSignatureSize returns an error if sig doesn't have the size of the
+// signatures made with the private key of pubKey. Signatures of key types
+// with no known size are only checked against MaxSignatureSize.
+func ValidateSignatureSize(pubKey crypto.PubKey, sig []byte) error {
+	size, ok := signatureSizes[pubKey.Type()]
+	if !ok {
+		if len(sig) > MaxSignatureSize {
+			return fmt.Errorf("signature is too big (max: %d)", MaxSignatureSize)
+		}
+		return nil
+	}
+	if len(sig) != size {
+		return fmt.Errorf("expected %d bytes %s signature, got %d bytes", size, pubKey.Type(), len(sig))
+	}
+	return nil
+}
+
 // Signable is an interface for all signable things.
 // It typically removes signatures before serializing.
 // SignBytes returns the bytes to be signed